@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
+)
+
+// prints the report of every directory and returns false if any of them is invalid.
+// with strict enabled, unused images count as errors.
+func validateAssets(dirs []string, strict bool) bool {
+	ok := true
+	for _, dir := range dirs {
+		report, err := generator.ValidateAssets(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", dir, err)
+			ok = false
+			continue
+		}
+		for _, e := range report.Errors {
+			fmt.Printf("%s: ERROR - %s\n", dir, e)
+		}
+		for _, w := range report.Warnings {
+			fmt.Printf("%s: WARNING - %s\n", dir, w)
+		}
+		valid := report.Ok() && (!strict || len(report.Warnings) == 0)
+		if valid {
+			fmt.Printf("%s: ok (%d errors, %d warnings)\n", dir, len(report.Errors), len(report.Warnings))
+		} else {
+			fmt.Printf("%s: invalid (%d errors, %d warnings)\n", dir, len(report.Errors), len(report.Warnings))
+		}
+		ok = ok && valid
+	}
+	return ok
+}
+
+func assetsCommand(args []string, strict bool) {
+	if len(args) < 2 || args[0] != "validate" {
+		log.Fatal("usage: crypto-koi-cli [-strict] assets validate <dir> [<dir> ...]")
+	}
+	if !validateAssets(args[1:], strict) {
+		os.Exit(1)
+	}
+}
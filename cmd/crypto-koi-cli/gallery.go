@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math/big"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+// height of the label below each koi
+const labelHeight = 36
+
+type galleryOptions struct {
+	amount   int
+	columns  int
+	cellSize int
+	species  string
+	out      string
+}
+
+type galleryEntry struct {
+	// decimal representation
+	tokenId string
+	// hex representation
+	uuid       string
+	attributes cryptokoi.KoiAttributes
+	img        image.Image
+}
+
+func imageNames(images []util.ImageWithColor) string {
+	names := make([]string, len(images))
+	for i, img := range images {
+		names[i] = img.ImageName
+	}
+	return strings.Join(names, " ")
+}
+
+// returns the decimal and the hex representation of a token id.
+func normalizeTokenId(tokenId string) (string, string, error) {
+	if strings.IndexFunc(tokenId, util.IsNotDigit) > -1 {
+		tmp, err := util.UuidToUint256(tokenId)
+		if err != nil {
+			return "", "", err
+		}
+		return tmp.String(), strings.ReplaceAll(tokenId, "-", ""), nil
+	}
+	uInt, ok := new(big.Int).SetString(tokenId, 10)
+	if !ok {
+		return "", "", fmt.Errorf("invalid token id: %s", tokenId)
+	}
+	id, err := util.Uint256ToUuid(uInt)
+	if err != nil {
+		return "", "", err
+	}
+	return tokenId, strings.ReplaceAll(id.String(), "-", ""), nil
+}
+
+// picks the token ids to render. Listed token ids are used as provided - otherwise random ones get generated.
+// both are filtered by species.
+func pickTokenIds(listed []string, opts galleryOptions) ([][2]string, error) {
+	var species cryptokoi.KoiType
+	if opts.species != "" {
+		var err error
+		species, err = cryptokoi.IsKoiType(opts.species)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches := func(tokenId string) bool {
+		return species == "" || cryptokoi.NewKoi(tokenId).GetAttributes().KoiType == species
+	}
+
+	result := make([][2]string, 0)
+	if len(listed) > 0 {
+		for _, tokenId := range listed {
+			dec, hex, err := normalizeTokenId(tokenId)
+			if err != nil {
+				return nil, err
+			}
+			if matches(dec) {
+				result = append(result, [2]string{dec, hex})
+			}
+		}
+		return result, nil
+	}
+
+	// give up at some point - the species might be really rare.
+	maxAttempts := opts.amount * 100
+	for attempt := 0; attempt < maxAttempts && len(result) < opts.amount; attempt++ {
+		id := uuid.New()
+		dec, err := util.UuidToUint256(id.String())
+		if err != nil {
+			return nil, err
+		}
+		if matches(dec.String()) {
+			result = append(result, [2]string{dec.String(), strings.ReplaceAll(id.String(), "-", "")})
+		}
+	}
+	if len(result) < opts.amount {
+		log.Printf("WARNING - only found %d of %d kois matching species: %s", len(result), opts.amount, species)
+	}
+	return result, nil
+}
+
+func renderGalleryEntries(g *generator.Generator, tokenIds [][2]string, size int) []galleryEntry {
+	entries := make([]galleryEntry, len(tokenIds))
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				img, koi := g.TokenId2Image(tokenIds[i][0], size)
+				entries[i] = galleryEntry{
+					tokenId:    tokenIds[i][0],
+					uuid:       tokenIds[i][1],
+					attributes: koi.GetAttributes(),
+					img:        img,
+				}
+			}
+		}()
+	}
+	for i := range tokenIds {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return entries
+}
+
+func drawLabel(dst draw.Image, x, y int, text string) {
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.Black),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func drawContactSheet(entries []galleryEntry, opts galleryOptions) image.Image {
+	columns := opts.columns
+	if columns > len(entries) {
+		columns = len(entries)
+	}
+	rows := (len(entries) + columns - 1) / columns
+	cellHeight := opts.cellSize + labelHeight
+
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*opts.cellSize, rows*cellHeight))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	// the label has room for this many characters
+	maxChars := opts.cellSize / basicfont.Face7x13.Advance
+	for i, entry := range entries {
+		x := (i % columns) * opts.cellSize
+		y := (i / columns) * cellHeight
+
+		draw.Draw(sheet, image.Rect(x, y, x+opts.cellSize, y+opts.cellSize), entry.img, image.Point{}, draw.Over)
+
+		id := entry.uuid
+		if len(id) > maxChars {
+			id = id[:maxChars]
+		}
+		drawLabel(sheet, x+4, y+opts.cellSize+14, id)
+		drawLabel(sheet, x+4, y+opts.cellSize+30, fmt.Sprintf("%d %s", i+1, entry.attributes.KoiType))
+	}
+	return sheet
+}
+
+func writeGalleryCSV(path string, entries []galleryEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"index", "tokenId", "uuid", "species", "primaryColor", "bodyColor", "finColor", "patternQuantity", "bodyImages", "headImages", "finImages"})
+	for i, entry := range entries {
+		a := entry.attributes
+		w.Write([]string{
+			strconv.Itoa(i + 1),
+			entry.tokenId,
+			entry.uuid,
+			a.KoiType,
+			util.ConvertColor2Hex(a.PrimaryColor),
+			util.ConvertColor2Hex(a.BodyColor),
+			util.ConvertColor2Hex(a.FinColor),
+			strconv.Itoa(len(a.BodyImages) + len(a.HeadImages) + len(a.FinImages)),
+			imageNames(a.BodyImages),
+			imageNames(a.HeadImages),
+			imageNames(a.FinImages),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// renders a labelled grid of kois and a csv file containing their attributes.
+// the index in the csv matches the number printed below each koi.
+func drawGallery(g *generator.Generator, preloader generator.Preloader, listed []string, opts galleryOptions) {
+	if opts.amount < 1 || opts.columns < 1 || opts.cellSize < 1 {
+		log.Fatal("amount, columns and cellSize need to be greater than zero")
+	}
+
+	tokenIds, err := pickTokenIds(listed, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(tokenIds) == 0 {
+		log.Fatal("no kois to render")
+	}
+
+	preloader.BuildCachesForSizes([]int{opts.cellSize})
+	entries := renderGalleryEntries(g, tokenIds, opts.cellSize)
+
+	f, err := os.Create(opts.out + ".png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err = png.Encode(f, drawContactSheet(entries, opts)); err != nil {
+		log.Fatal(err)
+	}
+
+	if err = writeGalleryCSV(opts.out+".csv", entries); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Gallery with %d kois saved at: %s.png and %s.csv", len(entries), opts.out, opts.out)
+}
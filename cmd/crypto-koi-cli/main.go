@@ -21,6 +21,8 @@ import (
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/pubsub"
+	"gorm.io/gorm"
 )
 
 func randomString(n int) string {
@@ -33,8 +35,7 @@ func randomString(n int) string {
 	return string(s)
 }
 
-func registerRandomUser(amount int) {
-	// register the user with the token id
+func connectDB() *gorm.DB {
 	db, err := db.NewMySQL(db.MySQLConfig{
 		User:     os.Getenv("DB_USER"),
 		Password: strings.TrimSpace(string(util.MustReadFile(os.Getenv("DB_PASSWORD_FILE_PATH")))),
@@ -46,6 +47,12 @@ func registerRandomUser(amount int) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return db
+}
+
+func registerRandomUser(amount int) {
+	// register the user with the token id
+	db := connectDB()
 
 	userRep := repositories.NewGormUserRepository(db)
 	refreshToken, _ := uuid.NewRandom()
@@ -57,7 +64,7 @@ func registerRandomUser(amount int) {
 	userRep.Save(&newUser)
 
 	cryptogotchiRep := repositories.NewGormCryptogotchiRepository(db)
-	cryptogotchiSvc := service.NewCryptogotchiService(cryptogotchiRep, userRep, nil)
+	cryptogotchiSvc := service.NewCryptogotchiService(cryptogotchiRep, userRep, repositories.NewGormOwnershipTransferRepository(db), nil, service.DefaultNamePolicy(), service.DefaultReservationPolicy(), service.NewLiveUpdateService(pubsub.NewMemoryPubSub()))
 
 	wg := sync.WaitGroup{}
 	wg.Add(amount)
@@ -75,6 +82,24 @@ func registerRandomUser(amount int) {
 	wg.Wait()
 }
 
+// used to grant the first admin - afterwards the admins can use the setRole mutation.
+func setRole(email, roleStr string) {
+	role, err := models.IsRole(strings.ToUpper(roleStr))
+	if err != nil {
+		log.Fatal(err)
+	}
+	userRep := repositories.NewGormUserRepository(connectDB())
+	user, err := userRep.GetByEmail(email)
+	if err != nil {
+		log.Fatalf("could not find user with email %s: %s", email, err)
+	}
+	user.Role = role
+	if err := userRep.Save(&user); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("user %s has the role %s now", user.Id, role)
+}
+
 func drawImage(g *generator.Generator, drawPrimaryColor bool, tokenId string) {
 
 	originalTokenId := tokenId
@@ -134,24 +159,44 @@ func main() {
 
 	err := godotenv.Load()
 
-	baseImagePath := os.Getenv("BASE_IMAGE_PATH")
-
-	if baseImagePath == "" {
-		log.Fatal("BASE_IMAGE_PATH environment variable not set")
-	}
-
 	drawPrimaryColor := flag.Bool("drawPrimaryColor", false, "draw the primary color onto the image")
 	debug := flag.Bool("debug", false, "enable debug mode")
-	amount := flag.Int("amount", 1, "amount of users to register")
+	amount := flag.Int("amount", 1, "amount of users to register or amount of kois to render into the gallery")
+	columns := flag.Int("columns", 8, "amount of columns of the gallery")
+	cellSize := flag.Int("cellSize", 256, "size of each koi in the gallery")
+	species := flag.String("species", "", "only render kois of this species into the gallery [kohaku | showa | utsuri | monochrome | shigure]")
+	out := flag.String("out", "gallery", "file name of the gallery without extension. A .png and a .csv file are created")
+	strict := flag.Bool("strict", false, "treat unused images as errors when validating assets")
 
 	t := flag.String("type", "koi", "type of the cryptogotchi to generate [koi | dragon]")
 
 	flag.Parse()
 
+	// the validation is used in ci - it neither needs a .env file nor the BASE_IMAGE_PATH.
+	if flag.Arg(0) == "assets" {
+		assetsCommand(flag.Args()[1:], *strict)
+		return
+	}
+
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
+	// does not need the images.
+	if flag.Arg(0) == "set-role" {
+		if flag.NArg() != 3 {
+			log.Fatal("usage: crypto-koi-cli set-role <email> <USER | ADMIN>")
+		}
+		setRole(flag.Arg(1), flag.Arg(2))
+		return
+	}
+
+	baseImagePath := os.Getenv("BASE_IMAGE_PATH")
+
+	if baseImagePath == "" {
+		log.Fatal("BASE_IMAGE_PATH environment variable not set")
+	}
+
 	pathSuffix := "koi"
 	if *t == "dragon" {
 		pathSuffix = "dragon"
@@ -171,11 +216,19 @@ func main() {
 		fmt.Println(util.UuidToUint256(uuidStr))
 	case "draw":
 		drawImage(&g, *drawPrimaryColor, flag.Arg(1))
+	case "gallery":
+		drawGallery(&g, preloader, flag.Args()[1:], galleryOptions{
+			amount:   *amount,
+			columns:  *columns,
+			cellSize: *cellSize,
+			species:  *species,
+			out:      *out,
+		})
 	case "register":
 		registerRandomUser(*amount)
 	case "sync-with-blockchain":
 		// syncWithBlockchain()
 	default:
-		log.Fatalf("command: %s not found. Please use one of the following commands: register, draw", command)
+		log.Fatalf("command: %s not found. Please use one of the following commands: register, draw, gallery, assets, set-role", command)
 	}
 }
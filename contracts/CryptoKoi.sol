@@ -4,19 +4,29 @@ pragma solidity ^0.8.0;
 import '@openzeppelin/contracts/access/AccessControl.sol';
 import '@openzeppelin/contracts/token/ERC721/ERC721.sol';
 import '@openzeppelin/contracts/utils/cryptography/ECDSA.sol';
+import '@openzeppelin/contracts/utils/cryptography/draft-EIP712.sol';
+
+contract CryptoKoi is ERC721, EIP712 {
+    bytes32 private constant VOUCHER_TYPEHASH =
+        keccak256(
+            'Voucher(address account,uint256 tokenId,uint256 price,uint256 expiry)'
+        );
 
-contract CryptoKoi is ERC721 {
     address payable public owner;
 
     string baseURI;
     uint256 price;
 
+    // the untyped vouchers issued before the EIP-712 migration.
+    // they never expire - disable them once the migration window is over.
+    bool public legacyVouchersAccepted = true;
+
     constructor(
         string memory name,
         string memory symbol,
         string memory uri,
         uint256 p
-    ) ERC721(name, symbol) {
+    ) ERC721(name, symbol) EIP712('CryptoKoi', '1') {
         owner = payable(msg.sender);
         baseURI = uri;
         price = p;
@@ -59,11 +69,37 @@ contract CryptoKoi is ERC721 {
         return super.supportsInterface(interfaceId);
     }
 
+    function disableLegacyVouchers() external onlyOwner {
+        legacyVouchersAccepted = false;
+    }
+
+    function redeemVoucher(
+        address account,
+        uint256 tokenId,
+        uint256 voucherPrice,
+        uint256 expiry,
+        bytes calldata signature
+    ) external payable {
+        require(block.timestamp <= expiry, 'Voucher expired');
+        require(msg.value >= voucherPrice, 'Insufficient funds');
+        require(
+            _verify(
+                _hashVoucher(account, tokenId, voucherPrice, expiry),
+                signature
+            ),
+            'Invalid signature'
+        );
+
+        _safeMint(account, tokenId);
+    }
+
+    // deprecated: use redeemVoucher.
     function redeem(
         address account,
         uint256 tokenId,
         bytes calldata signature
     ) external payable {
+        require(legacyVouchersAccepted, 'Legacy vouchers disabled');
         require(msg.value >= price, 'Insufficient funds');
         require(
             _verify(_hash(account, tokenId), signature),
@@ -98,6 +134,26 @@ contract CryptoKoi is ERC721 {
             );
     }
 
+    function _hashVoucher(
+        address account,
+        uint256 tokenId,
+        uint256 voucherPrice,
+        uint256 expiry
+    ) internal view returns (bytes32) {
+        return
+            _hashTypedDataV4(
+                keccak256(
+                    abi.encode(
+                        VOUCHER_TYPEHASH,
+                        account,
+                        tokenId,
+                        voucherPrice,
+                        expiry
+                    )
+                )
+            );
+    }
+
     function _verify(bytes32 digest, bytes memory signature)
         internal
         view
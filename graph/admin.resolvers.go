@@ -0,0 +1,112 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+	"strings"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func (r *mutationResolver) BanUser(ctx context.Context, id string, reason string) (*models.User, error) {
+	currentUser, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentUser.Id.String() == id {
+		return nil, apperror.NewValidation("you can not ban yourself")
+	}
+	user, err := r.userSvc.GetById(id)
+	if err != nil {
+		return nil, notFound(err, "could not find user with id %s", id)
+	}
+	r.logger.Infof("user %s bans user %s", currentUser.Id, user.Id)
+	err = r.authSvc.BanUser(&user, reason)
+	return &user, err
+}
+
+func (r *mutationResolver) UnbanUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := r.userSvc.GetById(id)
+	if err != nil {
+		return nil, notFound(err, "could not find user with id %s", id)
+	}
+	err = r.authSvc.UnbanUser(&user)
+	return &user, err
+}
+
+func (r *mutationResolver) SetRole(ctx context.Context, id string, role models.Role) (*models.User, error) {
+	currentUser, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentUser.Id.String() == id {
+		// there would be no admin left to undo it.
+		return nil, apperror.NewValidation("you can not change your own role")
+	}
+	user, err := r.userSvc.GetById(id)
+	if err != nil {
+		return nil, notFound(err, "could not find user with id %s", id)
+	}
+	r.logger.Infof("user %s sets the role of user %s to %s", currentUser.Id, user.Id, role)
+	user.Role = role
+	err = r.userSvc.Save(&user)
+	return &user, err
+}
+
+func (r *mutationResolver) CorrectCryptogotchi(ctx context.Context, id string, correction input.CryptogotchiCorrection) (*models.Cryptogotchi, error) {
+	cryptogotchi, err := r.cryptogotchiSvc.GetById(id)
+	if err != nil {
+		return nil, notFound(err, "could not find cryptogotchi with id %s", id)
+	}
+	if err := r.cryptogotchiSvc.Correct(&cryptogotchi, correction); err != nil {
+		return nil, err
+	}
+	return &cryptogotchi, nil
+}
+
+func (r *queryResolver) LookupUser(ctx context.Context, lookup input.UserLookup) (*models.User, error) {
+	var user models.User
+	var err error
+	switch {
+	case lookup.ID != nil:
+		user, err = r.userSvc.GetById(*lookup.ID)
+	case lookup.Email != nil:
+		user, err = r.userSvc.GetByEmail(*lookup.Email)
+	case lookup.WalletAddress != nil:
+		// the wallet addresses are stored lower cased.
+		user, err = r.userSvc.GetByWalletAddress(strings.ToLower(*lookup.WalletAddress))
+	case lookup.DeviceID != nil:
+		user, err = r.userSvc.GetByDeviceId(*lookup.DeviceID)
+	default:
+		return nil, apperror.NewValidation("one of id, email, walletAddress or deviceId is required")
+	}
+	if db.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *queryResolver) FlaggedGameStats(ctx context.Context, offset int, limit int) ([]*models.GameStat, error) {
+	limit, err := pageLimit(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	gameStats, err := r.gameSvc.GetFlagged(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.GameStat, len(gameStats))
+	for i, g := range gameStats {
+		tmp := g
+		res[i] = &tmp
+	}
+	return res, nil
+}
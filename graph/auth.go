@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+// returns the user set by the auth middleware - or nil for anonymous requests.
+func optionalUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(config.USER_CTX_KEY).(*models.User)
+	return user
+}
+
+// returns the user set by the auth middleware.
+// clients use the UNAUTHENTICATED code to redirect to the login.
+func CurrentUser(ctx context.Context) (*models.User, error) {
+	user := optionalUser(ctx)
+	if user == nil {
+		return nil, apperror.New(apperror.Unauthenticated, "not authenticated")
+	}
+	return user, nil
+}
+
+// every root field needs to be either marked with @public or require authentication.
+// fails closed: a new field without a directive would be public otherwise.
+func ValidateAuthDirectives(schema *ast.Schema) error {
+	for _, root := range []*ast.Definition{schema.Query, schema.Mutation, schema.Subscription} {
+		if root == nil {
+			continue
+		}
+		for _, field := range root.Fields {
+			// introspection fields.
+			if len(field.Name) > 1 && field.Name[:2] == "__" {
+				continue
+			}
+			if field.Directives.ForName("public") == nil && field.Directives.ForName("auth") == nil && field.Directives.ForName("hasRole") == nil {
+				return fmt.Errorf("%s.%s needs to be marked with @auth, @hasRole or @public", root.Name, field.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+)
+
+// the cursors are opaque for the clients - the encoded sort key of the row.
+func encodeCursor(key interface{}) string {
+	b, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// returns nil for the first page.
+func decodeCursor[K any](after *string) (*K, error) {
+	if after == nil {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(*after)
+	if err != nil {
+		return nil, apperror.NewValidation("invalid cursor")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	// a cursor of another connection has other fields.
+	decoder.DisallowUnknownFields()
+	var key K
+	if err := decoder.Decode(&key); err != nil {
+		return nil, apperror.NewValidation("invalid cursor")
+	}
+	return &key, nil
+}
+
+func firstLimit(first int) (int, error) {
+	if first < 0 {
+		return 0, apperror.NewValidation("first must not be negative")
+	}
+	return pageLimit(0, first)
+}
+
+// the rows are fetched with first+1 as limit - the additional row only tells if there is a next page.
+func pageInfo(cursors []string, first int, hasPreviousPage bool) (*input.PageInfo, []string) {
+	info := &input.PageInfo{
+		HasNextPage:     len(cursors) > first,
+		HasPreviousPage: hasPreviousPage,
+	}
+	if len(cursors) > first {
+		cursors = cursors[:first]
+	}
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+	return info, cursors
+}
+
+func rankCursor(cryptogotchi models.Cryptogotchi) string {
+	return encodeCursor(repositories.RankKey{Rank: cryptogotchi.Rank, Id: cryptogotchi.Id})
+}
+
+func timeCursor(base models.Base) string {
+	return encodeCursor(repositories.TimeKey{CreatedAt: base.CreatedAt, Id: base.Id})
+}
+
+func cryptogotchiConnection(cryptogotchies []models.Cryptogotchi, first int, hasPreviousPage bool) *input.CryptogotchiConnection {
+	cursors := make([]string, len(cryptogotchies))
+	for i, c := range cryptogotchies {
+		cursors[i] = rankCursor(c)
+	}
+	info, cursors := pageInfo(cursors, first, hasPreviousPage)
+	edges := make([]*input.CryptogotchiEdge, len(cursors))
+	for i := range edges {
+		edges[i] = &input.CryptogotchiEdge{Cursor: cursors[i], Node: &cryptogotchies[i]}
+	}
+	return &input.CryptogotchiConnection{Edges: edges, PageInfo: info}
+}
+
+func eventConnection(events []models.Event, first int, hasPreviousPage bool) *input.EventConnection {
+	cursors := make([]string, len(events))
+	for i, e := range events {
+		cursors[i] = timeCursor(e.Base)
+	}
+	info, cursors := pageInfo(cursors, first, hasPreviousPage)
+	edges := make([]*input.EventEdge, len(cursors))
+	for i := range edges {
+		edges[i] = &input.EventEdge{Cursor: cursors[i], Node: &events[i]}
+	}
+	return &input.EventConnection{Edges: edges, PageInfo: info}
+}
+
+func userConnection(users []models.User, first int, hasPreviousPage bool) *input.UserConnection {
+	cursors := make([]string, len(users))
+	for i, u := range users {
+		cursors[i] = timeCursor(u.Base)
+	}
+	info, cursors := pageInfo(cursors, first, hasPreviousPage)
+	edges := make([]*input.UserEdge, len(cursors))
+	for i := range edges {
+		edges[i] = &input.UserEdge{Cursor: cursors[i], Node: &users[i]}
+	}
+	return &input.UserConnection{Edges: edges, PageInfo: info}
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := repositories.TimeKey{CreatedAt: time.Date(2022, 3, 1, 12, 0, 0, 123000000, time.UTC), Id: uuid.New()}
+	cursor := encodeCursor(key)
+
+	decoded, err := decodeCursor[repositories.TimeKey](&cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Id, decoded.Id)
+	assert.True(t, key.CreatedAt.Equal(decoded.CreatedAt))
+
+	decoded, err = decodeCursor[repositories.TimeKey](nil)
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	_, err := decodeCursor[repositories.RankKey](util.Str("not a cursor"))
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+
+	// the cursor of another connection.
+	cursor := encodeCursor(repositories.TimeKey{CreatedAt: time.Now(), Id: uuid.New()})
+	_, err = decodeCursor[repositories.RankKey](&cursor)
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+}
+
+func TestFirstLimit(t *testing.T) {
+	_, err := firstLimit(-1)
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+
+	first, err := firstLimit(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, MaxPageSize, first)
+}
+
+func TestCryptogotchiConnection(t *testing.T) {
+	cryptogotchies := []models.Cryptogotchi{
+		{Base: models.Base{Id: uuid.New()}, Rank: 1},
+		{Base: models.Base{Id: uuid.New()}, Rank: 2},
+		{Base: models.Base{Id: uuid.New()}, Rank: 3},
+	}
+
+	// first+1 rows were fetched - the last one only indicates the next page.
+	connection := cryptogotchiConnection(cryptogotchies, 2, false)
+	assert.Len(t, connection.Edges, 2)
+	assert.True(t, connection.PageInfo.HasNextPage)
+	assert.False(t, connection.PageInfo.HasPreviousPage)
+	assert.Equal(t, connection.Edges[0].Cursor, *connection.PageInfo.StartCursor)
+	assert.Equal(t, connection.Edges[1].Cursor, *connection.PageInfo.EndCursor)
+
+	key, err := decodeCursor[repositories.RankKey](connection.PageInfo.EndCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, repositories.RankKey{Rank: 2, Id: cryptogotchies[1].Id}, *key)
+
+	connection = cryptogotchiConnection(cryptogotchies, 3, true)
+	assert.Len(t, connection.Edges, 3)
+	assert.False(t, connection.PageInfo.HasNextPage)
+	assert.True(t, connection.PageInfo.HasPreviousPage)
+
+	connection = cryptogotchiConnection(nil, 3, true)
+	assert.Empty(t, connection.Edges)
+	assert.Nil(t, connection.PageInfo.EndCursor)
+}
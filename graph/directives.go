@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+// implements the @auth directive.
+// the field is only resolved for authenticated users.
+func Auth(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, err := CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// implements the @public directive - it only marks the field as intentionally public.
+func Public(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	return next(ctx)
+}
+
+// implements the @hasRole directive.
+// the field is only resolved if the current user has the role.
+func HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role models.Role) (interface{}, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.HasRole(role) {
+		return nil, apperror.NewForbidden("not allowed - requires the role %s", role)
+	}
+	return next(ctx)
+}
+
+// the implementations of all directives of the schema.
+func Directives() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		Auth:    Auth,
+		HasRole: HasRole,
+		Public:  Public,
+	}
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func resolved(ctx context.Context) (interface{}, error) {
+	return "resolved", nil
+}
+
+func withUser(user *models.User) context.Context {
+	return context.WithValue(context.Background(), config.USER_CTX_KEY, user)
+}
+
+func TestCurrentUserUnauthenticated(t *testing.T) {
+	_, err := CurrentUser(context.Background())
+	assert.Equal(t, apperror.Unauthenticated, apperror.CodeOf(err))
+
+	user := &models.User{Name: "koi"}
+	current, err := CurrentUser(withUser(user))
+	assert.Nil(t, err)
+	assert.Equal(t, user, current)
+}
+
+func TestAuthDirective(t *testing.T) {
+	_, err := Auth(context.Background(), nil, resolved)
+	assert.NotNil(t, err)
+
+	res, err := Auth(withUser(&models.User{}), nil, resolved)
+	assert.Nil(t, err)
+	assert.Equal(t, "resolved", res)
+}
+
+func TestHasRoleDirective(t *testing.T) {
+	_, err := HasRole(context.Background(), nil, resolved, models.AdminRole)
+	assert.Equal(t, apperror.Unauthenticated, apperror.CodeOf(err))
+
+	_, err = HasRole(withUser(&models.User{Role: models.UserRole}), nil, resolved, models.AdminRole)
+	assert.Equal(t, apperror.Forbidden, apperror.CodeOf(err))
+
+	res, err := HasRole(withUser(&models.User{Role: models.AdminRole}), nil, resolved, models.AdminRole)
+	assert.Nil(t, err)
+	assert.Equal(t, "resolved", res)
+}
+
+func TestSchemaRootFieldsHaveAuthDirectives(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{}, Directives: Directives()}).Schema()
+	assert.Nil(t, ValidateAuthDirectives(schema))
+
+	// the allowlist of public fields.
+	var public []string
+	for _, field := range schema.Query.Fields {
+		if field.Directives.ForName("public") != nil {
+			public = append(public, field.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{"leaderboard", "leaderboardConnection", "cryptogotchi"}, public)
+}
+
+func TestValidateAuthDirectivesFailsClosed(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		directive @public on FIELD_DEFINITION
+		type Query {
+			open: String @public
+			forgotten: String
+		}
+	`})
+	assert.EqualError(t, ValidateAuthDirectives(schema), "Query.forgotten needs to be marked with @auth, @hasRole or @public")
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/getsentry/sentry-go"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+// the sentry middleware attaches a hub to every request.
+func reportToSentry(ctx context.Context, err error) *sentry.EventID {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	return hub.CaptureException(err)
+}
+
+func internalError(ctx context.Context, err error) *gqlerror.Error {
+	orchardclient.Logger.WithField("component", "ErrorPresenter").Errorf("internal error at %s: %s", graphql.GetPath(ctx), err)
+	extensions := map[string]interface{}{"code": string(apperror.Internal)}
+	if eventId := reportToSentry(ctx, err); eventId != nil {
+		// allows to find the error in sentry.
+		extensions["eventId"] = string(*eventId)
+	}
+	return &gqlerror.Error{
+		Message:    "internal server error",
+		Path:       graphql.GetPath(ctx),
+		Extensions: extensions,
+	}
+}
+
+// maps the apperror codes into the extensions.
+// errors without a code are hidden from the client and reported to sentry.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		if appErr.Code == apperror.Internal {
+			return internalError(ctx, err)
+		}
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		gqlErr.Message = appErr.Message
+		gqlErr.Extensions = appErr.Extensions()
+		return gqlErr
+	}
+	if db.IsNotFound(err) {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		gqlErr.Message = "not found"
+		gqlErr.Extensions = apperror.NewNotFound("not found").Extensions()
+		return gqlErr
+	}
+	// created by gqlgen - for example invalid arguments.
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) && gqlErr.Unwrap() == nil {
+		return graphql.DefaultErrorPresenter(ctx, err)
+	}
+	return internalError(ctx, err)
+}
+
+// gqlgen recovers panics in resolvers - they would never reach the sentry middleware.
+func RecoverFunc(ctx context.Context, p interface{}) error {
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("panic: %v", p)
+	}
+	return apperror.Wrap(apperror.Internal, err, "internal server error")
+}
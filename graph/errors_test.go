@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// resolver errors reach the presenter wrapped with their path.
+func presentResolverError(err error) *gqlerror.Error {
+	ctx := graphql.WithFieldContext(context.Background(), &graphql.FieldContext{Field: graphql.CollectedField{}})
+	return ErrorPresenter(ctx, graphql.ErrorOnPath(ctx, err))
+}
+
+func TestErrorPresenterMapsCodes(t *testing.T) {
+	gqlErr := presentResolverError(apperror.NewForbidden("you are not the owner of this cryptogotchi"))
+	assert.Equal(t, "you are not the owner of this cryptogotchi", gqlErr.Message)
+	assert.Equal(t, "FORBIDDEN", gqlErr.Extensions["code"])
+
+	retryAt := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	gqlErr = presentResolverError(fmt.Errorf("feed: %w", apperror.NewCooldown(retryAt, "it is not time to feed yet")))
+	assert.Equal(t, "it is not time to feed yet", gqlErr.Message)
+	assert.Equal(t, "COOLDOWN", gqlErr.Extensions["code"])
+	assert.Equal(t, "2022-01-01T12:00:00Z", gqlErr.Extensions["retryAt"])
+}
+
+func TestErrorPresenterHidesCause(t *testing.T) {
+	gqlErr := presentResolverError(apperror.Wrap(apperror.Validation, errors.New("secret cause"), "invalid game token"))
+	assert.Equal(t, "invalid game token", gqlErr.Message)
+}
+
+func TestErrorPresenterNotFound(t *testing.T) {
+	gqlErr := presentResolverError(gorm.ErrRecordNotFound)
+	assert.Equal(t, "not found", gqlErr.Message)
+	assert.Equal(t, "NOT_FOUND", gqlErr.Extensions["code"])
+}
+
+func TestErrorPresenterHidesInternalErrors(t *testing.T) {
+	for _, err := range []error{
+		errors.New("dial tcp 10.0.0.1:3306: connection refused"),
+		apperror.Wrap(apperror.Internal, errors.New("panic"), "internal server error"),
+	} {
+		gqlErr := presentResolverError(err)
+		assert.Equal(t, "internal server error", gqlErr.Message)
+		assert.Equal(t, "INTERNAL", gqlErr.Extensions["code"])
+	}
+}
+
+func TestErrorPresenterKeepsGqlgenErrors(t *testing.T) {
+	gqlErr := presentResolverError(gqlerror.Errorf("must not be null"))
+	assert.Equal(t, "must not be null", gqlErr.Message)
+}
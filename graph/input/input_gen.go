@@ -2,6 +2,12 @@
 
 package input
 
+import (
+	"time"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
 type CryptogotchiAttributes struct {
 	Birthday        int     `json:"birthday"`
 	PrimaryColor    string  `json:"primaryColor"`
@@ -12,17 +18,91 @@ type CryptogotchiAttributes struct {
 	Food            float64 `json:"food"`
 }
 
+type CryptogotchiConnection struct {
+	Edges    []*CryptogotchiEdge `json:"edges"`
+	PageInfo *PageInfo           `json:"pageInfo"`
+}
+
+type CryptogotchiCorrection struct {
+	Food       *float64 `json:"food"`
+	Name       *string  `json:"name"`
+	IsValidNft *bool    `json:"isValidNft"`
+}
+
+type CryptogotchiEdge struct {
+	Cursor string               `json:"cursor"`
+	Node   *models.Cryptogotchi `json:"node"`
+}
+
+type EventConnection struct {
+	Edges    []*EventEdge `json:"edges"`
+	PageInfo *PageInfo    `json:"pageInfo"`
+}
+
+type EventEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *models.Event `json:"node"`
+}
+
 type GameStartResponse struct {
 	Token string `json:"token"`
 }
 
+type MintVoucher struct {
+	ContractAddress string    `json:"contractAddress"`
+	Price           string    `json:"price"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+	Signature       string    `json:"signature"`
+}
+
 type NftData struct {
-	Signature string `json:"signature"`
-	Address   string `json:"address"`
-	TokenID   string `json:"tokenId"`
-	ChainID   int    `json:"chainId"`
+	Signature     *string      `json:"signature"`
+	Address       string       `json:"address"`
+	TokenID       string       `json:"tokenId"`
+	ChainID       int          `json:"chainId"`
+	ReservedUntil *time.Time   `json:"reservedUntil"`
+	Voucher       *MintVoucher `json:"voucher"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+type PairingCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 type SearchQuery struct {
 	Name string `json:"name"`
 }
+
+type UserConnection struct {
+	Edges    []*UserEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type UserEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *models.User `json:"node"`
+}
+
+type UserLookup struct {
+	ID            *string `json:"id"`
+	Email         *string `json:"email"`
+	WalletAddress *string `json:"walletAddress"`
+	DeviceID      *string `json:"deviceId"`
+}
+
+type WalletChallenge struct {
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type WalletProof struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+)
+
+// the hard cap of every limit argument - larger limits are reduced to it.
+const MaxPageSize = 100
+
+// the cryptogotchies of a user are not paginated. Most users own a single one.
+const estimatedCryptogotchiesPerUser = 10
+
+// the ownership history is not paginated either. Most nfts are never sold.
+const estimatedTransfersPerCryptogotchi = 5
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// returns the limit to use for the page.
+func pageLimit(offset, limit int) (int, error) {
+	if offset < 0 || limit < 0 {
+		return 0, apperror.NewValidation("offset and limit must not be negative")
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize, nil
+	}
+	return limit, nil
+}
+
+func listComplexity(childComplexity, limit int) int {
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return 1 + childComplexity*limit
+}
+
+// weights every list by its limit. Fields not listed here count 1 plus their children.
+func Complexity() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+	c.Query.Leaderboard = func(childComplexity int, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.Events = func(childComplexity int, cryptogotchiID string, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.Cryptogotchies = func(childComplexity int, query *input.SearchQuery, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.Users = func(childComplexity int, query *input.SearchQuery, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.FlaggedGameStats = func(childComplexity int, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.Query.LeaderboardConnection = func(childComplexity int, first int, after *string) int {
+		return listComplexity(childComplexity, first)
+	}
+	c.Query.EventsConnection = func(childComplexity int, cryptogotchiID string, first int, after *string) int {
+		return listComplexity(childComplexity, first)
+	}
+	c.Query.CryptogotchiesConnection = func(childComplexity int, query *input.SearchQuery, first int, after *string) int {
+		return listComplexity(childComplexity, first)
+	}
+	c.Query.UsersConnection = func(childComplexity int, query *input.SearchQuery, first int, after *string) int {
+		return listComplexity(childComplexity, first)
+	}
+	c.Subscription.LeaderboardChanged = func(childComplexity int, offset int, limit int) int {
+		return listComplexity(childComplexity, limit)
+	}
+	c.User.Cryptogotchies = func(childComplexity int) int {
+		return listComplexity(childComplexity, estimatedCryptogotchiesPerUser)
+	}
+	c.Cryptogotchi.OwnershipHistory = func(childComplexity int) int {
+		return listComplexity(childComplexity, estimatedTransfersPerCryptogotchi)
+	}
+	return c
+}
+
+// rejects operations with deeply nested selections - the complexity does not grow with the depth of single objects.
+type DepthLimit struct {
+	MaxDepth int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(schema graphql.ExecutableSchema) error {
+	if d.MaxDepth < 1 {
+		return fmt.Errorf("DepthLimit.MaxDepth needs to be positive")
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op == nil {
+		// reported by the validation.
+		return nil
+	}
+	if depth := selectionDepth(op.SelectionSet, 0, map[string]bool{}); depth > d.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.MaxDepth)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// fragments are inlined - visited holds the fragments of the current path to stop cycles.
+func selectionDepth(selectionSet ast.SelectionSet, depth int, visited map[string]bool) int {
+	max := depth
+	for _, selection := range selectionSet {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			// the introspection queries of the tools are deeply nested.
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = selectionDepth(s.SelectionSet, depth+1, visited)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet, depth, visited)
+		case *ast.FragmentSpread:
+			if visited[s.Name] || s.Definition == nil {
+				continue
+			}
+			visited[s.Name] = true
+			d = selectionDepth(s.Definition.SelectionSet, depth, visited)
+			delete(visited, s.Name)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
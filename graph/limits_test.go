@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+)
+
+func newExecutor(extensions ...graphql.HandlerExtension) *executor.Executor {
+	exec := executor.New(generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{}, Directives: Directives(), Complexity: Complexity()}))
+	for _, extension := range extensions {
+		exec.Use(extension)
+	}
+	return exec
+}
+
+func operationErrorCode(exec *executor.Executor, params *graphql.RawParams) interface{} {
+	_, errs := exec.CreateOperationContext(graphql.StartOperationTrace(context.Background()), params)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0].Extensions["code"]
+}
+
+func TestPageLimit(t *testing.T) {
+	limit, err := pageLimit(0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, limit)
+
+	limit, err = pageLimit(20, 10000)
+	assert.Nil(t, err)
+	assert.Equal(t, MaxPageSize, limit)
+
+	_, err = pageLimit(-1, 10)
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+	_, err = pageLimit(0, -1)
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+}
+
+func TestDepthLimit(t *testing.T) {
+	exec := newExecutor(DepthLimit{MaxDepth: 3})
+
+	assert.Nil(t, operationErrorCode(exec, &graphql.RawParams{Query: `{ users(offset: 0, limit: 1) { cryptogotchies { id } } }`}))
+	assert.Equal(t, errDepthLimit, operationErrorCode(exec, &graphql.RawParams{Query: `{ users(offset: 0, limit: 1) { cryptogotchies { attributes { species } } } }`}))
+	// fragments do not hide the depth.
+	assert.Equal(t, errDepthLimit, operationErrorCode(exec, &graphql.RawParams{Query: `
+		{ users(offset: 0, limit: 1) { ...koi } }
+		fragment koi on User { cryptogotchies { attributes { species } } }
+	`}))
+	// introspection is not limited.
+	assert.Nil(t, operationErrorCode(exec, &graphql.RawParams{Query: `{ __schema { types { fields { type { ofType { name } } } } } }`}))
+}
+
+func TestComplexityUsesTheLimitArgument(t *testing.T) {
+	exec := newExecutor(extension.FixedComplexityLimit(500))
+
+	assert.Nil(t, operationErrorCode(exec, &graphql.RawParams{Query: `{ leaderboard(offset: 0, limit: 10) { id name rank } }`}))
+	assert.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", operationErrorCode(exec, &graphql.RawParams{Query: `{ leaderboard(offset: 0, limit: 100) { id name rank ownerAddress food } }`}))
+	// the limit is capped - larger limits are not more expensive.
+	assert.Equal(t, listComplexity(5, MaxPageSize), listComplexity(5, 100000))
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/dataloader"
+	"gorm.io/gorm"
+)
+
+type loadersCtxKey struct{}
+
+const (
+	// the resolvers of a list run concurrently - a short wait is enough to collect their keys.
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// batches the lookups of the field resolvers. A leaderboard page results in a single query per loader.
+type Loaders struct {
+	users                 *dataloader.Loader[string, models.User]
+	cryptogotchies        *dataloader.Loader[string, models.Cryptogotchi]
+	cryptogotchiesByOwner *dataloader.Loader[string, []models.Cryptogotchi]
+	ownershipHistory      *dataloader.Loader[string, []models.OwnershipTransfer]
+}
+
+func NewLoaders(userSvc service.UserSvc, cryptogotchiSvc service.CryptogotchiSvc) *Loaders {
+	return &Loaders{
+		users: dataloader.New(func(ids []string) (map[string]models.User, error) {
+			users, err := userSvc.GetByIds(ids)
+			res := make(map[string]models.User, len(users))
+			for _, user := range users {
+				res[user.Id.String()] = user
+			}
+			return res, err
+		}, loaderWait, loaderMaxBatch),
+		cryptogotchies: dataloader.New(func(ids []string) (map[string]models.Cryptogotchi, error) {
+			cryptogotchies, err := cryptogotchiSvc.GetByIds(ids)
+			res := make(map[string]models.Cryptogotchi, len(cryptogotchies))
+			for _, cryptogotchi := range cryptogotchies {
+				res[cryptogotchi.Id.String()] = cryptogotchi
+			}
+			return res, err
+		}, loaderWait, loaderMaxBatch),
+		cryptogotchiesByOwner: dataloader.New(func(ownerIds []string) (map[string][]models.Cryptogotchi, error) {
+			cryptogotchies, err := cryptogotchiSvc.GetByOwnerIds(ownerIds)
+			res := make(map[string][]models.Cryptogotchi, len(ownerIds))
+			for _, cryptogotchi := range cryptogotchies {
+				ownerId := cryptogotchi.OwnerId.String()
+				res[ownerId] = append(res[ownerId], cryptogotchi)
+			}
+			return res, err
+		}, loaderWait, loaderMaxBatch),
+		ownershipHistory: dataloader.New(func(cryptogotchiIds []string) (map[string][]models.OwnershipTransfer, error) {
+			transfers, err := cryptogotchiSvc.GetOwnershipTransfers(cryptogotchiIds)
+			res := make(map[string][]models.OwnershipTransfer, len(cryptogotchiIds))
+			for _, transfer := range transfers {
+				cryptogotchiId := transfer.CryptogotchiId.String()
+				res[cryptogotchiId] = append(res[cryptogotchiId], transfer)
+			}
+			return res, err
+		}, loaderWait, loaderMaxBatch),
+	}
+}
+
+// forgets every loaded value - used before the next state of a subscription is resolved.
+func (l *Loaders) Clear() {
+	l.users.Clear()
+	l.cryptogotchies.Clear()
+	l.cryptogotchiesByOwner.Clear()
+	l.ownershipHistory.Clear()
+}
+
+// attaches new loaders to every operation.
+// every root field of a mutation gets its own loaders - the previous mutation might have changed the values.
+func LoaderMiddleware(userSvc service.UserSvc, cryptogotchiSvc service.CryptogotchiSvc) (graphql.OperationMiddleware, graphql.RootFieldMiddleware) {
+	operations := func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		return next(context.WithValue(ctx, loadersCtxKey{}, NewLoaders(userSvc, cryptogotchiSvc)))
+	}
+	rootFields := func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+		if graphql.GetOperationContext(ctx).Operation.Operation == ast.Mutation {
+			ctx = context.WithValue(ctx, loadersCtxKey{}, NewLoaders(userSvc, cryptogotchiSvc))
+		}
+		return next(ctx)
+	}
+	return operations, rootFields
+}
+
+// returns the loaders of the operation.
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if loaders, ok := ctx.Value(loadersCtxKey{}).(*Loaders); ok {
+		return loaders
+	}
+	// not attached in tests - nothing gets batched.
+	return NewLoaders(r.userSvc, r.cryptogotchiSvc)
+}
+
+func (r *Resolver) loadUser(ctx context.Context, id string) (models.User, error) {
+	user, found, err := r.loaders(ctx).users.Load(id)
+	if err == nil && !found {
+		err = gorm.ErrRecordNotFound
+	}
+	return user, err
+}
+
+func (r *Resolver) loadCryptogotchi(ctx context.Context, id string) (models.Cryptogotchi, error) {
+	cryptogotchi, found, err := r.loaders(ctx).cryptogotchies.Load(id)
+	if err == nil && !found {
+		err = gorm.ErrRecordNotFound
+	}
+	return cryptogotchi, err
+}
+
+func (r *Resolver) loadCryptogotchiesOfOwner(ctx context.Context, ownerId string) ([]models.Cryptogotchi, error) {
+	cryptogotchies, _, err := r.loaders(ctx).cryptogotchiesByOwner.Load(ownerId)
+	return cryptogotchies, err
+}
+
+// oldest first - empty for cryptogotchies which were never minted.
+func (r *Resolver) loadOwnershipHistory(ctx context.Context, cryptogotchiId string) ([]models.OwnershipTransfer, error) {
+	transfers, _, err := r.loaders(ctx).ownershipHistory.Load(cryptogotchiId)
+	return transfers, err
+}
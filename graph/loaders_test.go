@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+type countingUserSvc struct {
+	service.UserSvc
+	mu    sync.Mutex
+	calls int
+	users []models.User
+}
+
+func (svc *countingUserSvc) GetByIds(ids []string) ([]models.User, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.calls++
+	var res []models.User
+	for _, user := range svc.users {
+		for _, id := range ids {
+			if user.Id.String() == id {
+				res = append(res, user)
+			}
+		}
+	}
+	return res, nil
+}
+
+func newUser(walletAddress string) models.User {
+	return models.User{Base: models.Base{Id: uuid.New()}, WalletAddress: util.Str(walletAddress)}
+}
+
+func TestOwnerAddressIsBatched(t *testing.T) {
+	owners := []models.User{newUser("0x1"), newUser("0x2")}
+	userSvc := &countingUserSvc{users: owners}
+	r := &Resolver{userSvc: userSvc}
+	ctx := context.WithValue(context.Background(), loadersCtxKey{}, NewLoaders(userSvc, nil))
+
+	// a leaderboard page - the field resolvers run concurrently.
+	cryptogotchies := []models.Cryptogotchi{models.NewCryptogotchi(&owners[0]), models.NewCryptogotchi(&owners[1]), models.NewCryptogotchi(&owners[0])}
+	addresses := make([]*string, len(cryptogotchies))
+	wg := sync.WaitGroup{}
+	wg.Add(len(cryptogotchies))
+	for i := range cryptogotchies {
+		go func(i int) {
+			defer wg.Done()
+			addresses[i], _ = (&cryptogotchiResolver{r}).OwnerAddress(ctx, &cryptogotchies[i])
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, userSvc.calls)
+	assert.Equal(t, "0x1", *addresses[0])
+	assert.Equal(t, "0x2", *addresses[1])
+	assert.Equal(t, "0x1", *addresses[2])
+}
+
+func TestLoadUserNotFound(t *testing.T) {
+	userSvc := &countingUserSvc{}
+	r := &Resolver{userSvc: userSvc}
+
+	_, err := r.loadUser(context.Background(), uuid.NewString())
+	assert.True(t, db.IsNotFound(err))
+}
+
+func TestOwnerOfParkedCryptogotchi(t *testing.T) {
+	userSvc := &countingUserSvc{}
+	r := &Resolver{userSvc: userSvc}
+	// the nft was sold to a wallet which is not connected to a user.
+	crypt := models.Cryptogotchi{OwnerWalletAddress: util.Str("0x3")}
+
+	address, err := (&cryptogotchiResolver{r}).OwnerAddress(context.Background(), &crypt)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x3", *address)
+	ownerId, err := (&cryptogotchiResolver{r}).OwnerID(context.Background(), &crypt)
+	assert.Nil(t, err)
+	assert.Nil(t, ownerId)
+	assert.Equal(t, 0, userSvc.calls)
+}
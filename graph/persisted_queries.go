@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	errPersistedQueryNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	errPersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// serves the queries of a manifest - the sha256 hash of the query mapped to the query.
+// the clients send the hash using the persistedQuery extension of apollo:
+// { "extensions": { "persistedQuery": { "version": 1, "sha256Hash": "..." } } }
+// replaces the automatic persisted queries: clients can not add queries at runtime.
+type PersistedQueries struct {
+	queries map[string]string
+	// rejects every query which is not part of the manifest.
+	Enforce bool
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = PersistedQueries{}
+
+func hashQuery(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+func NewPersistedQueries(queries map[string]string, enforce bool) (PersistedQueries, error) {
+	for hash, query := range queries {
+		if hashQuery(query) != hash {
+			return PersistedQueries{}, fmt.Errorf("the hash %s does not match its query", hash)
+		}
+	}
+	return PersistedQueries{queries: queries, Enforce: enforce}, nil
+}
+
+// reads the manifest generated by the build of the app.
+func LoadPersistedQueries(path string, enforce bool) (PersistedQueries, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return PersistedQueries{}, err
+	}
+	var queries map[string]string
+	if err := json.Unmarshal(b, &queries); err != nil {
+		return PersistedQueries{}, err
+	}
+	return NewPersistedQueries(queries, enforce)
+}
+
+func (p PersistedQueries) ExtensionName() string {
+	return "PersistedQueries"
+}
+
+func (p PersistedQueries) Validate(schema graphql.ExecutableSchema) error {
+	if p.queries == nil {
+		return fmt.Errorf("PersistedQueries needs to be created using NewPersistedQueries")
+	}
+	return nil
+}
+
+func (p PersistedQueries) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	var hash string
+	if extension, ok := rawParams.Extensions["persistedQuery"].(map[string]interface{}); ok {
+		hash, _ = extension["sha256Hash"].(string)
+	}
+
+	if rawParams.Query == "" && hash != "" {
+		query, ok := p.queries[hash]
+		if !ok {
+			err := gqlerror.Errorf("PersistedQueryNotFound")
+			errcode.Set(err, errPersistedQueryNotFound)
+			return err
+		}
+		rawParams.Query = query
+		return nil
+	}
+
+	if hash != "" && hashQuery(rawParams.Query) != hash {
+		return gqlerror.Errorf("provided persisted query hash does not match query")
+	}
+	if p.Enforce {
+		if _, ok := p.queries[hashQuery(rawParams.Query)]; !ok {
+			err := gqlerror.Errorf("only persisted queries are allowed")
+			errcode.Set(err, errPersistedQueryNotAllowed)
+			return err
+		}
+	}
+	return nil
+}
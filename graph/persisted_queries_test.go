@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+const leaderboardQuery = `{ leaderboard(offset: 0, limit: 10) { id } }`
+
+func persistedQueryParams(hash string) *graphql.RawParams {
+	return &graphql.RawParams{Extensions: map[string]interface{}{
+		"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+	}}
+}
+
+func TestNewPersistedQueriesVerifiesHashes(t *testing.T) {
+	_, err := NewPersistedQueries(map[string]string{"abc": leaderboardQuery}, false)
+	assert.NotNil(t, err)
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"`+hashQuery(leaderboardQuery)+`": "{ leaderboard(offset: 0, limit: 10) { id } }"}`), 0600))
+
+	queries, err := LoadPersistedQueries(path, true)
+	assert.Nil(t, err)
+	assert.True(t, queries.Enforce)
+	assert.Equal(t, leaderboardQuery, queries.queries[hashQuery(leaderboardQuery)])
+}
+
+func TestPersistedQueriesResolvesHashes(t *testing.T) {
+	queries, err := NewPersistedQueries(map[string]string{hashQuery(leaderboardQuery): leaderboardQuery}, false)
+	assert.Nil(t, err)
+	exec := newExecutor(queries)
+
+	params := persistedQueryParams(hashQuery(leaderboardQuery))
+	assert.Nil(t, operationErrorCode(exec, params))
+	assert.Equal(t, leaderboardQuery, params.Query)
+
+	assert.Equal(t, errPersistedQueryNotFound, operationErrorCode(exec, persistedQueryParams(hashQuery("{ leaderboard { id } }"))))
+	// not enforced - every query is allowed.
+	assert.Nil(t, operationErrorCode(exec, &graphql.RawParams{Query: `{ leaderboard(offset: 0, limit: 5) { id } }`}))
+}
+
+func TestPersistedQueriesEnforced(t *testing.T) {
+	queries, err := NewPersistedQueries(map[string]string{hashQuery(leaderboardQuery): leaderboardQuery}, true)
+	assert.Nil(t, err)
+	exec := newExecutor(queries)
+
+	assert.Nil(t, operationErrorCode(exec, &graphql.RawParams{Query: leaderboardQuery}))
+	assert.Equal(t, errPersistedQueryNotAllowed, operationErrorCode(exec, &graphql.RawParams{Query: `{ leaderboard(offset: 0, limit: 100) { id } }`}))
+}
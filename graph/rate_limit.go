@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/ratelimit"
+)
+
+// limits the root fields - the name of the field selects the policy. Fields without a policy are not limited.
+// authenticated requests are limited per user, anonymous ones per client ip.
+type RateLimit struct {
+	Limiter *ratelimit.Limiter
+}
+
+var _ interface {
+	graphql.RootFieldInterceptor
+	graphql.HandlerExtension
+} = RateLimit{}
+
+func (l RateLimit) ExtensionName() string {
+	return "RateLimit"
+}
+
+func (l RateLimit) Validate(schema graphql.ExecutableSchema) error {
+	if l.Limiter == nil {
+		return fmt.Errorf("RateLimit.Limiter needs to be set")
+	}
+	return nil
+}
+
+func rateLimitKey(ctx context.Context) string {
+	if user := optionalUser(ctx); user != nil {
+		return "user:" + user.Id.String()
+	}
+	clientIp, _ := ctx.Value(config.CLIENT_IP_CTX_KEY).(string)
+	return "ip:" + clientIp
+}
+
+func (l RateLimit) InterceptRootField(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx).Field
+	res := l.Limiter.Allow(field.Name, rateLimitKey(ctx))
+	if !res.Allowed {
+		// the field context does not exist yet - the path is set explicitly.
+		graphql.AddError(ctx, gqlerror.WrapPath(
+			ast.Path{ast.PathName(field.Alias)},
+			apperror.NewRateLimited(res.RetryAt, "too many requests for %s", field.Name),
+		))
+		return graphql.Null
+	}
+	return next(ctx)
+}
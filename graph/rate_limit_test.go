@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/ratelimit"
+)
+
+func execute(t *testing.T, ctx context.Context, extension graphql.HandlerExtension, query string) *graphql.Response {
+	exec := newExecutor(extension)
+	exec.SetErrorPresenter(ErrorPresenter)
+	ctx = graphql.StartOperationTrace(ctx)
+	rc, errs := exec.CreateOperationContext(ctx, &graphql.RawParams{Query: query})
+	assert.Empty(t, errs)
+	responses, ctx := exec.DispatchOperation(ctx, rc)
+	return responses(ctx)
+}
+
+func TestRateLimitPerUser(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Policy{"self": {Limit: 1, Period: time.Minute}})
+	extension := RateLimit{Limiter: limiter}
+	ctx := withUser(&models.User{Base: models.Base{Id: uuid.New()}, Name: "koi"})
+
+	res := execute(t, ctx, extension, `{ self { name } }`)
+	assert.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"self": {"name": "koi"}}`, string(res.Data))
+
+	res = execute(t, ctx, extension, `{ me: self { name } }`)
+	assert.Len(t, res.Errors, 1)
+	assert.Equal(t, "RATE_LIMITED", res.Errors[0].Extensions["code"])
+	assert.NotEmpty(t, res.Errors[0].Extensions["retryAt"])
+	assert.Equal(t, "me", res.Errors[0].Path.String())
+
+	// another user owns another bucket.
+	res = execute(t, withUser(&models.User{Base: models.Base{Id: uuid.New()}, Name: "koi"}), extension, `{ self { name } }`)
+	assert.Empty(t, res.Errors)
+}
+
+func TestRateLimitKey(t *testing.T) {
+	user := &models.User{Base: models.Base{Id: uuid.New()}}
+	assert.Equal(t, "user:"+user.Id.String(), rateLimitKey(withUser(user)))
+	assert.Equal(t, "ip:10.0.0.1", rateLimitKey(context.WithValue(context.Background(), config.CLIENT_IP_CTX_KEY, "10.0.0.1")))
+}
@@ -2,14 +2,18 @@ package graph
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/vektah/gqlparser/v2/gqlerror"
-	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
@@ -23,48 +27,228 @@ type Resolver struct {
 	userSvc         service.UserSvc
 	gameSvc         service.GameSvc
 	authSvc         service.AuthSvc
+	liveUpdateSvc   service.LiveUpdateSvc
 	cryptokoiApi    cryptokoi.CryptoKoiApi
 	generator       generator.Generator
 	logger          *logrus.Entry
 	chainId         int
+	// a voucher of a reservation expires together with the reservation.
+	voucherTtl time.Duration
+	// issues the untyped signatures of the legacy redeem function as well.
+	legacyVouchers bool
 }
 
 func NewResolver(
 	chainId int,
+	voucherTtl time.Duration,
+	legacyVouchers bool,
 	userSvc service.UserSvc,
 	eventSvc service.EventSvc,
 	cryptogotchiSvc service.CryptogotchiSvc,
 	gameSvc service.GameSvc,
 	authSvc service.AuthSvc,
+	liveUpdateSvc service.LiveUpdateSvc,
 	cryptokoiApi cryptokoi.CryptoKoiApi,
 	generator generator.Generator,
 ) Resolver {
 	return Resolver{
 		chainId:         chainId,
+		voucherTtl:      voucherTtl,
+		legacyVouchers:  legacyVouchers,
 		eventSvc:        eventSvc,
 		userSvc:         userSvc,
 		cryptogotchiSvc: cryptogotchiSvc,
 		gameSvc:         gameSvc,
 		authSvc:         authSvc,
+		liveUpdateSvc:   liveUpdateSvc,
 		cryptokoiApi:    cryptokoiApi,
 		generator:       generator,
 		logger:          orchardclient.Logger.WithField("package", "graph"),
 	}
 }
 
+// maps the not found error of the repositories to a NOT_FOUND error.
+// other errors are internal errors and returned as is.
+func notFound(err error, format string, args ...interface{}) error {
+	if db.IsNotFound(err) {
+		return apperror.Wrap(apperror.NotFound, err, format, args...)
+	}
+	return err
+}
+
+// the events are only returned to the owner.
+func (r *Resolver) publicCryptogotchi(ctx context.Context, cryptogotchiId string) (*models.Cryptogotchi, error) {
+	currentUser := optionalUser(ctx)
+	cryptogotchi, err := r.loadCryptogotchi(ctx, cryptogotchiId)
+	if err != nil {
+		return nil, notFound(err, "could not find cryptogotchi with id %s", cryptogotchiId)
+	}
+
+	// check if the cryptogotchi belongs to the current user
+	if currentUser == nil || !cryptogotchi.IsOwnedBy(currentUser.Id) {
+		// remove the events from the history
+		// privacy policy :-)
+		cryptogotchi.Events = nil
+	}
+	return &cryptogotchi, nil
+}
+
+func (r *Resolver) leaderboardPage(offset, limit int) ([]*models.Cryptogotchi, error) {
+	limit, err := pageLimit(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	cryptogotchis, err := r.cryptogotchiSvc.GetCachedLeaderboard(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Cryptogotchi, len(cryptogotchis))
+	for i, c := range cryptogotchis {
+		tmp := c
+		res[i] = &tmp
+	}
+	return res, nil
+}
+
+// only the owner is allowed to see the events of a cryptogotchi.
+func (r *Resolver) checkCryptogotchiOwner(ctx context.Context, cryptogotchiId string) error {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	cryptogotchi, err := r.cryptogotchiSvc.GetById(cryptogotchiId)
+	if err != nil {
+		return notFound(err, "could not find cryptogotchi with id %s", cryptogotchiId)
+	}
+	if !cryptogotchi.IsOwnedBy(user.Id) {
+		return apperror.NewForbidden("you are not the owner of this cryptogotchi")
+	}
+	return nil
+}
+
 func (r *Resolver) checkCryptogotchiInteractable(ctx context.Context, cryptogotchiId string) (models.Cryptogotchi, error) {
 	// check if we are allowed to interact
 	cryptogotchi, err := r.cryptogotchiSvc.GetById(cryptogotchiId)
+	if err != nil {
+		return cryptogotchi, notFound(err, "could not find cryptogotchi with id %s", cryptogotchiId)
+	}
+	currentUser, err := CurrentUser(ctx)
 	if err != nil {
 		return cryptogotchi, err
 	}
-	currentUser := ctx.Value(config.USER_CTX_KEY).(*models.User)
-	if cryptogotchi.OwnerId != currentUser.Id {
-		return cryptogotchi, gqlerror.Errorf("you are not the owner of this cryptogotchi")
+	if !cryptogotchi.IsOwnedBy(currentUser.Id) {
+		return cryptogotchi, apperror.NewForbidden("you are not the owner of this cryptogotchi")
 	}
 
 	if !cryptogotchi.IsAlive() {
-		return cryptogotchi, (gqlerror.Errorf("this cryptogotchi is already dead"))
+		return cryptogotchi, apperror.NewValidation("this cryptogotchi is already dead")
 	}
 	return cryptogotchi, nil
 }
+
+// connects the wallet to the current user after verifying the proof of ownership.
+// the proof can only be used once.
+func (r *Resolver) connectProvenWallet(ctx context.Context, walletAddress string, proof input.WalletProof) (*models.User, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.authSvc.VerifyWalletOwnership(user, walletAddress, proof.Message, proof.Signature); err != nil {
+		r.logger.Warnf("wallet ownership not proven for user %s: %s", user.Id, err)
+		return nil, apperror.Wrap(apperror.Validation, err, "%s", err)
+	}
+
+	// the wallet addresses are stored lower cased.
+	lowerCasedWalletAddress := strings.ToLower(walletAddress)
+	if user.WalletAddress != nil && lowerCasedWalletAddress == strings.ToLower(*user.WalletAddress) {
+		if user.WalletVerifiedAt != nil {
+			return user, nil
+		}
+	} else {
+		// check if a user does already exist.
+		existing, err := r.userSvc.GetByWalletAddress(lowerCasedWalletAddress)
+		if err == nil && existing.WalletVerifiedAt != nil {
+			r.logger.Errorf("wallet: [%s] already connected", walletAddress)
+			// there is already a user with this wallet address.
+			return nil, apperror.NewValidation("wallet: [%s] already connected", walletAddress)
+		}
+		if err == nil {
+			// the other user never proved the wallet - it belongs to the current user.
+			r.logger.Warnf("removing the unproven wallet [%s] from user %s", walletAddress, existing.Id)
+			existing.WalletAddress = nil
+			if err := r.userSvc.Save(&existing); err != nil {
+				return nil, err
+			}
+		} else if !db.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	user.WalletAddress = &lowerCasedWalletAddress
+	user.WalletVerifiedAt = &now
+	if err := r.userSvc.Save(user); err != nil {
+		return nil, err
+	}
+	// the wallet might have received nfts before.
+	return user, r.cryptogotchiSvc.ClaimWalletCryptogotchies(user)
+}
+
+// the wallet of the user needs to be proven before.
+func (r *Resolver) getNftSignature(ctx context.Context, user *models.User, cryptogotchiId string) (*input.NftData, error) {
+	// only the owner is allowed to transform the cryptogotchi into an nft.
+	cryptogotchi, err := r.checkCryptogotchiInteractable(ctx, cryptogotchiId)
+	if err != nil {
+		return nil, err
+	}
+
+	// deleted by the cleanup soon.
+	if cryptogotchi.ReservedUntil != nil && cryptogotchi.ReservedUntil.Before(time.Now()) {
+		return nil, apperror.New(apperror.Gone, "the reservation expired - create a new cryptogotchi")
+	}
+
+	tokenId, err := util.UuidToUint256(cryptogotchi.Id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// the legacy signatures never expire - only issued during the migration of the apps.
+	var signature *string
+	if r.legacyVouchers {
+		legacySignature, _, err := r.cryptokoiApi.GetNftSignatureForCryptogotchi(cryptogotchi.Id.String(), *user.WalletAddress)
+		if err != nil {
+			return nil, err
+		}
+		signature = &legacySignature
+	}
+
+	price, err := r.cryptokoiApi.GetPrice()
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Internal, err, "could not read the price of the contract")
+	}
+
+	expiry := time.Now().Add(r.voucherTtl)
+	if cryptogotchi.ReservedUntil != nil && cryptogotchi.ReservedUntil.Before(expiry) {
+		expiry = *cryptogotchi.ReservedUntil
+	}
+
+	voucher, voucherSignature, err := r.cryptokoiApi.GetVoucherForCryptogotchi(cryptogotchi.Id.String(), *user.WalletAddress, price, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &input.NftData{
+		Signature:     signature,
+		TokenID:       tokenId.String(),
+		Address:       *user.WalletAddress,
+		ChainID:       r.chainId,
+		ReservedUntil: cryptogotchi.ReservedUntil,
+		Voucher: &input.MintVoucher{
+			ContractAddress: r.cryptokoiApi.ContractAddress().Hex(),
+			Price:           voucher.Price.String(),
+			ExpiresAt:       voucher.Expiry,
+			Signature:       voucherSignature,
+		},
+	}, nil
+}
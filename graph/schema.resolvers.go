@@ -5,16 +5,17 @@ package graph
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
-	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 )
 
@@ -58,7 +59,10 @@ func (r *cryptogotchiResolver) Color(ctx context.Context, obj *models.Cryptogotc
 }
 
 func (r *cryptogotchiResolver) OwnerAddress(ctx context.Context, obj *models.Cryptogotchi) (*string, error) {
-	owner, err := r.userSvc.GetById(obj.OwnerId.String())
+	if obj.OwnerId == nil {
+		return obj.OwnerWalletAddress, nil
+	}
+	owner, err := r.loadUser(ctx, obj.OwnerId.String())
 	if err != nil {
 		return nil, err
 	}
@@ -66,8 +70,11 @@ func (r *cryptogotchiResolver) OwnerAddress(ctx context.Context, obj *models.Cry
 	return owner.WalletAddress, err
 }
 
-func (r *cryptogotchiResolver) OwnerID(ctx context.Context, obj *models.Cryptogotchi) (string, error) {
-	return obj.OwnerId.String(), nil
+func (r *cryptogotchiResolver) OwnerID(ctx context.Context, obj *models.Cryptogotchi) (*string, error) {
+	if obj.OwnerId == nil {
+		return nil, nil
+	}
+	return util.Str(obj.OwnerId.String()), nil
 }
 
 func (r *cryptogotchiResolver) Attributes(ctx context.Context, obj *models.Cryptogotchi) (*input.CryptogotchiAttributes, error) {
@@ -91,6 +98,18 @@ func (r *cryptogotchiResolver) Attributes(ctx context.Context, obj *models.Crypt
 	}, nil
 }
 
+func (r *cryptogotchiResolver) OwnershipHistory(ctx context.Context, obj *models.Cryptogotchi) ([]*models.OwnershipTransfer, error) {
+	transfers, err := r.loadOwnershipHistory(ctx, obj.Id.String())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.OwnershipTransfer, len(transfers))
+	for i := range transfers {
+		res[i] = &transfers[i]
+	}
+	return res, nil
+}
+
 func (r *eventResolver) ID(ctx context.Context, obj *models.Event) (string, error) {
 	return obj.Id.String(), nil
 }
@@ -126,7 +145,7 @@ func (r *mutationResolver) Feed(ctx context.Context, cryptogotchiID string) (*mo
 	// get the last time it was fed.
 	nextFeedingTime := cryptogotchi.GetNextFeedingTime()
 	if !nextFeedingTime.Before(time.Now()) {
-		return &cryptogotchi, gqlerror.Errorf("it is not time to feed yet")
+		return nil, apperror.NewCooldown(nextFeedingTime, "it is not time to feed yet")
 	}
 
 	// finally feed it.
@@ -142,8 +161,11 @@ func (r *mutationResolver) Feed(ctx context.Context, cryptogotchiID string) (*mo
 	feedEvent.Apply(&cryptogotchi)
 
 	err = r.cryptogotchiSvc.Save(&cryptogotchi)
-
-	return &cryptogotchi, err
+	if err != nil {
+		return nil, err
+	}
+	r.liveUpdateSvc.CryptogotchiUpdated(cryptogotchi.Id)
+	return &cryptogotchi, nil
 }
 
 func (r *mutationResolver) StartGame(ctx context.Context, cryptogotchiID string, gameType string) (*input.GameStartResponse, error) {
@@ -156,7 +178,7 @@ func (r *mutationResolver) StartGame(ctx context.Context, cryptogotchiID string,
 	// check if valid game type.
 	parsedGameType, err := models.IsGameType(gameType)
 	if err != nil {
-		return nil, err
+		return nil, apperror.Wrap(apperror.Validation, err, "unknown game type: %s", gameType)
 	}
 
 	_, token, err := r.gameSvc.StartGame(&cryptogotchi, models.GameType(parsedGameType))
@@ -172,7 +194,7 @@ func (r *mutationResolver) StartGame(ctx context.Context, cryptogotchiID string,
 func (r *mutationResolver) FinishGame(ctx context.Context, token string, score float64) (*models.Cryptogotchi, error) {
 	game, err := r.gameSvc.GetGameByToken(token)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "could not find the game")
 	}
 
 	// check if the cryptogotchi is interactable
@@ -192,127 +214,138 @@ func (r *mutationResolver) FinishGame(ctx context.Context, token string, score f
 }
 
 func (r *mutationResolver) ChangeCryptogotchiName(ctx context.Context, id string, newName string) (*models.Cryptogotchi, error) {
-	cryptogotchi, err := r.cryptogotchiSvc.GetById(id)
+	cryptogotchi, err := r.checkCryptogotchiInteractable(ctx, id)
 	if err != nil {
-		return nil, gqlerror.Errorf("could not find cryptogotchi with id %s", id)
+		return nil, err
 	}
 
-	cryptogotchi.Name = &newName
-	err = r.cryptogotchiSvc.Save(&cryptogotchi)
+	err = r.cryptogotchiSvc.Rename(&cryptogotchi, newName)
 	return &cryptogotchi, err
 }
 
 func (r *mutationResolver) ChangeUserName(ctx context.Context, newName string) (*models.User, error) {
-	currentUser := ctx.Value(config.USER_CTX_KEY).(*models.User)
-	currentUser.Name = newName
-	err := r.userSvc.Save(currentUser)
+	currentUser, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = r.userSvc.Rename(currentUser, newName)
 	return currentUser, err
 }
 
-func (r *mutationResolver) GetNftSignature(ctx context.Context, id string, address string) (*input.NftData, error) {
-	cryptogotchi, err := r.checkCryptogotchiInteractable(ctx, id)
+func (r *mutationResolver) WalletChallenge(ctx context.Context, walletAddress string) (*input.WalletChallenge, error) {
+	user, err := CurrentUser(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("could not find cryptogotchi with id %s", id)
+		return nil, err
 	}
 
-	user, err := r.ConnectWallet(ctx, address)
+	message, expiresAt, err := r.authSvc.CreateWalletChallenge(user, walletAddress)
 	if err != nil {
 		return nil, err
 	}
+	return &input.WalletChallenge{
+		Message:   message,
+		ExpiresAt: expiresAt,
+	}, nil
+}
 
-	if user.Id.String() != cryptogotchi.OwnerId.String() {
-		// the current user is not the owner.
-		// only the owner is allowed to transform the cryptogotchi into an nft.
-		return nil, fmt.Errorf("only the owner is allowed to transform the cryptogotchi into an nft")
-	}
-
-	signature, tokenId, err := r.cryptokoiApi.GetNftSignatureForCryptogotchi(cryptogotchi.Id.String(), *user.WalletAddress)
-
+func (r *mutationResolver) GetNftSignature(ctx context.Context, id string, address string, proof input.WalletProof) (*input.NftData, error) {
+	user, err := r.connectProvenWallet(ctx, address, proof)
 	if err != nil {
 		return nil, err
 	}
-
-	return &input.NftData{
-		Signature: signature,
-		TokenID:   tokenId,
-		Address:   *user.WalletAddress,
-		ChainID:   r.chainId,
-	}, nil
+	return r.getNftSignature(ctx, user, id)
 }
 
-func (r *mutationResolver) CreateCryptogotchi(ctx context.Context, walletAddress string) (*input.NftData, error) {
-	user, err := r.ConnectWallet(ctx, walletAddress)
+func (r *mutationResolver) CreateCryptogotchi(ctx context.Context, walletAddress string, proof input.WalletProof) (*input.NftData, error) {
+	user, err := r.connectProvenWallet(ctx, walletAddress, proof)
 	if err != nil {
 		return nil, err
 	}
-	// mark the cryptogotchi as "inactive" - the user first has to buy it.
-	cryptogotchi, err := r.cryptogotchiSvc.GenerateCryptogotchiForUser(user, false)
+	// the cryptogotchi is inactive until the user bought it.
+	cryptogotchi, err := r.cryptogotchiSvc.Reserve(user)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.GetNftSignature(ctx, cryptogotchi.Id.String(), walletAddress)
+	return r.getNftSignature(ctx, user, cryptogotchi.Id.String())
 }
 
-func (r *mutationResolver) ConnectWallet(ctx context.Context, walletAddress string) (*models.User, error) {
-	user := ctx.Value(config.USER_CTX_KEY).(*models.User)
-	if user == nil {
-		r.logger.Errorf("could not find user in context")
-		return nil, fmt.Errorf("user not found")
-	}
-	if user.WalletAddress != nil && walletAddress == *user.WalletAddress {
-		return user, nil
-	}
-	// check if a user does already exist.
-	_, err := r.userSvc.GetByWalletAddress(walletAddress)
+func (r *mutationResolver) ConnectWallet(ctx context.Context, walletAddress string, proof input.WalletProof) (*models.User, error) {
+	return r.connectProvenWallet(ctx, walletAddress, proof)
+}
 
-	if err == nil {
-		r.logger.Errorf("wallet: [%s] already connected", walletAddress)
-		// there is already a user with this wallet address.
-		return nil, fmt.Errorf("wallet: [%s] already connected", walletAddress)
+func (r *mutationResolver) AcceptPushNotifications(ctx context.Context, pushNotificationToken string) (*models.User, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	user.WalletAddress = &walletAddress
+	user.PushNotificationToken = &pushNotificationToken
 	err = r.userSvc.Save(user)
+
 	return user, err
 }
 
-func (r *mutationResolver) AcceptPushNotifications(ctx context.Context, pushNotificationToken string) (*models.User, error) {
-	user := ctx.Value(config.USER_CTX_KEY).(*models.User)
-	if user == nil {
-		r.logger.Errorf("could not find user in context")
-		return nil, fmt.Errorf("user not found")
+func (r *mutationResolver) CreatePairingCode(ctx context.Context) (*input.PairingCode, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	user.PushNotificationToken = &pushNotificationToken
-	err := r.userSvc.Save(user)
+	code, expiresAt, err := r.authSvc.CreatePairingCode(user)
+	if err != nil {
+		return nil, err
+	}
+	return &input.PairingCode{
+		Code:      code,
+		ExpiresAt: expiresAt,
+	}, nil
+}
 
-	return user, err
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.authSvc.RevokeSession(user, id)
+	if errors.Is(err, service.ErrSessionNotFound) {
+		return false, apperror.NewNotFound("session not found")
+	}
+	return err == nil, err
+}
+
+func (r *ownershipTransferResolver) BlockNumber(ctx context.Context, obj *models.OwnershipTransfer) (int, error) {
+	return int(obj.BlockNumber), nil
 }
 
 func (r *queryResolver) Leaderboard(ctx context.Context, offset int, limit int) ([]*models.Cryptogotchi, error) {
-	cryptogotchis, err := r.cryptogotchiSvc.GetCachedLeaderboard(offset, limit)
+	return r.leaderboardPage(offset, limit)
+}
+
+func (r *queryResolver) LeaderboardConnection(ctx context.Context, first int, after *string) (*input.CryptogotchiConnection, error) {
+	first, err := firstLimit(first)
 	if err != nil {
 		return nil, err
 	}
-	res := make([]*models.Cryptogotchi, len(cryptogotchis))
-	for i, c := range cryptogotchis {
-		tmp := c
-		res[i] = &tmp
+	key, err := decodeCursor[repositories.RankKey](after)
+	if err != nil {
+		return nil, err
 	}
-	return res, nil
+	cryptogotchies, err := r.cryptogotchiSvc.GetLeaderboardAfter(key, first+1)
+	if err != nil {
+		return nil, err
+	}
+	return cryptogotchiConnection(cryptogotchies, first, after != nil), nil
 }
 
 func (r *queryResolver) Events(ctx context.Context, cryptogotchiID string, offset int, limit int) ([]*models.Event, error) {
-	cryptogotchi, err := r.cryptogotchiSvc.GetById(cryptogotchiID)
+	limit, err := pageLimit(offset, limit)
 	if err != nil {
 		return nil, err
 	}
-
-	// check if the user is the owner of this cryptogotchi.
-	// if not - return an error.
-	if cryptogotchi.OwnerId.String() != ctx.Value(config.USER_CTX_KEY).(*models.User).Id.String() {
-		return nil, gqlerror.Errorf("you are not the owner of this cryptogotchi")
+	if err := r.checkCryptogotchiOwner(ctx, cryptogotchiID); err != nil {
+		return nil, err
 	}
 
 	events, err := r.eventSvc.GetPaginated(cryptogotchiID, offset, limit)
@@ -327,23 +360,35 @@ func (r *queryResolver) Events(ctx context.Context, cryptogotchiID string, offse
 	return eventPointer, nil
 }
 
-func (r *queryResolver) Cryptogotchi(ctx context.Context, cryptogotchiID string) (*models.Cryptogotchi, error) {
-	currentUser := ctx.Value(config.USER_CTX_KEY)
-	cryptogotchi, err := r.cryptogotchiSvc.GetById(cryptogotchiID)
-	if db.IsNotFound(err) {
-		return nil, gqlerror.Errorf("could not find cryptogotchi with id %s", cryptogotchiID)
+func (r *queryResolver) EventsConnection(ctx context.Context, cryptogotchiID string, first int, after *string) (*input.EventConnection, error) {
+	first, err := firstLimit(first)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decodeCursor[repositories.TimeKey](after)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkCryptogotchiOwner(ctx, cryptogotchiID); err != nil {
+		return nil, err
 	}
 
-	// check if the cryptogotchi belongs to the current user
-	if currentUser == nil || cryptogotchi.OwnerId != currentUser.(*models.User).Id {
-		// remove the events from the history
-		// privacy policy :-)
-		cryptogotchi.Events = nil
+	events, err := r.eventSvc.GetPageAfter(cryptogotchiID, key, first+1)
+	if err != nil {
+		return nil, err
 	}
-	return &cryptogotchi, err
+	return eventConnection(events, first, after != nil), nil
+}
+
+func (r *queryResolver) Cryptogotchi(ctx context.Context, cryptogotchiID string) (*models.Cryptogotchi, error) {
+	return r.publicCryptogotchi(ctx, cryptogotchiID)
 }
 
 func (r *queryResolver) Cryptogotchies(ctx context.Context, query *input.SearchQuery, offset int, limit int) ([]*models.Cryptogotchi, error) {
+	limit, err := pageLimit(offset, limit)
+	if err != nil {
+		return nil, err
+	}
 	cryptogotchis, err := r.cryptogotchiSvc.GetCryptogotchies(query, offset, limit)
 	if err != nil {
 		return nil, err
@@ -356,6 +401,22 @@ func (r *queryResolver) Cryptogotchies(ctx context.Context, query *input.SearchQ
 	return res, nil
 }
 
+func (r *queryResolver) CryptogotchiesConnection(ctx context.Context, query *input.SearchQuery, first int, after *string) (*input.CryptogotchiConnection, error) {
+	first, err := firstLimit(first)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decodeCursor[repositories.RankKey](after)
+	if err != nil {
+		return nil, err
+	}
+	cryptogotchies, err := r.cryptogotchiSvc.GetCryptogotchiesAfter(query, key, first+1)
+	if err != nil {
+		return nil, err
+	}
+	return cryptogotchiConnection(cryptogotchies, first, after != nil), nil
+}
+
 func (r *queryResolver) User(ctx context.Context, id string) (*models.User, error) {
 	user, err := r.userSvc.GetById(id)
 	if err != nil {
@@ -366,6 +427,10 @@ func (r *queryResolver) User(ctx context.Context, id string) (*models.User, erro
 }
 
 func (r *queryResolver) Users(ctx context.Context, query *input.SearchQuery, offset int, limit int) ([]*models.User, error) {
+	limit, err := pageLimit(offset, limit)
+	if err != nil {
+		return nil, err
+	}
 	users, err := r.userSvc.GetUsers(query, offset, limit)
 	if err != nil {
 		return nil, err
@@ -378,17 +443,138 @@ func (r *queryResolver) Users(ctx context.Context, query *input.SearchQuery, off
 	return res, nil
 }
 
+func (r *queryResolver) UsersConnection(ctx context.Context, query *input.SearchQuery, first int, after *string) (*input.UserConnection, error) {
+	first, err := firstLimit(first)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decodeCursor[repositories.TimeKey](after)
+	if err != nil {
+		return nil, err
+	}
+	users, err := r.userSvc.GetUsersAfter(query, key, first+1)
+	if err != nil {
+		return nil, err
+	}
+	return userConnection(users, first, after != nil), nil
+}
+
 func (r *queryResolver) Self(ctx context.Context) (*models.User, error) {
-	if ctx.Value(config.USER_CTX_KEY) == nil {
-		return nil, gqlerror.Errorf("user not found")
+	return CurrentUser(ctx)
+}
+
+func (r *queryResolver) Sessions(ctx context.Context) ([]*models.Session, error) {
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := r.authSvc.GetSessions(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Session, len(sessions))
+	for i, session := range sessions {
+		tmp := session
+		res[i] = &tmp
+	}
+	return res, nil
+}
+
+func (r *sessionResolver) ID(ctx context.Context, obj *models.Session) (string, error) {
+	return obj.Id.String(), nil
+}
+
+func (r *sessionResolver) Current(ctx context.Context, obj *models.Session) (bool, error) {
+	return ctx.Value(config.SESSION_CTX_KEY) == obj.Id.String(), nil
+}
+
+func (r *subscriptionResolver) CryptogotchiUpdated(ctx context.Context, id string) (<-chan *models.Cryptogotchi, error) {
+	// fail early for unknown ids.
+	if _, err := r.publicCryptogotchi(ctx, id); err != nil {
+		return nil, err
+	}
+	updates := r.liveUpdateSvc.CryptogotchiUpdates(ctx, id)
+	res := make(chan *models.Cryptogotchi)
+	go func() {
+		defer close(res)
+		for range updates {
+			// the loaders of the subscription would return the previous state.
+			r.loaders(ctx).Clear()
+			cryptogotchi, err := r.publicCryptogotchi(ctx, id)
+			if err != nil {
+				r.logger.Errorf("could not fetch updated cryptogotchi %s: %s", id, err)
+				continue
+			}
+			select {
+			case res <- cryptogotchi:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return res, nil
+}
+
+func (r *subscriptionResolver) LeaderboardChanged(ctx context.Context, offset int, limit int) (<-chan []*models.Cryptogotchi, error) {
+	// fail early for invalid pages.
+	if _, err := pageLimit(offset, limit); err != nil {
+		return nil, err
 	}
-	return ctx.Value(config.USER_CTX_KEY).(*models.User), nil
+	updates := r.liveUpdateSvc.LeaderboardUpdates(ctx)
+	res := make(chan []*models.Cryptogotchi)
+	go func() {
+		defer close(res)
+		for range updates {
+			r.loaders(ctx).Clear()
+			leaderboard, err := r.leaderboardPage(offset, limit)
+			if err != nil {
+				r.logger.Errorf("could not fetch the leaderboard: %s", err)
+				continue
+			}
+			select {
+			case res <- leaderboard:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return res, nil
 }
 
 func (r *userResolver) ID(ctx context.Context, obj *models.User) (string, error) {
 	return obj.Id.String(), nil
 }
 
+func (r *userResolver) Cryptogotchies(ctx context.Context, obj *models.User) ([]*models.Cryptogotchi, error) {
+	cryptogotchies, err := r.loadCryptogotchiesOfOwner(ctx, obj.Id.String())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Cryptogotchi, len(cryptogotchies))
+	for i, c := range cryptogotchies {
+		tmp := c
+		res[i] = &tmp
+	}
+	return res, nil
+}
+
+func (r *userResolver) DeviceID(ctx context.Context, obj *models.User) (*string, error) {
+	// the device id allows to login - never return it for other users.
+	currentUser := optionalUser(ctx)
+	if currentUser == nil || currentUser.Id != obj.Id {
+		return nil, nil
+	}
+	devices, err := r.userSvc.GetDevices(obj.Id.String())
+	if err != nil || len(devices) == 0 {
+		return nil, err
+	}
+	return &devices[0].DeviceId, nil
+}
+
+func (r *userResolver) EmailVerified(ctx context.Context, obj *models.User) (bool, error) {
+	return obj.EmailVerifiedAt != nil, nil
+}
+
 // Cryptogotchi returns generated.CryptogotchiResolver implementation.
 func (r *Resolver) Cryptogotchi() generated.CryptogotchiResolver { return &cryptogotchiResolver{r} }
 
@@ -401,9 +587,20 @@ func (r *Resolver) GameStat() generated.GameStatResolver { return &gameStatResol
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// OwnershipTransfer returns generated.OwnershipTransferResolver implementation.
+func (r *Resolver) OwnershipTransfer() generated.OwnershipTransferResolver {
+	return &ownershipTransferResolver{r}
+}
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Session returns generated.SessionResolver implementation.
+func (r *Resolver) Session() generated.SessionResolver { return &sessionResolver{r} }
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
 // User returns generated.UserResolver implementation.
 func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
@@ -411,5 +608,8 @@ type cryptogotchiResolver struct{ *Resolver }
 type eventResolver struct{ *Resolver }
 type gameStatResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type ownershipTransferResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type sessionResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
 type userResolver struct{ *Resolver }
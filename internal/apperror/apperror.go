@@ -0,0 +1,100 @@
+// Package apperror contains the errors which are meant to be shown to the client.
+// Every error carries a code - the graphql error presenter maps it into the extensions.
+// Errors which are not of this type are treated as internal errors.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Code string
+
+const (
+	NotFound        Code = "NOT_FOUND"
+	Forbidden       Code = "FORBIDDEN"
+	Unauthenticated Code = "UNAUTHENTICATED"
+	// the action is allowed again at RetryAt.
+	Cooldown Code = "COOLDOWN"
+	// too many requests - the next one is allowed at RetryAt.
+	RateLimited Code = "RATE_LIMITED"
+	Validation  Code = "VALIDATION"
+	// the entity exists already - for example a taken email address.
+	Conflict Code = "CONFLICT"
+	// the endpoint or feature is not supported anymore.
+	Gone Code = "GONE"
+	// the message is never shown to the client.
+	Internal Code = "INTERNAL"
+)
+
+type Error struct {
+	Code    Code
+	Message string
+	// only set for the COOLDOWN and RATE_LIMITED codes.
+	RetryAt *time.Time
+	// the cause - used by errors.Is and errors.As. Never shown to the client.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// the extensions of the graphql error.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": string(e.Code)}
+	if e.RetryAt != nil {
+		extensions["retryAt"] = e.RetryAt.UTC().Format(time.RFC3339)
+	}
+	return extensions
+}
+
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// keeps the error as cause - the message is shown to the client instead of the error.
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func NewNotFound(format string, args ...interface{}) *Error {
+	return New(NotFound, format, args...)
+}
+
+func NewForbidden(format string, args ...interface{}) *Error {
+	return New(Forbidden, format, args...)
+}
+
+func NewValidation(format string, args ...interface{}) *Error {
+	return New(Validation, format, args...)
+}
+
+func NewCooldown(retryAt time.Time, format string, args ...interface{}) *Error {
+	err := New(Cooldown, format, args...)
+	err.RetryAt = &retryAt
+	return err
+}
+
+func NewRateLimited(retryAt time.Time, format string, args ...interface{}) *Error {
+	err := New(RateLimited, format, args...)
+	err.RetryAt = &retryAt
+	return err
+}
+
+// returns the code of the error - INTERNAL if the error is not an Error.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return Internal
+}
@@ -0,0 +1,31 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOf(t *testing.T) {
+	assert.Equal(t, NotFound, CodeOf(NewNotFound("koi %s not found", "1")))
+	// wrapped errors keep their code.
+	assert.Equal(t, Forbidden, CodeOf(fmt.Errorf("resolver: %w", NewForbidden("not the owner"))))
+	assert.Equal(t, Internal, CodeOf(errors.New("connection refused")))
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("cause")
+	err := Wrap(Validation, cause, "invalid name")
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "invalid name", err.Message)
+}
+
+func TestCooldownExtensions(t *testing.T) {
+	retryAt := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := NewCooldown(retryAt, "not yet")
+	assert.Equal(t, map[string]interface{}{"code": "COOLDOWN", "retryAt": "2022-01-01T12:00:00Z"}, err.Extensions())
+	assert.Equal(t, map[string]interface{}{"code": "NOT_FOUND"}, NewNotFound("x").Extensions())
+}
@@ -12,7 +12,10 @@ import (
 type CTX_KEYS string
 
 const (
-	USER_CTX_KEY CTX_KEYS = "user"
+	USER_CTX_KEY    CTX_KEYS = "user"
+	SESSION_CTX_KEY CTX_KEYS = "session"
+	// the address of the client - used by the rate limits of anonymous requests.
+	CLIENT_IP_CTX_KEY CTX_KEYS = "clientIp"
 )
 
 // the time between feedings
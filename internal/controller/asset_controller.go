@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+// asset packs larger than this get rejected.
+const maxAssetPackSize int64 = 512 << 20
+
+type AssetController struct {
+	adminToken string
+	// by cryptogotchi type - koi or dragon.
+	preloaders map[string]*generator.ReloadingPreloader
+	logger     *logrus.Entry
+}
+
+func NewAssetController(adminToken string, preloaders map[string]*generator.ReloadingPreloader) AssetController {
+	return AssetController{
+		adminToken: adminToken,
+		preloaders: preloaders,
+		logger:     orchardclient.Logger.WithField("component", "AssetController"),
+	}
+}
+
+// only allows requests carrying the admin token in the X-Admin-Token header.
+func (c *AssetController) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.adminToken)) != 1 {
+			http_util.WriteProblem(w, r, http.StatusUnauthorized, apperror.Unauthenticated, "invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *AssetController) getPreloader(w http.ResponseWriter, req *http.Request) (*generator.ReloadingPreloader, bool) {
+	preloader, ok := c.preloaders[chi.URLParam(req, "type")]
+	if !ok {
+		http_util.WriteProblem(w, req, http.StatusNotFound, apperror.NotFound, "unknown asset type")
+	}
+	return preloader, ok
+}
+
+// returns the status of all asset directories.
+func (c *AssetController) GetStatus(w http.ResponseWriter, req *http.Request) {
+	status := make(map[string]generator.AssetStatus, len(c.preloaders))
+	for t, preloader := range c.preloaders {
+		status[t] = preloader.Status()
+	}
+	http_util.WriteJSON(w, status)
+}
+
+// reloads the asset directory. Useful if the watcher is disabled.
+func (c *AssetController) Reload(w http.ResponseWriter, req *http.Request) {
+	preloader, ok := c.getPreloader(w, req)
+	if !ok {
+		return
+	}
+	if err := preloader.Reload(); err != nil {
+		problem := http_util.NewProblem(req, http.StatusUnprocessableEntity, apperror.Validation, "the asset directory is invalid - the current images stay in use")
+		problem.Errors = preloader.Status().Problems
+		http_util.WriteProblemDocument(w, problem)
+		return
+	}
+	http_util.WriteJSON(w, preloader.Status())
+}
+
+// installs a zipped asset pack. The pack gets validated before any image gets replaced.
+func (c *AssetController) UploadPack(w http.ResponseWriter, req *http.Request) {
+	preloader, ok := c.getPreloader(w, req)
+	if !ok {
+		return
+	}
+
+	// the zip reader needs random access - buffer the upload on disk.
+	tmp, err := os.CreateTemp("", "asset-pack-*.zip")
+	if err != nil {
+		c.logger.Errorf("could not create temporary file: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not store asset pack")
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, req.Body, maxAssetPackSize))
+	if err != nil {
+		http_util.WriteProblem(w, req, http.StatusRequestEntityTooLarge, apperror.Validation, "could not read asset pack")
+		return
+	}
+
+	if err := preloader.InstallPack(tmp, size); err != nil {
+		c.logger.Warnf("rejected asset pack: %s", err)
+		problem := http_util.NewProblem(req, http.StatusUnprocessableEntity, apperror.Validation, "the asset pack is invalid")
+		problem.Errors = strings.Split(err.Error(), "\n")
+		http_util.WriteProblemDocument(w, problem)
+		return
+	}
+	http_util.WriteJSON(w, preloader.Status())
+}
@@ -1,63 +1,87 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_dto"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/siwe"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
 type AuthController struct {
 	authSvc         service.AuthSvc
 	cryptogotchiSvc service.CryptogotchiSvc
-	logger          *logrus.Entry
+	// the login using only the wallet address is deprecated - it does not prove the ownership of the wallet.
+	// still allowed until all clients use Sign-In with Ethereum.
+	allowWalletAddressLogin bool
+	logger                  *logrus.Entry
 }
 
-func NewAuthController(userRepository repositories.UserRepository, cryptogotchiSvc service.CryptogotchiSvc, authSvc service.AuthSvc) AuthController {
+func NewAuthController(userRepository repositories.UserRepository, cryptogotchiSvc service.CryptogotchiSvc, authSvc service.AuthSvc, allowWalletAddressLogin bool) AuthController {
 	return AuthController{
-		authSvc:         authSvc,
-		cryptogotchiSvc: cryptogotchiSvc,
-		logger:          orchardclient.Logger.WithField("component", "AuthController"),
+		authSvc:                 authSvc,
+		cryptogotchiSvc:         cryptogotchiSvc,
+		allowWalletAddressLogin: allowWalletAddressLogin,
+		logger:                  orchardclient.Logger.WithField("component", "AuthController"),
 	}
 }
 
+// returns a nonce for the Sign-In with Ethereum message.
+func (c *AuthController) Nonce(w http.ResponseWriter, req *http.Request) {
+	res, err := c.authSvc.CreateLoginChallenge()
+	if err != nil {
+		c.logger.Errorf("could not create nonce: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not create nonce")
+		return
+	}
+	// every call needs a fresh nonce.
+	w.Header().Set("Cache-Control", "no-store")
+	http_util.WriteJSON(w, res)
+}
+
+func isSiweError(err error) bool {
+	for _, siweErr := range []error{siwe.ErrInvalidMessage, siwe.ErrInvalidSignature, siwe.ErrDomainMismatch, siwe.ErrChainMismatch, siwe.ErrExpired, siwe.ErrInvalidNonce} {
+		if errors.Is(err, siweErr) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *AuthController) Refresh(w http.ResponseWriter, req *http.Request) {
 	var refreshRequest http_dto.RefreshRequest
 	err := http_util.ParseBody(req, &refreshRequest)
 
 	if err != nil {
-		c.logger.Errorf("could not parse body: %e", err)
-		http_util.WriteHttpError(w, http.StatusBadRequest, fmt.Sprintf("could not parse body: %e", err))
+		c.logger.Errorf("could not parse body: %s", err)
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
 		return
 	}
 
-	user, err := c.authSvc.GetByRefreshToken(refreshRequest.RefreshToken)
-
-	if db.IsNotFound(err) {
-		c.logger.Warn("refresh token is not valid")
-		http_util.WriteHttpError(w, http.StatusForbidden, fmt.Sprintf("refresh token not valid: %e", err))
+	res, err := c.authSvc.RefreshSession(refreshRequest.RefreshToken, req.UserAgent())
+	if errors.Is(err, repositories.ErrRefreshTokenReused) {
+		// the session got revoked - the user needs to login again.
+		c.logger.Warn("refresh token reused")
+		http_util.WriteProblem(w, req, http.StatusForbidden, apperror.Forbidden, "refresh token not valid")
 		return
 	}
-
-	if err != nil {
-		c.logger.Errorf("could not get user: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not get user: %e", err))
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		c.logger.Warn("refresh token is not valid")
+		http_util.WriteProblem(w, req, http.StatusForbidden, apperror.Forbidden, "refresh token not valid")
 		return
 	}
-
-	// create new refresh token for user.
-	res, err := c.authSvc.CreateTokenForUser(&user)
 	if err != nil {
-		c.logger.Errorf("could not generate tokens: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not generate tokens: %e", err))
+		c.logger.Errorf("could not refresh session: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not refresh session")
 		return
 	}
 
@@ -68,32 +92,69 @@ func (c *AuthController) DestroyAccount(w http.ResponseWriter, req *http.Request
 	user := http_util.GetUserFromContext(req)
 	if user == nil {
 		c.logger.Warn("user is not authenticated")
-		http_util.WriteHttpError(w, http.StatusForbidden, "user is not authenticated")
+		http_util.WriteProblem(w, req, http.StatusForbidden, apperror.Forbidden, "user is not authenticated")
 		return
 	}
 
 	// delete the user account.
 	err := c.authSvc.Delete(user)
 	if err != nil {
-		c.logger.Errorf("could not delete user: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not delete user: %s", user.Id.String()))
+		c.logger.Errorf("could not delete user: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not delete user")
 		return
 	}
 	http_util.WriteJSON(w, http.StatusOK)
 }
 
+// checks if the registration request was sent by the existing user using one of the devices of the user.
+// the wallet address is public - it does not prove anything. Wallet users need to sign in using /auth/login.
+func (c *AuthController) ownsDevice(user *models.User, registerRequest http_dto.RegisterRequest) (bool, error) {
+	owner, err := c.authSvc.GetByDeviceId(*registerRequest.DeviceId)
+	if db.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner.Id == user.Id, nil
+}
+
+// starts a session for the user and writes the tokens.
+func (c *AuthController) writeTokenResponse(w http.ResponseWriter, req *http.Request, user *models.User) {
+	res, err := c.authSvc.CreateTokenForUser(user, req.UserAgent())
+	if errors.Is(err, service.ErrUserBanned) {
+		c.logger.Warnf("banned user %s tried to login", user.Id)
+		http_util.WriteProblem(w, req, http.StatusForbidden, apperror.Forbidden, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.Errorf("could not generate tokens: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not generate tokens")
+		return
+	}
+	http_util.WriteJSON(w, res)
+}
+
 func (c *AuthController) Register(w http.ResponseWriter, req *http.Request) {
 	var registerRequest http_dto.RegisterRequest
 	err := http_util.ParseBody(req, &registerRequest)
 	if err != nil {
-		c.logger.Errorf("could not parse body: %e", err)
-		http_util.WriteHttpError(w, http.StatusBadRequest, fmt.Sprintf("could not parse body: %e", err))
+		c.logger.Errorf("could not parse body: %s", err)
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
 		return
 	}
 	// check if the request is valid by checking if name and email is not an empty string.
 	if registerRequest.Name == "" || registerRequest.Email == "" {
 		c.logger.Warn("name and email is required")
-		http_util.WriteHttpError(w, http.StatusBadRequest, "name and email is required")
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "name and email is required")
+		return
+	}
+
+	// a wallet is never registered - it is not proven.
+	// the wallet gets connected after the registration using connectWallet.
+	if registerRequest.DeviceId == nil {
+		c.logger.Warn("registration without a device id")
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "device id is required - connect the wallet using connectWallet after the registration")
 		return
 	}
 
@@ -101,30 +162,30 @@ func (c *AuthController) Register(w http.ResponseWriter, req *http.Request) {
 	existingUser, err := c.authSvc.GetByEmail(registerRequest.Email)
 	if err == nil {
 		// the user does already exist.
-		// check if the wallet address or the device id matches - if so
-		// we can log him in.
-		if (existingUser.WalletAddress != nil && strings.EqualFold(*existingUser.WalletAddress, *registerRequest.WalletAddress)) || (existingUser.DeviceId != nil && existingUser.DeviceId == registerRequest.DeviceId) {
+		// check if the device id matches - if so
+		// we can log them in.
+		sameUser, err := c.ownsDevice(&existingUser, registerRequest)
+		if err != nil {
+			c.logger.Errorf("could not get user: %s", err)
+			http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get user")
+			return
+		}
+		if sameUser {
 			// the user is already registered.
 			c.logger.Infof("user %s is already registered", registerRequest.Email)
-			res, err := c.authSvc.CreateTokenForUser(&existingUser)
-			if err != nil {
-				c.logger.Errorf("could not generate tokens: %e", err)
-				http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not generate tokens: %e", err))
-				return
-			}
-			http_util.WriteJSON(w, res)
+			c.writeTokenResponse(w, req, &existingUser)
 			return
 		} else {
 			// the email address is already taken
 			c.logger.Warnf("email %s is already taken", registerRequest.Email)
-			http_util.WriteHttpError(w, http.StatusBadRequest, fmt.Sprintf("email %s is already taken", registerRequest.Email))
+			http_util.WriteProblem(w, req, http.StatusConflict, apperror.Conflict, fmt.Sprintf("email %s is already taken", registerRequest.Email))
 			return
 		}
 	} else if !db.IsNotFound(err) {
 		// an error occured which is not the user not found error.
 		// no way to recover
-		c.logger.Errorf("could not get user: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not get user: %e", err))
+		c.logger.Errorf("could not get user: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get user")
 		return
 	}
 
@@ -134,18 +195,25 @@ func (c *AuthController) Register(w http.ResponseWriter, req *http.Request) {
 	user.Name = registerRequest.Name
 	user.Email = registerRequest.Email
 
-	if registerRequest.WalletAddress != nil {
-		lowerCasedWalletAddress := strings.ToLower(*registerRequest.WalletAddress)
-		user.WalletAddress = &lowerCasedWalletAddress
-	} else {
-		user.DeviceId = registerRequest.DeviceId
+	// the device might belong to a user with another email address.
+	_, err = c.authSvc.GetByDeviceId(*registerRequest.DeviceId)
+	if err == nil {
+		http_util.WriteProblem(w, req, http.StatusConflict, apperror.Conflict, service.ErrDeviceIdTaken.Error())
+		return
+	}
+	if !db.IsNotFound(err) {
+		c.logger.Errorf("could not get user: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get user")
+		return
 	}
+	// saved together with the user.
+	user.Devices = []models.Device{{DeviceId: *registerRequest.DeviceId}}
 
 	err = c.authSvc.Save(&user)
 
 	if err != nil {
-		c.logger.Errorf("could not save user: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not save user: %e", err))
+		c.logger.Errorf("could not save user: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not save user")
 		return
 	}
 
@@ -153,22 +221,20 @@ func (c *AuthController) Register(w http.ResponseWriter, req *http.Request) {
 	_, err = c.cryptogotchiSvc.GenerateCryptogotchiForUser(&user, true)
 
 	if err != nil {
-		c.logger.Errorf("could not generate cryptogotchi: %e", err)
+		c.logger.Errorf("could not generate cryptogotchi: %s", err)
 		// delete the created user to avoid having a user without a cryptogotchi.
 		c.authSvc.Delete(&user)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not generate cryptogotchi: %e", err))
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not generate cryptogotchi")
 		return
 	}
 
-	// return a token for the user.
-	res, err := c.authSvc.CreateTokenForUser(&user)
-	if err != nil {
-		c.logger.Errorf("could not generate tokens: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not generate tokens: %e", err))
-		return
+	// the registration does not fail if the mail could not be sent - it can be requested again.
+	if err := c.authSvc.SendVerificationEmail(&user); err != nil {
+		c.logger.Errorf("could not send verification email: %s", err)
 	}
 
-	http_util.WriteJSON(w, res)
+	// return a token for the user.
+	c.writeTokenResponse(w, req, &user)
 }
 
 func (c *AuthController) Login(w http.ResponseWriter, req *http.Request) {
@@ -176,38 +242,199 @@ func (c *AuthController) Login(w http.ResponseWriter, req *http.Request) {
 	var loginRequest http_dto.LoginRequest
 	err := http_util.ParseBody(req, &loginRequest)
 	if err != nil {
-		c.logger.Warnf("could not parse body: %e", err)
-		http_util.WriteHttpError(w, http.StatusBadRequest, fmt.Sprintf("could not parse body: %e", err))
-		return
-	}
-
-	if loginRequest.WalletAddress == nil && loginRequest.DeviceId == nil {
-		c.logger.Warnf("called with empty wallet address and empty device token")
-		http_util.WriteHttpError(w, http.StatusBadRequest, "wallet address and device token is empty")
+		c.logger.Warnf("could not parse body: %s", err)
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
 		return
 	}
 
 	var user models.User
-	if loginRequest.WalletAddress != nil {
+	switch {
+	case loginRequest.Message != nil && loginRequest.Signature != nil:
+		user, err = c.authSvc.LoginWithSignature(*loginRequest.Message, *loginRequest.Signature)
+		if isSiweError(err) {
+			c.logger.Warnf("sign-in with ethereum failed: %s", err)
+			http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, err.Error())
+			return
+		}
+	case loginRequest.WalletAddress != nil:
+		if !c.allowWalletAddressLogin {
+			http_util.WriteProblem(w, req, http.StatusGone, apperror.Gone, "login with the wallet address is not supported anymore. Use sign-in with ethereum.")
+			return
+		}
+		c.logger.Warn("deprecated login with wallet address")
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", `299 - "login with the wallet address is deprecated. Use sign-in with ethereum."`)
 		user, err = c.authSvc.GetByWalletAddress(*loginRequest.WalletAddress)
-	} else {
+		// the proof would be worthless if the address alone still logged in.
+		if err == nil && user.WalletVerifiedAt != nil {
+			c.logger.Warnf("login with the proven wallet of user %s without a signature", user.Id)
+			http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, "the wallet is proven - use sign-in with ethereum.")
+			return
+		}
+	case loginRequest.DeviceId != nil:
 		user, err = c.authSvc.GetByDeviceId(*loginRequest.DeviceId)
+	default:
+		c.logger.Warnf("called without signature and empty device token")
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "message and signature or device token is required")
+		return
 	}
 
+	if db.IsNotFound(err) {
+		http_util.WriteProblem(w, req, http.StatusNotFound, apperror.NotFound, "user not found")
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("could not get user: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not get user: %e", err))
+		c.logger.Errorf("could not get user: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get user")
 		return
 	}
 
 	// the user is logged in.
 	// return a token for the user.
-	res, err := c.authSvc.CreateTokenForUser(&user)
+	c.writeTokenResponse(w, req, &user)
+}
+
+// sends the verification mail to the current user again.
+func (c *AuthController) RequestEmailVerification(w http.ResponseWriter, req *http.Request) {
+	user := http_util.GetUserFromContext(req)
+	if user == nil {
+		c.logger.Warn("user is not authenticated")
+		http_util.WriteProblem(w, req, http.StatusForbidden, apperror.Forbidden, "user is not authenticated")
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		http_util.WriteProblem(w, req, http.StatusConflict, apperror.Conflict, "email is already verified")
+		return
+	}
+	if err := c.authSvc.SendVerificationEmail(user); err != nil {
+		c.logger.Errorf("could not send verification email: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not send verification email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *AuthController) VerifyEmail(w http.ResponseWriter, req *http.Request) {
+	var linkRequest http_dto.EmailLinkRequest
+	if err := http_util.ParseBody(req, &linkRequest); err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
+		return
+	}
+	_, err := c.authSvc.VerifyEmail(linkRequest.Token)
+	if errors.Is(err, service.ErrInvalidEmailLink) {
+		http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, err.Error())
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("could not generate tokens: %e", err)
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not generate tokens: %e", err))
+		c.logger.Errorf("could not verify email: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not verify email")
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	http_util.WriteJSON(w, res)
+// handles the magic link and the recovery request.
+// always responds with 202 - the caller should not be able to tell if an account exists.
+func (c *AuthController) sendEmailLink(w http.ResponseWriter, req *http.Request, send func(email string) error) {
+	var emailRequest http_dto.EmailRequest
+	if err := http_util.ParseBody(req, &emailRequest); err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
+		return
+	}
+	if emailRequest.Email == "" {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "email is required")
+		return
+	}
+	if err := send(emailRequest.Email); err != nil {
+		c.logger.Errorf("could not send email: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not send email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *AuthController) RequestMagicLink(w http.ResponseWriter, req *http.Request) {
+	c.sendEmailLink(w, req, c.authSvc.SendMagicLink)
+}
+
+func (c *AuthController) RequestRecovery(w http.ResponseWriter, req *http.Request) {
+	c.sendEmailLink(w, req, c.authSvc.SendRecoveryEmail)
+}
+
+func (c *AuthController) LoginWithMagicLink(w http.ResponseWriter, req *http.Request) {
+	var linkRequest http_dto.EmailLinkRequest
+	if err := http_util.ParseBody(req, &linkRequest); err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
+		return
+	}
+	user, err := c.authSvc.LoginWithMagicLink(linkRequest.Token)
+	if errors.Is(err, service.ErrInvalidEmailLink) {
+		http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.Errorf("could not login with magic link: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not login")
+		return
+	}
+
+	c.writeTokenResponse(w, req, &user)
+}
+
+// binds the device id of the new device to the account and logs it in.
+func (c *AuthController) Recover(w http.ResponseWriter, req *http.Request) {
+	var recoveryRequest http_dto.RecoveryRequest
+	if err := http_util.ParseBody(req, &recoveryRequest); err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
+		return
+	}
+	if recoveryRequest.DeviceId == "" {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "device id is required")
+		return
+	}
+	user, err := c.authSvc.RecoverAccount(recoveryRequest.Token, recoveryRequest.DeviceId)
+	if errors.Is(err, service.ErrInvalidEmailLink) {
+		http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrDeviceIdTaken) {
+		http_util.WriteProblem(w, req, http.StatusConflict, apperror.Conflict, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.Errorf("could not recover account: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not recover account")
+		return
+	}
+
+	c.writeTokenResponse(w, req, &user)
+}
+
+// links the device to the user who created the pairing code and logs it in.
+func (c *AuthController) RedeemPairingCode(w http.ResponseWriter, req *http.Request) {
+	var pairingRequest http_dto.PairingRequest
+	if err := http_util.ParseBody(req, &pairingRequest); err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, fmt.Sprintf("could not parse body: %s", err))
+		return
+	}
+	if pairingRequest.Code == "" || pairingRequest.DeviceId == "" {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "code and device id are required")
+		return
+	}
+	user, err := c.authSvc.RedeemPairingCode(pairingRequest.Code, pairingRequest.DeviceId)
+	if errors.Is(err, service.ErrInvalidPairingCode) {
+		http_util.WriteProblem(w, req, http.StatusUnauthorized, apperror.Unauthenticated, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrDeviceIdTaken) {
+		http_util.WriteProblem(w, req, http.StatusConflict, apperror.Conflict, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.Errorf("could not redeem pairing code: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not redeem pairing code")
+		return
+	}
+
+	c.writeTokenResponse(w, req, &user)
 }
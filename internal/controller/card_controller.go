@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"fmt"
+	"html/template"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+// the page only exists to provide the open graph meta tags for link previews.
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="CryptoKoi">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.Url}}">
+<meta property="og:image" content="{{.ImageUrl}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="{{.ImageWidth}}">
+<meta property="og:image:height" content="{{.ImageHeight}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta name="twitter:image" content="{{.ImageUrl}}">
+</head>
+<body>
+<img src="{{.ImageUrl}}" alt="{{.Title}}" width="{{.ImageWidth}}" height="{{.ImageHeight}}">
+</body>
+</html>
+`))
+
+type sharePage struct {
+	Title       string
+	Description string
+	Url         string
+	ImageUrl    string
+	ImageWidth  int
+	ImageHeight int
+}
+
+type CardController struct {
+	imageBaseUrl    string
+	generator       *generator.Generator
+	cryptogotchiSvc service.CryptogotchiSvc
+	logger          *logrus.Entry
+}
+
+func NewCardController(imageBaseUrl string, generator *generator.Generator, cryptogotchiSvc service.CryptogotchiSvc) CardController {
+	return CardController{
+		imageBaseUrl:    imageBaseUrl,
+		generator:       generator,
+		cryptogotchiSvc: cryptogotchiSvc,
+		logger:          orchardclient.Logger.WithField("component", "CardController"),
+	}
+}
+
+// accepts the token id in hex (uuid) or decimal format.
+// returns the decimal representation.
+func parseTokenId(tokenId string) (string, error) {
+	if strings.IndexFunc(tokenId, util.IsNotDigit) > -1 {
+		// not only digits - use as hex.
+		tmp, err := util.UuidToUint256(tokenId)
+		if err != nil {
+			return "", err
+		}
+		return tmp.String(), nil
+	}
+	if _, ok := math.ParseBig256(tokenId); !ok {
+		return "", fmt.Errorf("invalid tokenId: %s", tokenId)
+	}
+	return tokenId, nil
+}
+
+func (c *CardController) getCryptogotchi(w http.ResponseWriter, req *http.Request) (models.Cryptogotchi, string, bool) {
+	tokenId, err := parseTokenId(chi.URLParam(req, "tokenId"))
+	if err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "invalid tokenId")
+		return models.Cryptogotchi{}, "", false
+	}
+
+	cryptogotchi, err := c.cryptogotchiSvc.GetCryptogotchiByUint256(tokenId)
+	if db.IsNotFound(err) {
+		http_util.WriteProblem(w, req, http.StatusNotFound, apperror.NotFound, "cryptogotchi not found")
+		return cryptogotchi, "", false
+	}
+	if err != nil {
+		c.logger.Errorf("could not get cryptogotchi: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get cryptogotchi")
+		return cryptogotchi, "", false
+	}
+	return cryptogotchi, tokenId, true
+}
+
+func cardData(cryptogotchi *models.Cryptogotchi, koi *cryptokoi.CryptoKoi) generator.CardData {
+	name := strings.Title(koi.GetAttributes().KoiType)
+	if cryptogotchi.Name != nil && *cryptogotchi.Name != "" {
+		name = *cryptogotchi.Name
+	}
+
+	isAlive := cryptogotchi.IsAlive()
+	age := time.Since(cryptogotchi.CreatedAt)
+	if !isAlive {
+		// the age stops counting at the death of the cryptogotchi.
+		age = cryptogotchi.PredictedDeathDate.Sub(cryptogotchi.CreatedAt)
+	}
+
+	return generator.CardData{
+		Name:    name,
+		Species: koi.GetAttributes().KoiType,
+		Rank:    cryptogotchi.Rank,
+		Age:     age,
+		IsAlive: isAlive,
+	}
+}
+
+// renders the share card as png.
+func (c *CardController) GetCard(w http.ResponseWriter, req *http.Request) {
+	cryptogotchi, tokenId, ok := c.getCryptogotchi(w, req)
+	if !ok {
+		return
+	}
+
+	// use the large image size - it is already cached by the preloader.
+	img, koi := c.generator.TokenId2Image(tokenId, 1024)
+	card := generator.RenderCard(img, koi.GetAttributes().PrimaryColor, cardData(&cryptogotchi, koi))
+
+	w.Header().Set("Content-Type", "image/png")
+	// the card changes with the rank and the age - but not that often.
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	png.Encode(w, card)
+}
+
+// serves a minimal html page carrying the open graph tags for the share card.
+func (c *CardController) GetSharePage(w http.ResponseWriter, req *http.Request) {
+	cryptogotchi, tokenId, ok := c.getCryptogotchi(w, req)
+	if !ok {
+		return
+	}
+
+	data := cardData(&cryptogotchi, cryptokoi.NewKoi(tokenId))
+	state := "alive"
+	if !data.IsAlive {
+		state = "dead"
+	}
+	description := fmt.Sprintf("A %s koi, %s old and %s.", data.Species, generator.FormatAge(data.Age), state)
+	if data.Rank > 0 {
+		description = fmt.Sprintf("A %s koi, rank #%d on the leaderboard, %s old and %s.", data.Species, data.Rank, generator.FormatAge(data.Age), state)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := sharePageTemplate.Execute(w, sharePage{
+		Title:       data.Name + " | CryptoKoi",
+		Description: description,
+		Url:         c.imageBaseUrl + "share/" + tokenId,
+		ImageUrl:    c.imageBaseUrl + "v1/cards/" + tokenId,
+		ImageWidth:  generator.CARD_WIDTH,
+		ImageHeight: generator.CARD_HEIGHT,
+	})
+	if err != nil {
+		c.logger.Errorf("could not render share page: %s", err)
+	}
+}
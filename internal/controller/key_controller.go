@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+)
+
+type KeyController struct {
+	tokenSvc service.TokenSvc
+}
+
+func NewKeyController(tokenSvc service.TokenSvc) KeyController {
+	return KeyController{
+		tokenSvc: tokenSvc,
+	}
+}
+
+// returns the public keys used to verify the tokens - served as /.well-known/jwks.json
+func (c *KeyController) GetJWKS(w http.ResponseWriter, req *http.Request) {
+	// verifiers refetch the keys if they encounter an unknown kid - a short cache is fine.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http_util.WriteJSON(w, c.tokenSvc.GetJWKS())
+}
@@ -1,21 +1,25 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
 type OpenseaController struct {
 	imageBaseUrl    string
 	eventSvc        service.EventSvc
 	cryptogotchiSvc service.CryptogotchiSvc
+	logger          *logrus.Entry
 }
 
 func NewOpenseaController(imageBaseUrl string, eventRepository repositories.EventRepository, cryptogotchiSvc service.CryptogotchiSvc) OpenseaController {
@@ -23,22 +27,33 @@ func NewOpenseaController(imageBaseUrl string, eventRepository repositories.Even
 		eventSvc:        service.NewEventService(eventRepository),
 		cryptogotchiSvc: cryptogotchiSvc,
 		imageBaseUrl:    imageBaseUrl,
+		logger:          orchardclient.Logger.WithField("component", "OpenseaController"),
 	}
 }
 
 func (c *OpenseaController) GetCryptogotchi(w http.ResponseWriter, req *http.Request) {
-	tokenId := chi.URLParam(req, "tokenId")
+	tokenId, err := parseTokenId(chi.URLParam(req, "tokenId"))
+	if err != nil {
+		http_util.WriteProblem(w, req, http.StatusBadRequest, apperror.Validation, "invalid tokenId")
+		return
+	}
 	// fetch the correct cryptogotchi using the token.
 	cryptogotchi, err := c.cryptogotchiSvc.GetCryptogotchiByUint256(tokenId)
+	if db.IsNotFound(err) {
+		http_util.WriteProblem(w, req, http.StatusNotFound, apperror.NotFound, "cryptogotchi not found")
+		return
+	}
 	if err != nil {
-		http_util.WriteHttpError(w, http.StatusNotFound, fmt.Sprintf("could not get cryptogotchi: %e", err))
+		c.logger.Errorf("could not get cryptogotchi: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not get cryptogotchi")
 		return
 	}
 
 	// transform the cryptogotchi to an opensea-NFT compatible json.
 	nft, err := cryptogotchi.ToOpenseaNFT(c.imageBaseUrl)
 	if err != nil {
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not transform cryptogotchi to opensea-NFT: %e", err))
+		c.logger.Errorf("could not transform cryptogotchi to opensea-NFT: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not transform cryptogotchi to opensea-NFT")
 		return
 	}
 	http_util.WriteJSON(w, nft)
@@ -51,7 +66,8 @@ func (c *OpenseaController) GetFakeCryptogotchi(w http.ResponseWriter, req *http
 
 	nft, err := models.ToOpenseaNFT(c.imageBaseUrl, tokenId, true, "Fake", time.Now())
 	if err != nil {
-		http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("could not transform cryptogotchi to opensea-NFT: %e", err))
+		c.logger.Errorf("could not transform cryptogotchi to opensea-NFT: %s", err)
+		http_util.WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "could not transform cryptogotchi to opensea-NFT")
 		return
 	}
 	http_util.WriteJSON(w, nft)
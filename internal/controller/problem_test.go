@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+func serveProblem(t *testing.T, router http.Handler, req *http.Request) (int, http_util.Problem) {
+	rec := httptest.NewRecorder()
+	middleware.RequestID(router).ServeHTTP(rec, req)
+
+	assert.Equal(t, http_util.ProblemContentType, rec.Header().Get("Content-Type"))
+	var problem http_util.Problem
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	return rec.Code, problem
+}
+
+func TestOpenseaInvalidTokenId(t *testing.T) {
+	c := NewOpenseaController("", nil, nil)
+	router := chi.NewRouter()
+	router.Get("/v1/tokens/{tokenId}", c.GetCryptogotchi)
+
+	status, problem := serveProblem(t, router, httptest.NewRequest(http.MethodGet, "/v1/tokens/not-a-token", nil))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, http_util.Problem{
+		Type:      "about:blank",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "invalid tokenId",
+		Instance:  "/v1/tokens/not-a-token",
+		Code:      "VALIDATION",
+		RequestId: problem.RequestId,
+	}, problem)
+	assert.NotEmpty(t, problem.RequestId)
+}
+
+func TestRegisterInvalidBody(t *testing.T) {
+	c := AuthController{logger: orchardclient.Logger.WithField("component", "AuthController")}
+	router := chi.NewRouter()
+	router.Post("/auth/register", c.Register)
+
+	status, problem := serveProblem(t, router, httptest.NewRequest(http.MethodPost, "/auth/register", nil))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "VALIDATION", string(problem.Code))
+	assert.Equal(t, "/auth/register", problem.Instance)
+}
+
+func TestRegisterRequiresDeviceId(t *testing.T) {
+	c := AuthController{logger: orchardclient.Logger.WithField("component", "AuthController")}
+	router := chi.NewRouter()
+	router.Post("/auth/register", c.Register)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"name": "koi", "email": "koi@crypto-koi.io", "walletAddress": "0xabc"}`))
+	status, problem := serveProblem(t, router, req)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "VALIDATION", string(problem.Code))
+	assert.Contains(t, problem.Detail, "connectWallet")
+}
+
+func TestAdminMiddlewareRejectsInvalidToken(t *testing.T) {
+	c := AssetController{adminToken: "secret"}
+	router := chi.NewRouter()
+	router.With(c.AdminMiddleware).Get("/admin/assets", c.GetStatus)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/assets", nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+	status, problem := serveProblem(t, router, req)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "UNAUTHENTICATED", string(problem.Code))
+}
+
+// only implements the methods used by the login.
+type walletAuthSvc struct {
+	service.AuthSvc
+	user models.User
+}
+
+func (svc walletAuthSvc) GetByWalletAddress(address string) (models.User, error) {
+	return svc.user, nil
+}
+
+func TestWalletAddressLoginRejectsProvenWallet(t *testing.T) {
+	now := time.Now()
+	c := NewAuthController(nil, nil, walletAuthSvc{user: models.User{WalletVerifiedAt: &now}}, true)
+	router := chi.NewRouter()
+	router.Post("/auth/login", c.Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"walletAddress": "0xabc"}`))
+	status, problem := serveProblem(t, router, req)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "UNAUTHENTICATED", string(problem.Code))
+}
+
+func TestUploadInvalidAssetPack(t *testing.T) {
+	c := NewAssetController("secret", map[string]*generator.ReloadingPreloader{"koi": generator.NewReloadingPreloader(t.TempDir())})
+	router := chi.NewRouter()
+	router.Post("/admin/assets/{type}/pack", c.UploadPack)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/assets/koi/pack", strings.NewReader("not a zip"))
+	status, problem := serveProblem(t, router, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "VALIDATION", string(problem.Code))
+	assert.Equal(t, "/admin/assets/koi/pack", problem.Instance)
+	assert.NotEmpty(t, problem.Errors)
+}
+
+func TestReloadInvalidAssetDirectory(t *testing.T) {
+	c := NewAssetController("secret", map[string]*generator.ReloadingPreloader{"koi": generator.NewReloadingPreloader(t.TempDir())})
+	router := chi.NewRouter()
+	router.Post("/admin/assets/{type}/reload", c.Reload)
+
+	status, problem := serveProblem(t, router, httptest.NewRequest(http.MethodPost, "/admin/assets/koi/reload", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "VALIDATION", string(problem.Code))
+	assert.NotEmpty(t, problem.Errors)
+}
@@ -71,9 +71,9 @@ func (c *CryptoKoi) generateAttributes() KoiAttributes {
 		amountOfHeadImages = c.randomizers.r1.Intn(maxHeadImages+1-minHeadImages) + minHeadImages
 	}
 	attributes := KoiAttributes{
-		BodyImages:   c.wrappedKoi.getBodyImages(amountOfBodyImages, c.randomizers.r2.Intn(255)),
-		HeadImages:   c.wrappedKoi.getHeadImages(amountOfHeadImages, c.randomizers.r2.Intn(255)),
-		FinImages:    c.wrappedKoi.getFinImages(amountOfFinImages, c.randomizers.r2.Intn(255)),
+		BodyImages:   pickAmount(amountOfBodyImages, c.randomizers.r2.Intn(255), c.wrappedKoi.bodyImages()),
+		HeadImages:   pickAmount(amountOfHeadImages, c.randomizers.r2.Intn(255), c.wrappedKoi.headImages()),
+		FinImages:    pickAmount(amountOfFinImages, c.randomizers.r2.Intn(255), c.wrappedKoi.finImages()),
 		BodyColor:    c.wrappedKoi.getBodyColor(c.randomizers.r3.Intn(255)),
 		FinColor:     c.wrappedKoi.getFinBackgroundColor(c.randomizers.r3.Intn(255)),
 		PrimaryColor: c.wrappedKoi.primaryColor(),
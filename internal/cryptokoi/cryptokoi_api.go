@@ -4,13 +4,13 @@ import (
 	"crypto/ecdsa"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	solsha3 "github.com/miguelmota/go-solidity-sha3"
 	"github.com/sirupsen/logrus"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
@@ -19,10 +19,11 @@ import (
 type CryptoKoiApi struct {
 	privateKey *ecdsa.PrivateKey
 	binding    *CryptoKoiBinding
+	domain     VoucherDomain
 	logger     *logrus.Entry
 }
 
-func NewCryptokoiApi(privateHexKey string, binding *CryptoKoiBinding) CryptoKoiApi {
+func NewCryptokoiApi(privateHexKey string, binding *CryptoKoiBinding, domain VoucherDomain) CryptoKoiApi {
 	privKey, err := crypto.HexToECDSA(strings.Replace(privateHexKey, "0x", "", 1))
 	logger := orchardclient.Logger.WithField("component", "CryptoKoiApi")
 	if err != nil {
@@ -32,42 +33,66 @@ func NewCryptokoiApi(privateHexKey string, binding *CryptoKoiBinding) CryptoKoiA
 	return CryptoKoiApi{
 		privateKey: privKey,
 		binding:    binding,
+		domain:     domain,
 		logger:     logger,
 	}
 }
 
+// the address of the contract owner - every voucher is signed by it.
+func (c *CryptoKoiApi) Signer() common.Address {
+	return crypto.PubkeyToAddress(c.privateKey.PublicKey)
+}
+
+func (c *CryptoKoiApi) ContractAddress() common.Address {
+	return c.domain.VerifyingContract
+}
+
+// reads the current price from the contract.
+func (c *CryptoKoiApi) GetPrice() (*big.Int, error) {
+	return c.binding.GetPrice(&bind.CallOpts{})
+}
+
 // pass the address in hex format
-func (c *CryptoKoiApi) GetNftSignatureForCryptogotchi(cryptogotchiId string, address string) (string, string, error) {
+// the voucher can only be redeemed using redeemVoucher on the configured chain and contract until the expiry.
+func (c *CryptoKoiApi) GetVoucherForCryptogotchi(cryptogotchiId string, address string, price *big.Int, expiry time.Time) (Voucher, string, error) {
 	tokenId, err := util.UuidToUint256(cryptogotchiId)
 	if err != nil {
-		return "", "", err
+		return Voucher{}, "", err
 	}
 
-	hash := solsha3.SoliditySHA3WithPrefix(solsha3.SoliditySHA3(
-		// types
-		[]string{"uint256", "address"},
-
-		// values
-		[]interface{}{
-			tokenId,
-			address,
-		},
-	))
+	voucher := Voucher{
+		Account: common.HexToAddress(address),
+		TokenId: tokenId,
+		Price:   price,
+		Expiry:  expiry,
+	}
+	signature, err := SignVoucher(c.privateKey, c.domain, voucher)
+	if err != nil {
+		return Voucher{}, "", err
+	}
+	return voucher, signature, nil
+}
 
+// pass the address in hex format
+// Deprecated: the signature is valid forever, on any chain and for any deployment. Use GetVoucherForCryptogotchi.
+// still issued during the migration - the legacy redeem function accepts it until it gets disabled.
+func (c *CryptoKoiApi) GetNftSignatureForCryptogotchi(cryptogotchiId string, address string) (string, string, error) {
+	tokenId, err := util.UuidToUint256(cryptogotchiId)
 	if err != nil {
 		return "", "", err
 	}
 
+	hash := LegacyVoucherHash(tokenId, common.HexToAddress(address))
+
 	sig, err := crypto.Sign(hash[:], c.privateKey)
+	if err != nil {
+		return "", "", err
+	}
 
 	// this took ages: https://stackoverflow.com/questions/69762108/implementing-ethereum-personal-sign-eip-191-from-go-ethereum-gives-different-s
 	// have a look at the link.
 	sig[64] += 27
 
-	if err != nil {
-		return "", "", err
-	}
-
 	return hexutil.Encode(sig), tokenId.String(), nil
 }
 
@@ -1,9 +1,12 @@
 package cryptokoi
 
 import (
+	"math/big"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +19,7 @@ const (
 func TestRedeemToken(t *testing.T) {
 	os.Setenv("CHAIN_URL", "http://localhost:8545")
 	os.Setenv("CONTRACT_ADDRESS", "0x133c4b6c69322D09C5B266EFa9559173B6c9F029")
-	cryptokoiApi := NewCryptokoiApi(privateKey, nil)
+	cryptokoiApi := NewCryptokoiApi(privateKey, nil, testDomain)
 
 	signature, _, err := cryptokoiApi.GetNftSignatureForCryptogotchi("b400af616cb4456589c4d6ba43f948b7", otherUserAddress)
 	if err != nil {
@@ -24,3 +27,16 @@ func TestRedeemToken(t *testing.T) {
 	}
 	assert.Equal(t, expectedSignature, signature)
 }
+
+func TestGetVoucherForCryptogotchi(t *testing.T) {
+	cryptokoiApi := NewCryptokoiApi(privateKey, nil, testDomain)
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	voucher, signature, err := cryptokoiApi.GetVoucherForCryptogotchi("b400af616cb4456589c4d6ba43f948b7", otherUserAddress, big.NewInt(1000000000), expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "239264596381739575473221873891232270519", voucher.TokenId.String())
+	assert.Equal(t, common.HexToAddress(otherUserAddress), voucher.Account)
+	assert.Nil(t, VerifyVoucher(cryptokoiApi.Signer(), testDomain, voucher, signature, time.Now()))
+}
@@ -23,6 +23,12 @@ type CryptoKoiEvent struct {
 	TokenId string
 	From    string
 	To      string
+	// a log is identified by its transaction hash and its index.
+	TxHash      string
+	LogIndex    uint
+	BlockNumber uint64
+	// the log was part of a block which got reorganized away.
+	Removed bool
 }
 
 func NewCryptoKoiEventListener(binding *CryptoKoiBinding) *CryptoKoiEventListener {
@@ -34,23 +40,40 @@ func NewCryptoKoiEventListener(binding *CryptoKoiBinding) *CryptoKoiEventListene
 	}
 }
 
+func newCryptoKoiEvent(transfer *CryptoKoiBindingTransfer) CryptoKoiEvent {
+	return CryptoKoiEvent{
+		TokenId:     transfer.TokenId.String(),
+		From:        transfer.From.String(),
+		To:          transfer.To.String(),
+		TxHash:      transfer.Raw.TxHash.Hex(),
+		LogIndex:    transfer.Raw.Index,
+		BlockNumber: transfer.Raw.BlockNumber,
+		Removed:     transfer.Raw.Removed,
+	}
+}
+
 func (c *CryptoKoiEventListener) init() (event.Subscription, chan *CryptoKoiBindingTransfer, error) {
 	transfers := make(chan *CryptoKoiBindingTransfer)
 	sub, err := c.binding.WatchTransfer(nil, transfers, nil, nil, nil)
 	return sub, transfers, err
 }
 
-func (c *CryptoKoiEventListener) connect(eventChan chan<- CryptoKoiEvent) {
+func (c *CryptoKoiEventListener) connect(eventChan chan<- CryptoKoiEvent, cancelChan <-chan struct{}) {
 	sub, ch, err := c.init()
 	if err != nil {
 		c.failureCount += 1
 		c.logger.Error(err)
 		// try to reconnect.
-		time.Sleep(time.Second * time.Duration(c.failureCount))
+		select {
+		case <-cancelChan:
+			return
+		case <-time.After(time.Second * time.Duration(c.failureCount)):
+		}
 		c.logger.Info("reconnecting...")
-		c.connect(eventChan)
+		c.connect(eventChan, cancelChan)
 		return
 	}
+	defer sub.Unsubscribe()
 	c.failureCount = 0
 
 	if c.log {
@@ -59,12 +82,14 @@ func (c *CryptoKoiEventListener) connect(eventChan chan<- CryptoKoiEvent) {
 
 	for {
 		select {
+		case <-cancelChan:
+			return
 		case transfer := <-ch:
 			c.logger.Info("Transfer: ", transfer.TokenId.String(), " ", transfer.From.String(), " ", transfer.To.String())
-			eventChan <- CryptoKoiEvent{
-				TokenId: transfer.TokenId.String(),
-				From:    transfer.From.String(),
-				To:      transfer.To.String(),
+			select {
+			case eventChan <- newCryptoKoiEvent(transfer):
+			case <-cancelChan:
+				return
 			}
 		case err := <-sub.Err():
 			// not required for matic network.
@@ -75,15 +100,16 @@ func (c *CryptoKoiEventListener) connect(eventChan chan<- CryptoKoiEvent) {
 				c.log = true
 			}
 			// try to reconnect.
-			c.connect(eventChan)
+			c.connect(eventChan, cancelChan)
 			return
 		}
 	}
 }
 
 // has basic reconnection logic
-func (c *CryptoKoiEventListener) StartListener() <-chan CryptoKoiEvent {
+// the live logs are not confirmed yet - events of a disconnect are lost. Use a TransferSource to catch up.
+func (c *CryptoKoiEventListener) StartListener(cancelChan <-chan struct{}) <-chan CryptoKoiEvent {
 	eventChan := make(chan CryptoKoiEvent)
-	go c.connect(eventChan)
+	go c.connect(eventChan, cancelChan)
 	return eventChan
 }
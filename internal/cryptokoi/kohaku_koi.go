@@ -31,8 +31,8 @@ func (koi KohakuKoi) getType() KoiType {
 	return koi.koiType
 }
 
-func (koi KohakuKoi) getFinImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("fin", amount, randomSeed, 1, 3, koi.color)
+func (koi KohakuKoi) finImages() []util.ImageWithColor {
+	return withColor("fin", 1, 3, koi.color)
 }
 
 func (koi KohakuKoi) amountFinImages() (int, int) {
@@ -55,11 +55,11 @@ func (koi KohakuKoi) getBodyColor(randomSeed int) color.Color {
 	return WhiteColorRange.Apply(randomSeed)
 }
 
-func (koi KohakuKoi) getBodyImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi KohakuKoi) bodyImages() []util.ImageWithColor {
 	// generate the red color - so that all image patterns have the same red color
-	return amountWithColor("body", amount, randomSeed, 1, 8, koi.color)
+	return withColor("body", 1, 8, koi.color)
 }
 
-func (koi KohakuKoi) getHeadImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("head", amount, randomSeed, 1, 5, koi.color)
+func (koi KohakuKoi) headImages() []util.ImageWithColor {
+	return withColor("head", 1, 5, koi.color)
 }
@@ -1,9 +1,11 @@
 package cryptokoi
 
 import (
+	"fmt"
 	"image/color"
 	"math/rand"
 	"strconv"
+	"strings"
 
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 )
@@ -20,6 +22,18 @@ const (
 	Shigure    KoiType = "shigure"    // white background + orange pattern
 )
 
+// all koi types which might be generated
+var KoiTypes = []KoiType{Kohaku, Showa, Utsuri, Monochrome, Shigure}
+
+func IsKoiType(stringToCheck string) (KoiType, error) {
+	for _, t := range KoiTypes {
+		if strings.EqualFold(t, stringToCheck) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown koi type: %s", stringToCheck)
+}
+
 // [[r, r], [g, g], [b, b]]
 type ColorRange struct {
 	raw [3][2]int
@@ -101,12 +115,13 @@ type KoiAttributes struct {
 	PrimaryColor color.Color
 }
 type Koi interface {
-	getFinImages(amount int, randomSeed int) []util.ImageWithColor
+	// all fin pattern images the koi might get.
+	finImages() []util.ImageWithColor
 	// [[r, r], [g, g], [b, b]]
 	getFinBackgroundColor(randomSeed int) color.Color
 	getBodyColor(randomSeed int) color.Color
-	getBodyImages(amount int, randomSeed int) []util.ImageWithColor
-	getHeadImages(amount int, randomSeed int) []util.ImageWithColor
+	bodyImages() []util.ImageWithColor
+	headImages() []util.ImageWithColor
 	amountHeadImages() (int, int)
 	amountBodyImages() (int, int)
 	amountFinImages() (int, int)
@@ -124,6 +139,23 @@ var koiCtrs = []koiCtr{
 	NewShigureKoi,
 }
 
+// returns the names of all pattern images which might be used by any koi type.
+// the color of the patterns depends on the seed - the names do not.
+func PatternImageNames() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, ctr := range koiCtrs {
+		koi := ctr(0)
+		for _, img := range util.ConcatPreAllocate(koi.bodyImages(), koi.headImages(), koi.finImages()) {
+			if !seen[img.ImageName] {
+				seen[img.ImageName] = true
+				names = append(names, img.ImageName)
+			}
+		}
+	}
+	return names
+}
+
 func pickAmount(amount, randomSeed int, images []util.ImageWithColor) []util.ImageWithColor {
 	if amount == 0 {
 		return []util.ImageWithColor{}
@@ -152,13 +184,3 @@ func withColor(prefix string, possibleMin, possibleMax int, c color.Color) []uti
 	}
 	return result
 }
-
-func amountWithColor(prefix string, amount, randomSeed, possibleMin, possibleMax int, c color.Color) []util.ImageWithColor {
-	if amount == 0 {
-		return []util.ImageWithColor{}
-	}
-
-	possibilities := withColor(prefix, possibleMin, possibleMax, c)
-
-	return pickAmount(amount, randomSeed, possibilities)
-}
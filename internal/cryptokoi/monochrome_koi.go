@@ -31,7 +31,7 @@ func (koi MonochromeKoi) getType() KoiType {
 	return koi.koiType
 }
 
-func (koi MonochromeKoi) getFinImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi MonochromeKoi) finImages() []util.ImageWithColor {
 	return []util.ImageWithColor{}
 }
 
@@ -55,11 +55,11 @@ func (koi MonochromeKoi) getBodyColor(randomSeed int) color.Color {
 	return koi.color
 }
 
-func (koi MonochromeKoi) getBodyImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi MonochromeKoi) bodyImages() []util.ImageWithColor {
 	// generate the red color - so that all image patterns have the same red color
 	return []util.ImageWithColor{}
 }
 
-func (koi MonochromeKoi) getHeadImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi MonochromeKoi) headImages() []util.ImageWithColor {
 	return []util.ImageWithColor{}
 }
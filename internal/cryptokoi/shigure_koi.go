@@ -32,8 +32,8 @@ func (koi ShigureKoi) getType() KoiType {
 	return koi.koiType
 }
 
-func (koi ShigureKoi) getFinImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("fin", amount, randomSeed, 1, 2, koi.blackColor)
+func (koi ShigureKoi) finImages() []util.ImageWithColor {
+	return withColor("fin", 1, 2, koi.blackColor)
 }
 
 func (koi ShigureKoi) amountFinImages() (int, int) {
@@ -56,11 +56,11 @@ func (koi ShigureKoi) getBodyColor(randomSeed int) color.Color {
 	return WhiteColorRange.Apply(randomSeed)
 }
 
-func (koi ShigureKoi) getBodyImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi ShigureKoi) bodyImages() []util.ImageWithColor {
 	// generate the red color - so that all image patterns have the same red color
-	return amountWithColor("body", amount, randomSeed, 1, 8, koi.blackColor)
+	return withColor("body", 1, 8, koi.blackColor)
 }
 
-func (koi ShigureKoi) getHeadImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("head", amount, randomSeed, 6, 7, koi.redColor)
+func (koi ShigureKoi) headImages() []util.ImageWithColor {
+	return withColor("head", 6, 7, koi.redColor)
 }
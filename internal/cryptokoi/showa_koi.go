@@ -35,14 +35,10 @@ func (koi ShowaKoi) getType() KoiType {
 	return koi.koiType
 }
 
-func (koi ShowaKoi) getFinImages(amount int, randomSeed int) []util.ImageWithColor {
-	return pickAmount(
-		amount,
-		randomSeed,
-		util.ConcatPreAllocate(
-			withColor("fin", 1, 2, koi.redColor),
-			withColor("fin", 1, 2, koi.blackColor),
-		),
+func (koi ShowaKoi) finImages() []util.ImageWithColor {
+	return util.ConcatPreAllocate(
+		withColor("fin", 1, 2, koi.redColor),
+		withColor("fin", 1, 2, koi.blackColor),
 	)
 }
 
@@ -66,21 +62,17 @@ func (koi ShowaKoi) getBodyColor(randomSeed int) color.Color {
 	return koi.bodyAndFinColor
 }
 
-func (koi ShowaKoi) getBodyImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi ShowaKoi) bodyImages() []util.ImageWithColor {
 	// generate the red color - so that all image patterns have the same red color
-	return pickAmount(
-		amount,
-		randomSeed,
-		util.ConcatPreAllocate(
-			withColor("body", 1, 8, koi.redColor),
-			withColor("body", 1, 8, koi.blackColor),
-		),
+	return util.ConcatPreAllocate(
+		withColor("body", 1, 8, koi.redColor),
+		withColor("body", 1, 8, koi.blackColor),
 	)
 }
 
-func (koi ShowaKoi) getHeadImages(amount int, randomSeed int) []util.ImageWithColor {
-	return pickAmount(amount, randomSeed, util.ConcatPreAllocate(
+func (koi ShowaKoi) headImages() []util.ImageWithColor {
+	return util.ConcatPreAllocate(
 		withColor("head", 1, 5, koi.redColor),
 		withColor("head", 1, 5, koi.redColor),
-	))
+	)
 }
@@ -0,0 +1,75 @@
+package cryptokoi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// reads the transfer logs of the contract.
+type TransferSource interface {
+	// the number of the latest block of the chain.
+	BlockNumber(ctx context.Context) (uint64, error)
+	// the transfers of the inclusive block range - ordered by block number and log index.
+	FilterTransfers(ctx context.Context, from uint64, to uint64) ([]CryptoKoiEvent, error)
+	// receives a value for every live transfer until the cancel chan gets closed.
+	// only a hint to check for new logs - there is no guarantee a transfer is delivered.
+	Notify(cancelChan <-chan struct{}) <-chan CryptoKoiEvent
+}
+
+// implemented by ethclient.Client
+type BlockNumberReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+type ChainTransferSource struct {
+	chain    BlockNumberReader
+	binding  *CryptoKoiBinding
+	listener *CryptoKoiEventListener
+}
+
+// pass the http binding - FilterTransfer requests many blocks at once. The listener watches the live logs.
+func NewChainTransferSource(chain BlockNumberReader, binding *CryptoKoiBinding, listener *CryptoKoiEventListener) TransferSource {
+	return &ChainTransferSource{
+		chain:    chain,
+		binding:  binding,
+		listener: listener,
+	}
+}
+
+func (s *ChainTransferSource) BlockNumber(ctx context.Context) (uint64, error) {
+	return s.chain.BlockNumber(ctx)
+}
+
+func (s *ChainTransferSource) FilterTransfers(ctx context.Context, from uint64, to uint64) ([]CryptoKoiEvent, error) {
+	it, err := s.binding.FilterTransfer(&bind.FilterOpts{
+		Start:   from,
+		End:     &to,
+		Context: ctx,
+	}, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var events []CryptoKoiEvent
+	for it.Next() {
+		events = append(events, newCryptoKoiEvent(it.Event))
+	}
+	if it.Error() != nil {
+		return nil, it.Error()
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+	return events, nil
+}
+
+func (s *ChainTransferSource) Notify(cancelChan <-chan struct{}) <-chan CryptoKoiEvent {
+	return s.listener.StartListener(cancelChan)
+}
@@ -31,8 +31,8 @@ func (koi UtsuriKoi) getType() KoiType {
 	return koi.koiType
 }
 
-func (koi UtsuriKoi) getFinImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("fin", amount, randomSeed, 1, 2, koi.color)
+func (koi UtsuriKoi) finImages() []util.ImageWithColor {
+	return withColor("fin", 1, 2, koi.color)
 }
 
 func (koi UtsuriKoi) amountFinImages() (int, int) {
@@ -55,11 +55,11 @@ func (koi UtsuriKoi) getBodyColor(randomSeed int) color.Color {
 	return pickColorOutOf(randomSeed, WhiteColorRange, OrangeColorRange, RedColorRange)
 }
 
-func (koi UtsuriKoi) getBodyImages(amount int, randomSeed int) []util.ImageWithColor {
+func (koi UtsuriKoi) bodyImages() []util.ImageWithColor {
 	// generate the red color - so that all image patterns have the same red color
-	return amountWithColor("body", amount, randomSeed, 1, 8, koi.color)
+	return withColor("body", 1, 8, koi.color)
 }
 
-func (koi UtsuriKoi) getHeadImages(amount int, randomSeed int) []util.ImageWithColor {
-	return amountWithColor("head", amount, randomSeed, 1, 5, koi.color)
+func (koi UtsuriKoi) headImages() []util.ImageWithColor {
+	return withColor("head", 1, 5, koi.color)
 }
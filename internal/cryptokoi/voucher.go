@@ -0,0 +1,122 @@
+package cryptokoi
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	solsha3 "github.com/miguelmota/go-solidity-sha3"
+)
+
+// needs to match the EIP712 constructor call and the VOUCHER_TYPEHASH of contracts/CryptoKoi.sol
+const (
+	voucherDomainName    = "CryptoKoi"
+	voucherDomainVersion = "1"
+)
+
+var (
+	domainTypeHash  = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	voucherTypeHash = crypto.Keccak256([]byte("Voucher(address account,uint256 tokenId,uint256 price,uint256 expiry)"))
+
+	ErrInvalidSignature = errors.New("invalid voucher signature")
+	ErrVoucherExpired   = errors.New("voucher expired")
+)
+
+// binds a voucher to a single deployment of the contract.
+type VoucherDomain struct {
+	ChainId           *big.Int
+	VerifyingContract common.Address
+}
+
+// the EIP-712 typed data redeemed by redeemVoucher.
+type Voucher struct {
+	Account common.Address
+	TokenId *big.Int
+	// in wei
+	Price  *big.Int
+	Expiry time.Time
+}
+
+func (d VoucherDomain) separator() []byte {
+	return crypto.Keccak256(
+		domainTypeHash,
+		crypto.Keccak256([]byte(voucherDomainName)),
+		crypto.Keccak256([]byte(voucherDomainVersion)),
+		math.U256Bytes(new(big.Int).Set(d.ChainId)),
+		common.LeftPadBytes(d.VerifyingContract.Bytes(), 32),
+	)
+}
+
+// the digest signed by the contract owner: keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(voucher))
+func (v Voucher) Hash(domain VoucherDomain) common.Hash {
+	structHash := crypto.Keccak256(
+		voucherTypeHash,
+		common.LeftPadBytes(v.Account.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(v.TokenId)),
+		math.U256Bytes(new(big.Int).Set(v.Price)),
+		math.U256Bytes(big.NewInt(v.Expiry.Unix())),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domain.separator(), structHash)
+}
+
+func SignVoucher(privateKey *ecdsa.PrivateKey, domain VoucherDomain, voucher Voucher) (string, error) {
+	hash := voucher.Hash(domain)
+	sig, err := crypto.Sign(hash[:], privateKey)
+	if err != nil {
+		return "", err
+	}
+	// solidity expects v to be 27 or 28.
+	sig[64] += 27
+	return hexutil.Encode(sig), nil
+}
+
+// checks the signature and the expiry of the voucher - exactly like redeemVoucher does.
+func VerifyVoucher(signer common.Address, domain VoucherDomain, voucher Voucher, signature string, now time.Time) error {
+	if now.After(voucher.Expiry) {
+		return ErrVoucherExpired
+	}
+	hash := voucher.Hash(domain)
+	return verify(signer, hash[:], signature)
+}
+
+// the vouchers issued before the migration to EIP-712.
+// they are accepted by the legacy redeem function until the contract owner disables them.
+func LegacyVoucherHash(tokenId *big.Int, account common.Address) []byte {
+	return solsha3.SoliditySHA3WithPrefix(solsha3.SoliditySHA3(
+		[]string{"uint256", "address"},
+		[]interface{}{
+			tokenId,
+			account.Hex(),
+		},
+	))
+}
+
+func VerifyLegacyVoucher(signer common.Address, tokenId *big.Int, account common.Address, signature string) error {
+	return verify(signer, LegacyVoucherHash(tokenId, account), signature)
+}
+
+func verify(signer common.Address, hash []byte, signature string) error {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return ErrInvalidSignature
+	}
+	// do not modify the passed signature.
+	sig = append([]byte{}, sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if crypto.PubkeyToAddress(*publicKey) != signer {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cryptokoi
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	contractAddress = common.HexToAddress("0x133c4b6c69322D09C5B266EFa9559173B6c9F029")
+	testDomain      = VoucherDomain{ChainId: big.NewInt(137), VerifyingContract: contractAddress}
+)
+
+func testVoucher(expiry time.Time) Voucher {
+	tokenId, _ := new(big.Int).SetString("239264596381739575473221873891232270519", 10)
+	return Voucher{
+		Account: common.HexToAddress(otherUserAddress),
+		TokenId: tokenId,
+		Price:   big.NewInt(1000000000),
+		Expiry:  expiry,
+	}
+}
+
+func TestVoucherHashMatchesEIP712(t *testing.T) {
+	voucher := testVoucher(time.Unix(1700000000, 0))
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Voucher": {
+				{Name: "account", Type: "address"},
+				{Name: "tokenId", Type: "uint256"},
+				{Name: "price", Type: "uint256"},
+				{Name: "expiry", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Voucher",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "CryptoKoi",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(137),
+			VerifyingContract: contractAddress.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"account": otherUserAddress,
+			"tokenId": voucher.TokenId.String(),
+			"price":   voucher.Price.String(),
+			"expiry":  "1700000000",
+		},
+	}
+
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	assert.Nil(t, err)
+	structHash, err := typedData.HashStruct("Voucher", typedData.Message)
+	assert.Nil(t, err)
+
+	expected := crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator, structHash)
+	assert.Equal(t, expected, voucher.Hash(testDomain))
+}
+
+func TestSignAndVerifyVoucher(t *testing.T) {
+	key, _ := crypto.HexToECDSA(privateKey[2:])
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	now := time.Now()
+	voucher := testVoucher(now.Add(time.Hour))
+
+	signature, err := SignVoucher(key, testDomain, voucher)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifyVoucher(signer, testDomain, voucher, signature, now))
+
+	// another chain.
+	otherChain := VoucherDomain{ChainId: big.NewInt(1), VerifyingContract: contractAddress}
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, otherChain, voucher, signature, now))
+
+	// another deployment.
+	otherContract := VoucherDomain{ChainId: big.NewInt(137), VerifyingContract: common.HexToAddress(otherUserAddress)}
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, otherContract, voucher, signature, now))
+
+	// a cheaper price.
+	cheaper := voucher
+	cheaper.Price = big.NewInt(1)
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, testDomain, cheaper, signature, now))
+
+	// extended expiry.
+	extended := voucher
+	extended.Expiry = voucher.Expiry.Add(time.Hour)
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, testDomain, extended, signature, now))
+
+	// another signer.
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(common.HexToAddress(otherUserAddress), testDomain, voucher, signature, now))
+
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, testDomain, voucher, "0x1234", now))
+}
+
+func TestVerifyExpiredVoucher(t *testing.T) {
+	key, _ := crypto.HexToECDSA(privateKey[2:])
+	now := time.Now()
+	voucher := testVoucher(now.Add(-time.Second))
+
+	signature, err := SignVoucher(key, testDomain, voucher)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrVoucherExpired, VerifyVoucher(crypto.PubkeyToAddress(key.PublicKey), testDomain, voucher, signature, now))
+}
+
+func TestVerifyLegacyVoucher(t *testing.T) {
+	key, _ := crypto.HexToECDSA(privateKey[2:])
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	voucher := testVoucher(time.Now())
+
+	// the signature issued before the migration.
+	assert.Nil(t, VerifyLegacyVoucher(signer, voucher.TokenId, voucher.Account, expectedSignature))
+	assert.Equal(t, ErrInvalidSignature, VerifyLegacyVoucher(signer, big.NewInt(1), voucher.Account, expectedSignature))
+	// a legacy signature is no valid typed data voucher.
+	assert.Equal(t, ErrInvalidSignature, VerifyVoucher(signer, testDomain, voucher, expectedSignature, time.Now().Add(-time.Minute)))
+}
+
+// the same values are used by test/CryptoKoi.spec.ts
+func TestFixedVoucherSignature(t *testing.T) {
+	key, _ := crypto.HexToECDSA(privateKey[2:])
+	voucher := testVoucher(time.Unix(1700000000, 0))
+
+	assert.Equal(t, "0xaf4659e6a9b84ba77d4aa28c59e67d904321189ef119097e15074d877a91a2ec", voucher.Hash(testDomain).Hex())
+	signature, err := SignVoucher(key, testDomain, voucher)
+	assert.Nil(t, err)
+	assert.Equal(t, "0xc3dc3426cb48e36d0a98273d451c1fbaa7a10d29172c8b52f2dee82fc9a6a83b6a4f73e213e99f4019a9720680d6f486690418af0d0471dbc5a03af5641cce2f1c", signature)
+}
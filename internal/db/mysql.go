@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
@@ -35,9 +36,33 @@ func NewMySQL(config MySQLConfig) (*gorm.DB, error) {
 	orchardclient.FailOnError(err, "failed during automigrate")
 	err = db.AutoMigrate(&models.GameStat{})
 	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.Nonce{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.Session{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.RefreshToken{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.Device{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.BlockCheckpoint{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.ProcessedLog{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = db.AutoMigrate(&models.OwnershipTransfer{})
+	orchardclient.FailOnError(err, "failed during automigrate")
+	err = migrateDeviceIds(db)
+	orchardclient.FailOnError(err, "failed to migrate the device ids")
 	return db, nil
 }
 
+// copies the device ids of the users table into the devices table.
+// safe to run multiple times.
+func migrateDeviceIds(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO devices (id, user_id, device_id, created_at, updated_at)
+		SELECT UUID(), users.id, users.device_id, NOW(), NOW() FROM users
+		WHERE users.device_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM devices WHERE devices.device_id = users.device_id)`).Error
+}
+
 func IsNotFound(err error) bool {
-	return gorm.ErrRecordNotFound == err
+	return errors.Is(err, gorm.ErrRecordNotFound)
 }
@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+const (
+	// the recommended open graph image size.
+	CARD_WIDTH  int = 1200
+	CARD_HEIGHT int = 630
+
+	cardMargin int = 48
+)
+
+// the information printed next to the koi on a share card.
+type CardData struct {
+	Name    string
+	Species string
+	// -1 if the cryptogotchi is not ranked yet.
+	Rank    int
+	Age     time.Duration
+	IsAlive bool
+}
+
+type cardFonts struct {
+	title   font.Face
+	body    font.Face
+	caption font.Face
+}
+
+var (
+	regularFont, boldFont *opentype.Font
+	parseFontsOnce        sync.Once
+)
+
+func mustParse(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		// the fonts are embedded into the binary - this can only fail on a broken build.
+		panic(err)
+	}
+	return f
+}
+
+func mustFace(f *opentype.Font, size float64) font.Face {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+// font faces are not safe for concurrent use - therefore every card gets its own faces.
+func newCardFonts() cardFonts {
+	parseFontsOnce.Do(func() {
+		regularFont = mustParse(goregular.TTF)
+		boldFont = mustParse(gobold.TTF)
+	})
+	return cardFonts{
+		title:   mustFace(boldFont, 60),
+		body:    mustFace(regularFont, 40),
+		caption: mustFace(boldFont, 28),
+	}
+}
+
+// formats the age of a cryptogotchi in the largest meaningful unit.
+func FormatAge(age time.Duration) string {
+	switch {
+	case age >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(age.Hours()/24))
+	case age >= 24*time.Hour:
+		return "1 day"
+	case age >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(age.Hours()))
+	case age >= time.Hour:
+		return "1 hour"
+	default:
+		return fmt.Sprintf("%d minutes", int(age.Minutes()))
+	}
+}
+
+// shortens the text until it fits into the provided width.
+func fitText(face font.Face, text string, maxWidth int) string {
+	if font.MeasureString(face, text).Ceil() <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if font.MeasureString(face, candidate).Ceil() <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func drawText(dst draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+// renders an open graph sized share card.
+// the koi image should be at least CARD_HEIGHT pixels wide to avoid upscaling.
+func RenderCard(koiImg image.Image, primaryColor color.Color, data CardData) image.Image {
+	fonts := newCardFonts()
+	card := image.NewRGBA(image.Rect(0, 0, CARD_WIDTH, CARD_HEIGHT))
+
+	background := primaryColor
+	if !data.IsAlive {
+		// dead kois get a gray card.
+		background = color.RGBA{R: 90, G: 90, B: 90, A: 255}
+	}
+	drawGradient(card, util.Shade(background, 30), util.Shade(background, -40))
+
+	// the koi is placed on a pond on the left side of the card.
+	koiSize := CARD_HEIGHT - 2*cardMargin
+	koiRect := image.Rect(cardMargin, cardMargin, cardMargin+koiSize, cardMargin+koiSize)
+	pond := image.NewRGBA(image.Rect(0, 0, koiSize, koiSize))
+	draw.CatmullRom.Scale(pond, pond.Bounds(), koiImg, koiImg.Bounds(), draw.Src, nil)
+	framed := Frame(pond, primaryColor, FrameOptions{
+		Background: PondBackground,
+		Padding:    koiSize / 16,
+		Mask:       CircleMask,
+	})
+	draw.Draw(card, koiRect, framed, image.Point{}, draw.Over)
+
+	textColor := color.Color(color.White)
+	secondaryColor := color.Color(color.NRGBA{R: 255, G: 255, B: 255, A: 200})
+	if !util.IsDark(util.Shade(background, -5)) {
+		textColor = color.RGBA{R: 20, G: 20, B: 20, A: 255}
+		secondaryColor = color.NRGBA{R: 20, G: 20, B: 20, A: 200}
+	}
+
+	textX := koiRect.Max.X + cardMargin
+	textWidth := CARD_WIDTH - textX - cardMargin
+
+	state := "Alive"
+	if !data.IsAlive {
+		state = "Dead"
+	}
+	rank := "Unranked"
+	if data.Rank > 0 {
+		rank = fmt.Sprintf("Rank #%d", data.Rank)
+	}
+
+	y := cardMargin + 110
+	drawText(card, fonts.title, textColor, textX, y, fitText(fonts.title, data.Name, textWidth))
+	y += 64
+	drawText(card, fonts.body, secondaryColor, textX, y, fitText(fonts.body, strings.Title(data.Species), textWidth))
+
+	y += 110
+	for _, line := range []string{rank, "Age: " + FormatAge(data.Age), state} {
+		drawText(card, fonts.body, textColor, textX, y, fitText(fonts.body, line, textWidth))
+		y += 60
+	}
+
+	drawText(card, fonts.caption, secondaryColor, textX, CARD_HEIGHT-cardMargin, "CryptoKoi")
+	return card
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCard(t *testing.T) {
+	card := RenderCard(transparentKoi(200), color.RGBA{R: 200, G: 10, B: 10, A: 255}, CardData{
+		Name:    "A very long name which does not fit onto the card at all",
+		Species: "kohaku",
+		Rank:    3,
+		Age:     50 * time.Hour,
+		IsAlive: true,
+	})
+	assert.Equal(t, image.Rect(0, 0, CARD_WIDTH, CARD_HEIGHT), card.Bounds())
+}
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "5 minutes", FormatAge(5*time.Minute))
+	assert.Equal(t, "1 hour", FormatAge(90*time.Minute))
+	assert.Equal(t, "1 day", FormatAge(30*time.Hour))
+	assert.Equal(t, "3 days", FormatAge(80*time.Hour))
+}
@@ -0,0 +1,241 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+type BackgroundType string
+
+const (
+	NoBackground       BackgroundType = "none"
+	SolidBackground    BackgroundType = "solid"
+	GradientBackground BackgroundType = "gradient"
+	PondBackground     BackgroundType = "pond"
+)
+
+func IsBackgroundType(stringToCheck string) (BackgroundType, error) {
+	switch BackgroundType(stringToCheck) {
+	case "", NoBackground:
+		return NoBackground, nil
+	case SolidBackground:
+		return SolidBackground, nil
+	case GradientBackground:
+		return GradientBackground, nil
+	case PondBackground:
+		return PondBackground, nil
+	default:
+		return "", fmt.Errorf("unknown background type: %s", stringToCheck)
+	}
+}
+
+type MaskType string
+
+const (
+	NoMask      MaskType = "none"
+	RoundedMask MaskType = "rounded"
+	CircleMask  MaskType = "circle"
+)
+
+func IsMaskType(stringToCheck string) (MaskType, error) {
+	switch MaskType(stringToCheck) {
+	case "", NoMask:
+		return NoMask, nil
+	case RoundedMask:
+		return RoundedMask, nil
+	case CircleMask:
+		return CircleMask, nil
+	default:
+		return "", fmt.Errorf("unknown mask type: %s", stringToCheck)
+	}
+}
+
+// options used to composite a rendered koi into a share card or an avatar.
+// the zero value leaves the image untouched.
+type FrameOptions struct {
+	Background BackgroundType
+	// padding in pixels between the image border and the koi.
+	// the koi gets scaled down to fit - the image keeps its size.
+	Padding int
+	Mask    MaskType
+	// only used by the rounded mask. Defaults to 1/8 of the image width.
+	CornerRadius int
+}
+
+func (o FrameOptions) isZero() bool {
+	return (o.Background == "" || o.Background == NoBackground) && o.Padding == 0 && (o.Mask == "" || o.Mask == NoMask)
+}
+
+// composites the koi image onto the requested background and applies the mask.
+// the provided image is never mutated - it might be cached by the preloader.
+func Frame(img image.Image, primaryColor color.Color, opts FrameOptions) image.Image {
+	if opts.isZero() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	result := image.NewRGBA(bounds)
+
+	switch opts.Background {
+	case SolidBackground:
+		draw.Draw(result, bounds, image.NewUniform(primaryColor), image.Point{}, draw.Src)
+	case GradientBackground:
+		drawGradient(result, util.Shade(primaryColor, 40), util.Shade(primaryColor, -30))
+	case PondBackground:
+		drawPond(result)
+	}
+
+	// scale the koi into the padded area.
+	inner := image.Rect(bounds.Min.X+opts.Padding, bounds.Min.Y+opts.Padding, bounds.Max.X-opts.Padding, bounds.Max.Y-opts.Padding)
+	if opts.Padding > 0 && !inner.Empty() {
+		draw.CatmullRom.Scale(result, inner, img, bounds, draw.Over, nil)
+	} else {
+		draw.Draw(result, bounds, img, bounds.Min, draw.Over)
+	}
+
+	var mask image.Image
+	switch opts.Mask {
+	case CircleMask:
+		mask = circleMask{bounds: bounds}
+	case RoundedMask:
+		radius := opts.CornerRadius
+		if radius <= 0 {
+			radius = bounds.Dx() / 8
+		}
+		mask = roundedRectMask{bounds: bounds, radius: radius}
+	default:
+		return result
+	}
+
+	masked := image.NewRGBA(bounds)
+	draw.DrawMask(masked, bounds, result, bounds.Min, mask, bounds.Min, draw.Src)
+	return masked
+}
+
+// vertical gradient from top to bottom
+func drawGradient(dst *image.RGBA, top, bottom color.Color) {
+	bounds := dst.Bounds()
+	tr, tg, tb := util.RGBA(top)
+	br, bg, bb := util.RGBA(bottom)
+	height := float64(bounds.Dy() - 1)
+	if height <= 0 {
+		height = 1
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		t := float64(y-bounds.Min.Y) / height
+		c := color.RGBA{
+			R: lerp(tr, br, t),
+			G: lerp(tg, bg, t),
+			B: lerp(tb, bb, t),
+			A: 255,
+		}
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dst.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func lerp(from, to uint32, t float64) uint8 {
+	return uint8(float64(from) + (float64(to)-float64(from))*t)
+}
+
+var (
+	pondDeep    = color.RGBA{R: 12, G: 58, B: 74, A: 255}
+	pondShallow = color.RGBA{R: 38, G: 112, B: 122, A: 255}
+)
+
+// draws a procedural water texture. The texture does only depend on the size
+// of the image - therefore the same koi always gets the same pond.
+func drawPond(dst *image.RGBA) {
+	bounds := dst.Bounds()
+	w := float64(bounds.Dx())
+	h := float64(bounds.Dy())
+	cx, cy := w/2, h/2
+	maxDist := math.Hypot(cx, cy)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			nx := float64(x-bounds.Min.X) / w
+			ny := float64(y-bounds.Min.Y) / h
+
+			// a few overlapping sine waves look close enough to caustics.
+			wave := math.Sin(nx*18+math.Sin(ny*7)*2) + math.Sin(ny*23+math.Sin(nx*11)*1.5) + 0.5*math.Sin((nx+ny)*41)
+			// normalize to [0, 1]
+			wave = (wave + 2.5) / 5
+
+			// darken towards the edges of the pond.
+			dist := math.Hypot(float64(x-bounds.Min.X)-cx, float64(y-bounds.Min.Y)-cy) / maxDist
+			t := wave * (1 - 0.6*dist)
+
+			c := color.RGBA{
+				R: lerp(uint32(pondDeep.R), uint32(pondShallow.R), t),
+				G: lerp(uint32(pondDeep.G), uint32(pondShallow.G), t),
+				B: lerp(uint32(pondDeep.B), uint32(pondShallow.B), t),
+				A: 255,
+			}
+			// add highlights on the wave crests
+			if wave > 0.85 {
+				c = util.Shade(c, int32((wave-0.85)*200)).(color.RGBA)
+			}
+			dst.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// returns the alpha coverage of a pixel center which is dist pixels outside of an edge.
+// using a one pixel wide ramp to anti-alias the edges of the masks.
+func coverage(dist float64) color.Alpha {
+	a := 0.5 - dist
+	if a <= 0 {
+		return color.Alpha{A: 0}
+	}
+	if a >= 1 {
+		return color.Alpha{A: 255}
+	}
+	return color.Alpha{A: uint8(a * 255)}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+type circleMask struct {
+	bounds image.Rectangle
+}
+
+func (m circleMask) ColorModel() color.Model { return color.AlphaModel }
+func (m circleMask) Bounds() image.Rectangle { return m.bounds }
+func (m circleMask) At(x, y int) color.Color {
+	r := float64(minInt(m.bounds.Dx(), m.bounds.Dy())) / 2
+	cx := float64(m.bounds.Min.X) + float64(m.bounds.Dx())/2
+	cy := float64(m.bounds.Min.Y) + float64(m.bounds.Dy())/2
+	return coverage(math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) - r)
+}
+
+type roundedRectMask struct {
+	bounds image.Rectangle
+	radius int
+}
+
+func (m roundedRectMask) ColorModel() color.Model { return color.AlphaModel }
+func (m roundedRectMask) Bounds() image.Rectangle { return m.bounds }
+func (m roundedRectMask) At(x, y int) color.Color {
+	r := float64(minInt(m.radius, minInt(m.bounds.Dx()/2, m.bounds.Dy()/2)))
+	px, py := float64(x)+0.5, float64(y)+0.5
+	// clamp the point into the inner rectangle - the distance to it is the distance to the rounded corner.
+	innerMinX, innerMaxX := float64(m.bounds.Min.X)+r, float64(m.bounds.Max.X)-r
+	innerMinY, innerMaxY := float64(m.bounds.Min.Y)+r, float64(m.bounds.Max.Y)-r
+	qx := math.Max(innerMinX, math.Min(px, innerMaxX))
+	qy := math.Max(innerMinY, math.Min(py, innerMaxY))
+	return coverage(math.Hypot(px-qx, py-qy) - r)
+}
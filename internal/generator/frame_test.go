@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func transparentKoi(size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	// the koi itself covers only the center of the image.
+	for x := size / 4; x < size*3/4; x++ {
+		for y := size / 4; y < size*3/4; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	return img
+}
+
+func TestFrameSolidBackgroundKeepsKoi(t *testing.T) {
+	primary := color.RGBA{R: 200, G: 10, B: 10, A: 255}
+	img := Frame(transparentKoi(100), primary, FrameOptions{Background: SolidBackground})
+
+	assert.Equal(t, primary, img.At(0, 0))
+	// the koi must still be visible on top of the background
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.At(50, 50))
+}
+
+func TestFrameCircleMask(t *testing.T) {
+	primary := color.RGBA{R: 200, G: 10, B: 10, A: 255}
+	img := Frame(transparentKoi(100), primary, FrameOptions{Background: SolidBackground, Mask: CircleMask})
+
+	_, _, _, a := img.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0), a)
+	_, _, _, a = img.At(50, 2).RGBA()
+	assert.Equal(t, uint32(0xffff), a)
+}
+
+func TestFramePaddingShrinksKoi(t *testing.T) {
+	img := Frame(transparentKoi(100), color.Black, FrameOptions{Padding: 20})
+
+	assert.Equal(t, image.Rect(0, 0, 100, 100), img.Bounds())
+	// the koi started at 25 - with padding it starts at 20 + 60 * 0.25 = 35
+	_, _, _, a := img.At(30, 50).RGBA()
+	assert.Equal(t, uint32(0), a)
+	_, _, _, a = img.At(50, 50).RGBA()
+	assert.Equal(t, uint32(0xffff), a)
+}
+
+func TestZeroFrameOptionsReturnOriginal(t *testing.T) {
+	original := transparentKoi(10)
+	assert.Same(t, original, Frame(original, color.Black, FrameOptions{}))
+}
+
+func TestIsBackgroundType(t *testing.T) {
+	bg, err := IsBackgroundType("")
+	assert.Nil(t, err)
+	assert.Equal(t, NoBackground, bg)
+
+	_, err = IsBackgroundType("rainbow")
+	assert.NotNil(t, err)
+}
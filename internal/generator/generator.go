@@ -25,6 +25,20 @@ type imageProcessingResult struct {
 	id     int
 }
 
+// the layers every koi consists of - independent of the koi type.
+var baseLayers = []string{"body", "fins", "outlines_highlights_combined"}
+
+// returns the names of all images an asset pack needs to provide.
+func RequiredImageNames() []string {
+	return append(append([]string{}, baseLayers...), cryptokoi.PatternImageNames()...)
+}
+
+// implemented by preloaders which might swap their images at runtime.
+type snapshotter interface {
+	// returns a preloader which is not affected by a swap.
+	Snapshot() Preloader
+}
+
 type Generator struct {
 	preloader Preloader
 	debug     bool
@@ -84,6 +98,11 @@ func (generator *Generator) applyColorToImage(c color.Color, img image.Image) im
 
 func (g *Generator) TokenId2Image(tokenId string, size int) (image.Image, *cryptokoi.CryptoKoi) {
 	koi := cryptokoi.NewKoi(tokenId)
+	preloader := g.preloader
+	if s, ok := preloader.(snapshotter); ok {
+		// all layers of a single image need to come from the same asset pack.
+		preloader = s.Snapshot()
+	}
 
 	attributes := koi.GetAttributes()
 	allImages := util.ConcatPreAllocate(
@@ -100,20 +119,20 @@ func (g *Generator) TokenId2Image(tokenId string, size int) (image.Image, *crypt
 
 	imgProcessingChan <- imageProcessingMessage{
 		id:        0,
-		baseImage: g.preloader.GetImage("body", size),
+		baseImage: preloader.GetImage("body", size),
 		color:     attributes.BodyColor,
 	}
 
 	imgProcessingChan <- imageProcessingMessage{
 		id:        1,
-		baseImage: g.preloader.GetImage("fins", size),
+		baseImage: preloader.GetImage("fins", size),
 		color:     attributes.FinColor,
 	}
 
 	for i, img := range allImages {
 		imgProcessingChan <- imageProcessingMessage{
 			id:        i + 2,
-			baseImage: g.preloader.GetImage(img.ImageName, size),
+			baseImage: preloader.GetImage(img.ImageName, size),
 			color:     img.Color,
 		}
 	}
@@ -134,7 +153,7 @@ func (g *Generator) TokenId2Image(tokenId string, size int) (image.Image, *crypt
 	close(imgProcessingChan)
 	close(imgResultChan)
 
-	resultImages = append(resultImages, g.preloader.GetImage("outlines_highlights_combined", size))
+	resultImages = append(resultImages, preloader.GetImage("outlines_highlights_combined", size))
 	// now we have all images in the collection.
 	// we need to draw them in the correct order.
 	result := recursiveBatchDraw(resultImages)
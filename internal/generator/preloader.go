@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"fmt"
 	"image"
 	"image/png"
 	"os"
@@ -31,27 +32,36 @@ type Preloader interface {
 	BuildCachesForSizes(sizes []int) Preloader
 }
 
-func loadImage(basePath string, name string) image.Image {
+func loadImage(basePath string, name string) (image.Image, error) {
 	if !strings.HasSuffix(name, ".png") {
 		name += ".png"
 	}
 
 	abs, err := filepath.Abs(filepath.Join(basePath, name))
 	if err != nil {
-		orchardclient.Logger.Fatal(err)
+		return nil, err
 	}
 	file, err := os.Open(abs)
 	if err != nil {
-		orchardclient.Logger.Fatal(err)
+		return nil, err
 	}
+	defer file.Close()
 	img, err := png.Decode(file)
 	if err != nil {
-		orchardclient.Logger.Fatal(err)
+		return nil, fmt.Errorf("could not decode %s: %w", name, err)
 	}
-	return img
+	return img, nil
 }
 
 func NewMemoryPreloader(basePath string) Preloader {
+	preloader, err := newMemoryPreloader(basePath)
+	if err != nil {
+		orchardclient.Logger.Fatal(err)
+	}
+	return preloader
+}
+
+func newMemoryPreloader(basePath string) (*MemoryPreloader, error) {
 	// load all images into ram
 	preloader := MemoryPreloader{
 		basePath:        basePath,
@@ -61,7 +71,7 @@ func NewMemoryPreloader(basePath string) Preloader {
 	}
 	entries, err := os.ReadDir(basePath)
 	if err != nil {
-		orchardclient.Logger.Fatal(err)
+		return nil, err
 	}
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".png" {
@@ -69,22 +79,34 @@ func NewMemoryPreloader(basePath string) Preloader {
 		}
 		preloader.availableImages = append(preloader.availableImages, strings.TrimSuffix(entry.Name(), ".png"))
 	}
-	return &preloader
+	return &preloader, nil
 }
 
 func (p *MemoryPreloader) BuildCachesForSizes(sizes []int) Preloader {
+	if err := p.buildCaches(sizes); err != nil {
+		p.logger.Error(err)
+	}
+	return p
+}
+
+// returns the first error which occurred while building the caches.
+func (p *MemoryPreloader) buildCaches(sizes []int) error {
+	var firstErr error
 	for _, size := range sizes {
-		p.buildCacheForSize(size)
+		if err := p.buildCacheForSize(size); err != nil && firstErr == nil {
+			firstErr = err
+		}
 		// call the garbage collector - otherwise the makeTmpBuffer will consume lots of memory.
 		runtime.GC()
 	}
-
-	return p
+	return firstErr
 }
 
-func (p *MemoryPreloader) buildCacheForSize(size int) *MemoryPreloader {
+func (p *MemoryPreloader) buildCacheForSize(size int) error {
 	now := time.Now()
 	var wg sync.WaitGroup
+	var errMut sync.Mutex
+	var firstErr error
 
 	cache, loaded := p.cache.LoadOrStore(size, &sync.Map{})
 	if !loaded {
@@ -93,7 +115,15 @@ func (p *MemoryPreloader) buildCacheForSize(size int) *MemoryPreloader {
 			wg.Add(1)
 			go func(imgName string) {
 				defer wg.Done()
-				img := p.scaleImage(imgName, size)
+				img, err := p.scaleImage(imgName, size)
+				if err != nil {
+					errMut.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMut.Unlock()
+					return
+				}
 				cache.(*sync.Map).Store(imgName, img)
 			}(imageName)
 		}
@@ -101,36 +131,39 @@ func (p *MemoryPreloader) buildCacheForSize(size int) *MemoryPreloader {
 
 	wg.Wait()
 	p.logger.WithField("took", time.Since(now).String()).Info("cache built: ", size)
-	return p
+	return firstErr
 }
 
-func (p *MemoryPreloader) scaleImage(imageName string, size int) image.Image {
+func (p *MemoryPreloader) scaleImage(imageName string, size int) (image.Image, error) {
 	now := time.Now()
 	// the image is not cached.
-	rawImage := loadImage(p.basePath, imageName)
+	rawImage, err := loadImage(p.basePath, imageName)
+	if err != nil {
+		return nil, err
+	}
 
 	// scale the image down.
 	scaledImg := image.NewRGBA(image.Rect(0, 0, size, size))
 	draw.CatmullRom.Scale(scaledImg, scaledImg.Rect, rawImage, rawImage.Bounds(), draw.Over, nil)
 	p.logger.WithField("took", time.Since(now).String()).Warn("cache miss: scaled image: ", imageName, " to size: ", size)
-	return scaledImg
+	return scaledImg, nil
 }
 
 func (p *MemoryPreloader) GetImage(imageName string, size int) image.Image {
-	var img any
-	var ok bool
+	cache, _ := p.cache.LoadOrStore(size, &sync.Map{})
 	// check if the image is already cached
-	if cache, loaded := p.cache.LoadOrStore(size, &sync.Map{}); loaded {
-		if img, ok = cache.(*sync.Map).Load(imageName); ok {
-			return img.(image.Image)
-		}
-		// the image does not exist in the size cache.
-		// create it and cache it.
-		img = p.scaleImage(imageName, size)
-		cache.(*sync.Map).Store(imageName, img)
-	} else {
-		img = p.scaleImage(imageName, size)
-		cache.(*sync.Map).Store(imageName, img)
+	if img, ok := cache.(*sync.Map).Load(imageName); ok {
+		return img.(image.Image)
 	}
-	return img.(image.Image)
+	// the image does not exist in the size cache.
+	// create it and cache it.
+	img, err := p.scaleImage(imageName, size)
+	if err != nil {
+		// a missing layer should not take down the whole server.
+		// render the koi without it - the transparent image gets cached to avoid flooding the logs.
+		p.logger.Errorf("could not load image %s: %s", imageName, err)
+		img = image.NewRGBA(image.Rect(0, 0, size, size))
+	}
+	cache.(*sync.Map).Store(imageName, img)
+	return img
 }
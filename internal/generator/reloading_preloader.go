@@ -0,0 +1,306 @@
+package generator
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+// a single image inside an asset pack is not allowed to be larger than this.
+const maxPackImageSize int64 = 32 << 20
+
+// the state of an asset directory - reported by the admin endpoint.
+type AssetStatus struct {
+	BasePath string `json:"basePath"`
+	// the amount of images currently in use.
+	Images int `json:"images"`
+	// the time the images currently in use were loaded.
+	LoadedAt time.Time `json:"loadedAt"`
+	// the time of the last reload - successful or not.
+	LastReloadAt time.Time `json:"lastReloadAt"`
+	// problems of the last reload. Empty if the last reload succeeded.
+	Problems []string `json:"problems"`
+}
+
+// wraps a memory preloader and swaps it atomically whenever the asset directory changes.
+// a broken asset directory never replaces a working one - the problems get reported instead.
+type ReloadingPreloader struct {
+	basePath string
+	sizes    []int
+	// holds the *MemoryPreloader currently in use.
+	current atomic.Value
+	// only a single reload at a time.
+	reloadMut   sync.Mutex
+	fingerprint string
+
+	statusMut sync.RWMutex
+	status    AssetStatus
+
+	logger *logrus.Entry
+}
+
+func NewReloadingPreloader(basePath string) *ReloadingPreloader {
+	p := &ReloadingPreloader{
+		basePath: basePath,
+		sizes:    make([]int, 0),
+		status: AssetStatus{
+			BasePath: basePath,
+			Problems: make([]string, 0),
+		},
+		logger: orchardclient.Logger.WithField("component", "ReloadingPreloader"),
+	}
+
+	preloader, err := newMemoryPreloader(basePath)
+	if err != nil {
+		// start with an empty preloader - the images might be provided later on.
+		p.logger.Error(err)
+		preloader = &MemoryPreloader{basePath: basePath, availableImages: make([]string, 0), logger: p.logger}
+		p.setProblems([]string{err.Error()})
+	} else if report, err := ValidateAssets(basePath); err == nil && !report.Ok() {
+		// keep serving - the images which are fine are still usable.
+		p.setProblems(report.Errors)
+	}
+	p.fingerprint, _ = fingerprint(basePath)
+	p.current.Store(preloader)
+	p.statusMut.Lock()
+	p.status.Images = len(preloader.availableImages)
+	p.status.LoadedAt = time.Now()
+	p.statusMut.Unlock()
+	return p
+}
+
+func (p *ReloadingPreloader) setProblems(problems []string) {
+	p.statusMut.Lock()
+	defer p.statusMut.Unlock()
+	p.status.Problems = problems
+	p.status.LastReloadAt = time.Now()
+}
+
+func (p *ReloadingPreloader) Status() AssetStatus {
+	p.statusMut.RLock()
+	defer p.statusMut.RUnlock()
+	status := p.status
+	status.Problems = append([]string{}, p.status.Problems...)
+	return status
+}
+
+func (p *ReloadingPreloader) Snapshot() Preloader {
+	return p.current.Load().(*MemoryPreloader)
+}
+
+func (p *ReloadingPreloader) GetImage(imageName string, size int) image.Image {
+	return p.Snapshot().GetImage(imageName, size)
+}
+
+// the sizes are remembered - reloaded images are cached in the same sizes before they get swapped in.
+func (p *ReloadingPreloader) BuildCachesForSizes(sizes []int) Preloader {
+	p.reloadMut.Lock()
+	p.sizes = append(p.sizes, sizes...)
+	p.reloadMut.Unlock()
+
+	p.Snapshot().BuildCachesForSizes(sizes)
+	return p
+}
+
+// loads the asset directory again and swaps the images if it is valid.
+func (p *ReloadingPreloader) Reload() error {
+	p.reloadMut.Lock()
+	defer p.reloadMut.Unlock()
+	return p.reload()
+}
+
+// needs to be called with the reload mutex held.
+func (p *ReloadingPreloader) reload() error {
+	now := time.Now()
+	// compute the fingerprint before loading - changes during the reload trigger another one.
+	fp, err := fingerprint(p.basePath)
+	if err == nil {
+		p.fingerprint = fp
+	}
+
+	preloader, err := validatedPreloader(p.basePath)
+	if err == nil {
+		// build all caches before swapping - requests should never hit a cold cache.
+		err = preloader.buildCaches(p.sizes)
+	}
+	if err != nil {
+		p.logger.Errorf("could not reload assets, keeping the current ones: %s", err)
+		p.setProblems(strings.Split(err.Error(), "\n"))
+		return err
+	}
+
+	p.current.Store(preloader)
+	p.setProblems(make([]string, 0))
+	p.statusMut.Lock()
+	p.status.Images = len(preloader.availableImages)
+	p.status.LoadedAt = time.Now()
+	p.statusMut.Unlock()
+	p.logger.WithField("took", time.Since(now).String()).Info("assets reloaded: ", p.basePath)
+	return nil
+}
+
+// returns a preloader for the directory if it passes the validation.
+func validatedPreloader(basePath string) (*MemoryPreloader, error) {
+	report, err := ValidateAssets(basePath)
+	if err != nil {
+		return nil, err
+	}
+	if !report.Ok() {
+		return nil, fmt.Errorf("%s", strings.Join(report.Errors, "\n"))
+	}
+	return newMemoryPreloader(basePath)
+}
+
+// polls the asset directory and reloads it on changes.
+// blocks until the cancel channel gets closed.
+func (p *ReloadingPreloader) Watch(interval time.Duration, cancelChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cancelChan:
+			return
+		case <-ticker.C:
+			fp, err := fingerprint(p.basePath)
+			if err != nil {
+				p.logger.Error(err)
+				continue
+			}
+			p.reloadMut.Lock()
+			if fp != p.fingerprint {
+				p.logger.Info("asset directory changed: ", p.basePath)
+				// the error is already logged and reported.
+				p.reload()
+			}
+			p.reloadMut.Unlock()
+		}
+	}
+}
+
+// identifies the current state of the asset directory by the name, size and modification time of every png.
+func fingerprint(basePath string) (string, error) {
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".png" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";"), nil
+}
+
+// validates the zipped asset pack and copies its images into the asset directory.
+// images of the directory which are not part of the pack are kept.
+// nothing gets copied if the pack is invalid.
+func (p *ReloadingPreloader) InstallPack(r io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "asset-pack-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || filepath.Ext(file.Name) != ".png" {
+			continue
+		}
+		// only use the base name - this flattens the pack and prevents writing outside of the directory.
+		if err := extractFile(file, filepath.Join(tmpDir, filepath.Base(file.Name))); err != nil {
+			return err
+		}
+	}
+
+	// validate the pack on its own - every image needs to be present and decodable.
+	preloader, err := validatedPreloader(tmpDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range preloader.availableImages {
+		if _, err := loadImage(tmpDir, name); err != nil {
+			return err
+		}
+	}
+
+	p.reloadMut.Lock()
+	defer p.reloadMut.Unlock()
+	for _, name := range preloader.availableImages {
+		if err := copyFile(filepath.Join(tmpDir, name+".png"), filepath.Join(p.basePath, name+".png")); err != nil {
+			return err
+		}
+	}
+	return p.reload()
+}
+
+func extractFile(file *zip.File, dst string) error {
+	if file.UncompressedSize64 > uint64(maxPackImageSize) {
+		return fmt.Errorf("image too large: %s", file.Name)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	// do not trust the header - limit the amount of bytes which get written.
+	n, err := io.Copy(out, io.LimitReader(src, maxPackImageSize+1))
+	if err != nil {
+		return err
+	}
+	if n > maxPackImageSize {
+		return fmt.Errorf("image too large: %s", file.Name)
+	}
+	return nil
+}
+
+// writes to a temporary file first and renames it afterwards.
+// this way a reload never sees a partially written image.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
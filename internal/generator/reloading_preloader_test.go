@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeAssets(t *testing.T, dir string, c color.Color) {
+	for _, name := range RequiredImageNames() {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		img.Set(0, 0, c)
+		f, err := os.Create(filepath.Join(dir, name+".png"))
+		assert.Nil(t, err)
+		assert.Nil(t, png.Encode(f, img))
+		f.Close()
+	}
+}
+
+func TestReloadKeepsWorkingAssetsOnMissingImage(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	p := NewReloadingPreloader(dir)
+	assert.Empty(t, p.Status().Problems)
+
+	snapshot := p.Snapshot()
+	assert.Nil(t, os.Remove(filepath.Join(dir, "body.png")))
+
+	assert.NotNil(t, p.Reload())
+	assert.Contains(t, p.Status().Problems, "missing image: body.png")
+	// the old images are still in use.
+	assert.Same(t, snapshot, p.Snapshot())
+}
+
+func TestReloadSwapsImages(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	p := NewReloadingPreloader(dir)
+	snapshot := p.Snapshot()
+
+	writeAssets(t, dir, color.Black)
+	assert.Nil(t, p.Reload())
+	assert.NotSame(t, snapshot, p.Snapshot())
+	assert.Empty(t, p.Status().Problems)
+}
+
+func TestInstallPack(t *testing.T) {
+	packDir := t.TempDir()
+	writeAssets(t, packDir, color.Black)
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, name := range RequiredImageNames() {
+		content, err := os.ReadFile(filepath.Join(packDir, name+".png"))
+		assert.Nil(t, err)
+		// nested directories get flattened.
+		f, err := w.Create("pack/" + name + ".png")
+		assert.Nil(t, err)
+		f.Write(content)
+	}
+	assert.Nil(t, w.Close())
+
+	dir := t.TempDir()
+	p := NewReloadingPreloader(dir)
+	assert.NotEmpty(t, p.Status().Problems)
+
+	assert.Nil(t, p.InstallPack(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	assert.Empty(t, p.Status().Problems)
+	assert.Equal(t, len(RequiredImageNames()), p.Status().Images)
+	_, err := os.Stat(filepath.Join(dir, "body.png"))
+	assert.Nil(t, err)
+}
+
+func TestInstallPackRejectsIncompletePack(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, _ := w.Create("body.png")
+	png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	assert.Nil(t, w.Close())
+
+	dir := t.TempDir()
+	p := NewReloadingPreloader(dir)
+	assert.NotNil(t, p.InstallPack(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	// nothing gets copied.
+	_, err := os.Stat(filepath.Join(dir, "body.png"))
+	assert.True(t, os.IsNotExist(err))
+}
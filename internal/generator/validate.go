@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// the result of validating an asset directory.
+type AssetReport struct {
+	Dir string
+	// problems which break the image generation.
+	Errors []string
+	// files which are not used by any koi type.
+	Warnings []string
+}
+
+func (r AssetReport) Ok() bool {
+	return len(r.Errors) == 0
+}
+
+// png images without an alpha channel are decoded with an opaque color model.
+func hasAlpha(model color.Model) bool {
+	switch m := model.(type) {
+	case color.Palette:
+		for _, c := range m {
+			if _, _, _, a := c.RGBA(); a != 0xffff {
+				return true
+			}
+		}
+		return false
+	default:
+		return model == color.NRGBAModel || model == color.NRGBA64Model
+	}
+}
+
+func decodeConfig(path string) (image.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	return png.DecodeConfig(f)
+}
+
+// checks that the directory contains every image referenced by the koi types,
+// that all images share the same dimensions and that they have an alpha channel.
+// only the headers of the images get read.
+func ValidateAssets(dir string) (AssetReport, error) {
+	report := AssetReport{
+		Dir:      dir,
+		Errors:   make([]string, 0),
+		Warnings: make([]string, 0),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return report, err
+	}
+
+	configs := make(map[string]image.Config)
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".png" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".png")
+		config, err := decodeConfig(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("could not decode %s: %s", entry.Name(), err))
+			continue
+		}
+		configs[name] = config
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	required := make(map[string]bool)
+	for _, name := range RequiredImageNames() {
+		required[name] = true
+		if _, ok := configs[name]; !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("missing image: %s.png", name))
+		}
+	}
+
+	// all layers are drawn on top of each other - the body defines the size.
+	reference, ok := configs["body"]
+	if !ok {
+		for _, name := range names {
+			if required[name] {
+				reference = configs[name]
+				break
+			}
+		}
+	}
+	for _, name := range names {
+		if !required[name] {
+			// unused images are never drawn - their format does not matter.
+			report.Warnings = append(report.Warnings, fmt.Sprintf("unused image: %s.png", name))
+			continue
+		}
+		config := configs[name]
+		if config.Width != reference.Width || config.Height != reference.Height {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s.png is %dx%d, expected %dx%d", name, config.Width, config.Height, reference.Width, reference.Height))
+		}
+		if !hasAlpha(config.ColorModel) {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s.png has no alpha channel", name))
+		}
+	}
+	return report, nil
+}
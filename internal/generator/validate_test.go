@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writePNG(t *testing.T, path string, img image.Image) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Nil(t, png.Encode(f, img))
+}
+
+func TestValidateAssets(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+
+	report, err := ValidateAssets(dir)
+	assert.Nil(t, err)
+	assert.True(t, report.Ok())
+	assert.Empty(t, report.Warnings)
+}
+
+func TestValidateAssetsMissingImage(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	assert.Nil(t, os.Remove(filepath.Join(dir, "body_7.png")))
+
+	report, err := ValidateAssets(dir)
+	assert.Nil(t, err)
+	assert.False(t, report.Ok())
+	assert.Equal(t, []string{"missing image: body_7.png"}, report.Errors)
+}
+
+func TestValidateAssetsWrongDimensions(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	writePNG(t, filepath.Join(dir, "head_1.png"), image.NewNRGBA(image.Rect(0, 0, 8, 8)))
+
+	report, _ := ValidateAssets(dir)
+	assert.Equal(t, []string{"head_1.png is 8x8, expected 4x4"}, report.Errors)
+}
+
+func TestValidateAssetsNoAlpha(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	writePNG(t, filepath.Join(dir, "fin_1.png"), image.NewGray(image.Rect(0, 0, 4, 4)))
+
+	report, _ := ValidateAssets(dir)
+	assert.Equal(t, []string{"fin_1.png has no alpha channel"}, report.Errors)
+}
+
+func TestValidateAssetsUnusedImage(t *testing.T) {
+	dir := t.TempDir()
+	writeAssets(t, dir, color.White)
+	// unused images are only reported - even if they would be invalid layers.
+	writePNG(t, filepath.Join(dir, "unused.png"), image.NewGray(image.Rect(0, 0, 8, 8)))
+
+	report, _ := ValidateAssets(dir)
+	assert.True(t, report.Ok())
+	assert.Equal(t, []string{"unused image: unused.png"}, report.Warnings)
+}
@@ -3,23 +3,76 @@ package http_dto
 type LoginType string
 
 type LoginRequest struct {
+	// Sign-In with Ethereum: the EIP-4361 message and its signature.
+	Message   *string `json:"message"`
+	Signature *string `json:"signature"`
+	// Deprecated: does not prove the ownership of the wallet. Use message and signature instead.
 	WalletAddress *string `json:"walletAddress"`
 	DeviceId      *string `json:"deviceId"`
 }
 
+// everything a client needs to build a Sign-In with Ethereum message.
+type NonceResponse struct {
+	Nonce          string `json:"nonce"`
+	Domain         string `json:"domain"`
+	URI            string `json:"uri"`
+	Version        string `json:"version"`
+	ChainId        int    `json:"chainId"`
+	Statement      string `json:"statement"`
+	IssuedAt       string `json:"issuedAt"`
+	ExpirationTime string `json:"expirationTime"`
+}
+
 type RegisterRequest struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	// either wallet address or device id needs to be defined.
-	WalletAddress *string `json:"walletAddress"`
-	DeviceId      *string `json:"deviceId"`
+	Name     string  `json:"name"`
+	Email    string  `json:"email"`
+	DeviceId *string `json:"deviceId"`
 }
 
 type TokenResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+	// seconds until the access token expires.
+	ExpiresIn int `json:"expiresIn"`
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken"`
 }
+
+// a public key in the JSON Web Key format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// the token of a link sent by mail.
+type EmailLinkRequest struct {
+	Token string `json:"token"`
+}
+
+type RecoveryRequest struct {
+	Token string `json:"token"`
+	// the id of the new device.
+	DeviceId string `json:"deviceId"`
+}
+
+type PairingRequest struct {
+	// the code created on the device which is already logged in.
+	Code string `json:"code"`
+	// the id of the device to link.
+	DeviceId string `json:"deviceId"`
+}
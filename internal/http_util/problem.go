@@ -0,0 +1,116 @@
+package http_util
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// the error body of every REST endpoint - see RFC 7807.
+type Problem struct {
+	// the status code carries the semantics - the code member is more specific.
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// extension members.
+	// a stable code clients can rely on - the detail might change.
+	Code apperror.Code `json:"code"`
+	// set by the RequestID middleware. Allows to find the request in the logs.
+	RequestId string `json:"requestId,omitempty"`
+	// only set for the COOLDOWN and RATE_LIMITED codes.
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+	// the single findings of a failed validation - for example the problems of an asset pack.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func NewProblem(req *http.Request, status int, code apperror.Code, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  req.URL.Path,
+		Code:      code,
+		RequestId: middleware.GetReqID(req.Context()),
+	}
+}
+
+// writes a problem document. Never pass the message of an internal error as detail.
+func WriteProblem(w http.ResponseWriter, req *http.Request, status int, code apperror.Code, detail string) {
+	WriteProblemDocument(w, NewProblem(req, status, code, detail))
+}
+
+// writes a problem document carrying extension members. Use WriteProblem otherwise.
+func WriteProblemDocument(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	if problem.RetryAt != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(secondsUntil(*problem.RetryAt)))
+	}
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// rounded up - a client retrying after the returned seconds is never too early.
+func secondsUntil(t time.Time) int {
+	seconds := int((time.Until(t) + time.Second - 1) / time.Second)
+	if seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+var codeStatus = map[apperror.Code]int{
+	apperror.NotFound:        http.StatusNotFound,
+	apperror.Forbidden:       http.StatusForbidden,
+	apperror.Unauthenticated: http.StatusUnauthorized,
+	apperror.Cooldown:        http.StatusTooManyRequests,
+	apperror.RateLimited:     http.StatusTooManyRequests,
+	apperror.Validation:      http.StatusBadRequest,
+	apperror.Conflict:        http.StatusConflict,
+	apperror.Gone:            http.StatusGone,
+	apperror.Internal:        http.StatusInternalServerError,
+}
+
+// maps the code of the error to the status. Errors which are no apperror.Error are hidden.
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "internal server error")
+		return
+	}
+	status, ok := codeStatus[appErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	problem := NewProblem(req, status, appErr.Code, appErr.Message)
+	problem.RetryAt = appErr.RetryAt
+	WriteProblemDocument(w, problem)
+}
+
+// like middleware.Recoverer but answers with a problem document.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					// the client is gone - nothing to answer.
+					panic(rvr)
+				}
+				orchardclient.Logger.WithField("requestId", middleware.GetReqID(req.Context())).Errorf("recovered from panic: %v\n%s", rvr, debug.Stack())
+				WriteProblem(w, req, http.StatusInternalServerError, apperror.Internal, "internal server error")
+			}
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,92 @@
+package http_util
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	var body map[string]interface{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func TestWriteProblem(t *testing.T) {
+	var rec *httptest.ResponseRecorder
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec = w.(*httptest.ResponseRecorder)
+		WriteProblem(w, req, http.StatusNotFound, apperror.NotFound, "user not found")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	body := decodeProblem(t, rec)
+	assert.Equal(t, "about:blank", body["type"])
+	assert.Equal(t, "Not Found", body["title"])
+	assert.Equal(t, float64(404), body["status"])
+	assert.Equal(t, "user not found", body["detail"])
+	assert.Equal(t, "/auth/login", body["instance"])
+	assert.Equal(t, "NOT_FOUND", body["code"])
+	assert.NotEmpty(t, body["requestId"])
+}
+
+func TestWriteProblemWithoutRequestId(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, httptest.NewRequest(http.MethodGet, "/v1/tokens/1", nil), http.StatusBadRequest, apperror.Validation, "invalid tokenId")
+
+	body := decodeProblem(t, rec)
+	assert.Equal(t, "VALIDATION", body["code"])
+	_, ok := body["requestId"]
+	assert.False(t, ok)
+}
+
+func TestRecoverer(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handler := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("secret failure")
+	}))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/images/1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	body := decodeProblem(t, rec)
+	assert.Equal(t, "INTERNAL", body["code"])
+	assert.Equal(t, "internal server error", body["detail"])
+}
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, req, apperror.New(apperror.Unauthenticated, "invalid token"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	body := decodeProblem(t, rec)
+	assert.Equal(t, "UNAUTHENTICATED", body["code"])
+	assert.Equal(t, "invalid token", body["detail"])
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, req, errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "internal server error", decodeProblem(t, rec)["detail"])
+}
+
+func TestWriteErrorSetsRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodPost, "/auth/login", nil), apperror.NewRateLimited(time.Now().Add(90*time.Second), "too many requests"))
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "90", rec.Header().Get("Retry-After"))
+	body := decodeProblem(t, rec)
+	assert.Equal(t, "RATE_LIMITED", body["code"])
+	assert.NotEmpty(t, body["retryAt"])
+}
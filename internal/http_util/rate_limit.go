@@ -0,0 +1,45 @@
+package http_util
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/ratelimit"
+)
+
+// the address of the client without the port. Relies on middleware.RealIP behind a proxy.
+func ClientIp(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		// RealIP sets the address without a port.
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// the headers of the IETF draft "RateLimit Header Fields for HTTP".
+func setRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	if res.Limit == 0 {
+		return
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(secondsUntil(res.ResetAt)))
+}
+
+// limits the requests of every client ip using the named policy.
+func RateLimit(limiter *ratelimit.Limiter, name string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			res := limiter.Allow(name, ClientIp(req))
+			setRateLimitHeaders(w, res)
+			if !res.Allowed {
+				WriteError(w, req, apperror.NewRateLimited(res.RetryAt, "too many requests"))
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
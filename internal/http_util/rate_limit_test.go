@@ -0,0 +1,49 @@
+package http_util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/ratelimit"
+)
+
+func TestClientIp(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:52000"
+	assert.Equal(t, "10.0.0.1", ClientIp(req))
+
+	// set by middleware.RealIP.
+	req.RemoteAddr = "2001:db8::1"
+	assert.Equal(t, "2001:db8::1", ClientIp(req))
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Policy{"login": {Limit: 2, Period: time.Minute}})
+	handler := RateLimit(limiter, "login")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = ip + ":52000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("10.0.0.1")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, http.StatusNoContent, request("10.0.0.1").Code)
+
+	rec = request("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "RATE_LIMITED", decodeProblem(t, rec)["code"])
+
+	// every ip owns a bucket.
+	assert.Equal(t, http.StatusNoContent, request("10.0.0.2").Code)
+}
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+type Sender interface {
+	// sends a plain text mail.
+	Send(to, subject, body string) error
+}
+
+// builds the raw mail including the headers.
+func buildMessage(from, to, subject, body string, date time.Time) ([]byte, error) {
+	// a line break inside of a header would allow injecting further headers.
+	for _, header := range []string{from, to, subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("invalid mail header: %q", header)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + subject + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
+
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// the auth is skipped if no username is provided.
+func NewSMTPSender(host string, port int, username, password, from string) Sender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{
+		addr: host + ":" + strconv.Itoa(port),
+		from: from,
+		auth: auth,
+	}
+}
+
+func (s *SMTPSender) Send(to, subject, body string) error {
+	msg, err := buildMessage(s.from, to, subject, body, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, msg)
+}
+
+// for local development. Logs the mails and writes them into the directory - if provided.
+type LogSender struct {
+	dir    string
+	logger *logrus.Entry
+}
+
+func NewLogSender(dir string) Sender {
+	return &LogSender{
+		dir:    dir,
+		logger: orchardclient.Logger.WithField("component", "LogSender"),
+	}
+}
+
+func (s *LogSender) Send(to, subject, body string) error {
+	now := time.Now()
+	msg, err := buildMessage("crypto-koi@localhost", to, subject, body, now)
+	if err != nil {
+		return err
+	}
+	s.logger.WithField("to", to).WithField("subject", subject).Info(body)
+	if s.dir == "" {
+		return nil
+	}
+	// the recipient is part of the file name to find the mail - the path separators are removed.
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), strings.NewReplacer("/", "_", "\\", "_").Replace(to))
+	return os.WriteFile(filepath.Join(s.dir, name), msg, 0644)
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg, err := buildMessage("from@crypto-koi.io", "to@crypto-koi.io", "Hello", "line 1\nline 2", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "From: from@crypto-koi.io\r\nTo: to@crypto-koi.io\r\nSubject: Hello\r\nDate: Sat, 01 Jan 2022 00:00:00 +0000\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\nline 1\r\nline 2", string(msg))
+}
+
+func TestBuildMessageHeaderInjection(t *testing.T) {
+	_, err := buildMessage("from@crypto-koi.io", "to@crypto-koi.io\r\nBcc: other@crypto-koi.io", "Hello", "", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestLogSenderWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, NewLogSender(dir).Send("to@crypto-koi.io", "Hello", "body"))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), "-to@crypto-koi.io.eml"))
+	b, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "Subject: Hello\r\n")
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// the last block the blockchain listener processed completely - one row per contract.
+type BlockCheckpoint struct {
+	Contract    string    `json:"contract" gorm:"type:varchar(42);primary_key"`
+	BlockNumber uint64    `json:"blockNumber" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// makes the processing of a log idempotent.
+// a log is identified by the hash of its transaction and its index inside the block.
+type ProcessedLog struct {
+	TxHash      string    `json:"txHash" gorm:"type:char(66);primary_key"`
+	LogIndex    uint      `json:"logIndex" gorm:"primary_key;autoIncrement:false"`
+	BlockNumber uint64    `json:"blockNumber" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
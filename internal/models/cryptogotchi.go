@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,8 +12,13 @@ import (
 
 type Cryptogotchi struct {
 	Base
-	Name    *string   `json:"name" gorm:"type:varchar(255);default:null"`
-	OwnerId uuid.UUID `json:"owner" gorm:"type:char(36);not null"`
+	Name *string `json:"name" gorm:"type:varchar(255);default:null"`
+	// nil while the nft belongs to a wallet which is not connected to a user - see OwnerWalletAddress.
+	OwnerId *uuid.UUID `json:"owner" gorm:"type:char(36);default:null"`
+	// the lower cased wallet which received the nft. Only set while no user connected the wallet.
+	OwnerWalletAddress *string `json:"-" gorm:"type:varchar(255);default:null;index"`
+	// the last time the owner renamed the cryptogotchi.
+	NameChangedAt *time.Time `json:"-" gorm:"type:datetime;default:null"`
 
 	IsValidNft bool `json:"isValidNft" gorm:"default:false"`
 
@@ -26,13 +32,21 @@ type Cryptogotchi struct {
 	// mapping to the event struct.
 	Events    []Event    `json:"events" gorm:"constraint:OnDelete:CASCADE;"`
 	GameStats []GameStat `json:"game_stats" gorm:"foreignKey:cryptogotchi_id;constraint:OnDelete:CASCADE;"`
-	Active    bool       `json:"released" gorm:"default:true"`
+	// oldest first - the mint is the first transfer.
+	OwnershipTransfers []OwnershipTransfer `json:"-" gorm:"constraint:OnDelete:CASCADE;"`
+	Active             bool                `json:"released" gorm:"default:true"`
 	// the timestamp of the current snapshot stored inside the database.
 	// in most cases this equals the LastFeed value. - nevertheless to build the struct a bit more
 	// future proof, we store the timestamp of the snapshot in the database as a separate column.
 	// currently this affects only the food value.
 	SnapshotValid time.Time `json:"-" gorm:"not null"`
-	Rank          int       `json:"rank" gorm:"default:-1"`
+	Rank          int       `json:"rank" gorm:"default:-1;index"`
+	// set for purchases - the nft needs to be minted before, otherwise the cryptogotchi is deleted. Nil after the mint.
+	ReservedUntil *time.Time `json:"reservedUntil" gorm:"type:datetime;default:null;index"`
+}
+
+func (c *Cryptogotchi) IsOwnedBy(userId uuid.UUID) bool {
+	return c.OwnerId != nil && *c.OwnerId == userId
 }
 
 func ToOpenseaNFT(baseUrl, tokenIdUint string, isAlive bool, name string, createdAt time.Time) (OpenseaNFT, error) {
@@ -95,6 +109,14 @@ func (c *Cryptogotchi) PredictNewDeathDate() time.Time {
 	return time.Now().Add(time.Duration(c.Food/c.FoodDrain) * time.Minute)
 }
 
+// overwrites the food value at the time - values outside of 0 and 100 are clamped.
+// a dead cryptogotchi gets revived if the food is above 0.
+func (c *Cryptogotchi) SetFood(food float64, now time.Time) {
+	c.Food = math.Max(0, math.Min(100, food))
+	c.SnapshotValid = now
+	c.PredictedDeathDate = now.Add(time.Duration(c.Food / c.FoodDrain * float64(time.Minute)))
+}
+
 func (c *Cryptogotchi) IsAlive() bool {
 	return c.PredictedDeathDate.After(time.Now())
 }
@@ -128,5 +150,6 @@ func (c *Cryptogotchi) GetNextFeedingTime() time.Time {
 }
 
 func NewCryptogotchi(user *User) Cryptogotchi {
-	return Cryptogotchi{OwnerId: user.Id}
+	ownerId := user.Id
+	return Cryptogotchi{OwnerId: &ownerId}
 }
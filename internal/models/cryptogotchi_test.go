@@ -80,3 +80,30 @@ func TestNextFeedingTime(t *testing.T) {
 	// feeding time should be now + 10 minutes (or whatever the config value is set to)
 	assert.Equal(t, time.Now().Add(config.TIME_BETWEEN_FEEDINGS).Unix(), nextFeeding.Unix())
 }
+
+func TestSetFoodRevivesCryptogotchi(t *testing.T) {
+	now := time.Now()
+	cryptogotchi := models.Cryptogotchi{
+		Food:               0,
+		FoodDrain:          1,
+		PredictedDeathDate: now.Add(-10 * time.Minute),
+	}
+
+	cryptogotchi.SetFood(50, now)
+	assert.Equal(t, float64(50), cryptogotchi.Food)
+	assert.Equal(t, now, cryptogotchi.SnapshotValid)
+	assert.Equal(t, now.Add(50*time.Minute), cryptogotchi.PredictedDeathDate)
+	assert.True(t, cryptogotchi.IsAlive())
+}
+
+func TestSetFoodClampsValue(t *testing.T) {
+	now := time.Now()
+	cryptogotchi := models.Cryptogotchi{FoodDrain: 1}
+
+	cryptogotchi.SetFood(500, now)
+	assert.Equal(t, float64(100), cryptogotchi.Food)
+
+	cryptogotchi.SetFood(-5, now)
+	assert.Equal(t, float64(0), cryptogotchi.Food)
+	assert.False(t, cryptogotchi.IsAlive())
+}
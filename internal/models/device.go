@@ -0,0 +1,11 @@
+package models
+
+import "github.com/google/uuid"
+
+// a device the user can login with using its device id.
+// a user owns multiple devices - see the pairing codes.
+type Device struct {
+	Base
+	UserId   uuid.UUID `json:"userId" gorm:"type:char(36);not null;index"`
+	DeviceId string    `json:"-" gorm:"type:varchar(255);not null;unique"`
+}
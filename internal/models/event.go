@@ -31,7 +31,7 @@ func IsEventType(stringToCheck string) (EventType, error) {
 type Event struct {
 	Base
 	Type           EventType `json:"type" gorm:"type:varchar(255)"`
-	CryptogotchiId uuid.UUID `json:"cryptogotchiId" gorm:"type:char(36)"`
+	CryptogotchiId uuid.UUID `json:"cryptogotchiId" gorm:"type:char(36);index"`
 	// the value to increment.
 	// a regular feed event will contain the value 10
 	Payload float64
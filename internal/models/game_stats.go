@@ -31,6 +31,30 @@ type GameStat struct {
 	Type           GameType   `json:"type" gorm:"type:varchar(255)"`
 	Score          *float64   `json:"score" gorm:"default:null"`
 	GameFinished   *time.Time `json:"gameFinished" gorm:"type:datetime;default:null"`
+	// set if the score looks like cheating - see FlagReason.
+	FlaggedReason *string `json:"flaggedReason" gorm:"type:varchar(255);default:null;index"`
+}
+
+// the score is added to the food of the cryptogotchi - the food is capped at 100.
+const MAX_GAME_SCORE = 100.
+
+// a game needs to be played at least this long to achieve a score.
+const MIN_GAME_DURATION = 5 * time.Second
+
+// returns why the score is implausible - or nil if it looks fine.
+func (gameStat *GameStat) FlagReason(score float64, finished time.Time) *string {
+	var reason string
+	switch {
+	case score < 0:
+		reason = "negative score"
+	case score > MAX_GAME_SCORE:
+		reason = fmt.Sprintf("score above the maximum of %.0f", MAX_GAME_SCORE)
+	case score > 0 && finished.Sub(gameStat.CreatedAt) < MIN_GAME_DURATION:
+		reason = fmt.Sprintf("finished after %s", finished.Sub(gameStat.CreatedAt).Round(time.Millisecond))
+	default:
+		return nil
+	}
+	return &reason
 }
 
 // To event returns game won events
@@ -0,0 +1,22 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func TestFlagReason(t *testing.T) {
+	started := time.Now()
+	gameStat := models.GameStat{Base: models.Base{CreatedAt: started}}
+
+	assert.Nil(t, gameStat.FlagReason(10, started.Add(time.Minute)))
+	// losing quickly is fine.
+	assert.Nil(t, gameStat.FlagReason(0, started.Add(time.Second)))
+
+	assert.Equal(t, "negative score", *gameStat.FlagReason(-1, started.Add(time.Minute)))
+	assert.Equal(t, "score above the maximum of 100", *gameStat.FlagReason(101, started.Add(time.Minute)))
+	assert.Equal(t, "finished after 1s", *gameStat.FlagReason(10, started.Add(time.Second)))
+}
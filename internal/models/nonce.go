@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NoncePurpose string
+
+const (
+	// Sign-In with Ethereum
+	LoginNoncePurpose NoncePurpose = "login"
+	// proof of the ownership of a wallet - bound to a user and a wallet address.
+	WalletNoncePurpose NoncePurpose = "wallet"
+	// the one-time links sent by mail - bound to a user.
+	EmailVerificationNoncePurpose NoncePurpose = "email_verification"
+	MagicLinkNoncePurpose         NoncePurpose = "magic_link"
+	RecoveryNoncePurpose          NoncePurpose = "recovery"
+	// links another device to the user - the value is a short code the user types in.
+	PairingNoncePurpose NoncePurpose = "pairing"
+)
+
+// a single use value which needs to be part of a signed message.
+// prevents replaying a signature.
+type Nonce struct {
+	Base
+	Value     string       `json:"value" gorm:"type:varchar(255);not null;unique"`
+	Purpose   NoncePurpose `json:"purpose" gorm:"type:varchar(255);not null"`
+	ExpiresAt time.Time    `json:"expiresAt" gorm:"type:datetime;not null;index"`
+	// only set for wallet nonces and mail links.
+	UserId        *uuid.UUID `json:"userId" gorm:"type:char(36);index"`
+	WalletAddress *string    `json:"-" gorm:"type:varchar(255)"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/google/uuid"
+
+// a transfer of the nft - the mint included.
+type OwnershipTransfer struct {
+	Base
+	CryptogotchiId uuid.UUID `json:"cryptogotchiId" gorm:"type:char(36);not null;index"`
+	// lower cased - the zero address for the mint.
+	FromAddress string `json:"fromAddress" gorm:"type:varchar(255);not null"`
+	ToAddress   string `json:"toAddress" gorm:"type:varchar(255);not null"`
+	// the transfer log - the same log is never stored twice.
+	TxHash      string `json:"txHash" gorm:"type:char(66);not null;uniqueIndex:idx_ownership_transfers_log"`
+	LogIndex    uint   `json:"logIndex" gorm:"not null;uniqueIndex:idx_ownership_transfers_log"`
+	BlockNumber uint64 `json:"blockNumber" gorm:"not null"`
+}
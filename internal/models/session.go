@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// a login of a user on a single device.
+// every refresh rotates the refresh token of the session.
+type Session struct {
+	Base
+	UserId uuid.UUID `json:"userId" gorm:"type:char(36);not null;index"`
+	// the user agent of the device which created the session.
+	Device     string    `json:"device" gorm:"type:varchar(255)"`
+	LastUsedAt time.Time `json:"lastUsedAt" gorm:"type:datetime;not null"`
+	// the session expires if it is not refreshed until then.
+	ExpiresAt     time.Time      `json:"expiresAt" gorm:"type:datetime;not null;index"`
+	RevokedAt     *time.Time     `json:"revokedAt" gorm:"type:datetime;default:null"`
+	RefreshTokens []RefreshToken `json:"-" gorm:"foreignKey:SessionId;references:Id;constraint:OnDelete:CASCADE;"`
+}
+
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// only the hash of a refresh token is stored.
+// used tokens are kept until the session expires to detect a reuse.
+type RefreshToken struct {
+	Base
+	SessionId uuid.UUID `gorm:"type:char(36);not null;index"`
+	Session   Session   `gorm:"foreignKey:SessionId"`
+	Hash      string    `gorm:"type:varchar(255);not null;unique"`
+	// set as soon as the token got rotated.
+	UsedAt *time.Time `gorm:"type:datetime;default:null"`
+}
@@ -1,16 +1,63 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
+type Role string
+
+const (
+	UserRole  Role = "USER"
+	AdminRole Role = "ADMIN"
+)
+
+func IsRole(stringToCheck string) (Role, error) {
+	switch Role(stringToCheck) {
+	case UserRole:
+		return UserRole, nil
+	case AdminRole:
+		return AdminRole, nil
+	default:
+		return "", fmt.Errorf("unknown role: %s", stringToCheck)
+	}
+}
+
 // the owner of a cryptogotchi.
 // there is a variety of possible authentication methods.
 // for example, a user can be authenticated by their wallet and the device id
 type User struct {
 	Base
 	Cryptogotchies []Cryptogotchi `json:"cryptogotchies" gorm:"foreignKey:OwnerId;references:Id;constraint:OnDelete:CASCADE;"`
+	Sessions       []Session      `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE;"`
+	Devices        []Device       `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE;"`
 	Email          string         `json:"email" gorm:"type:varchar(255);not null;unique"`
 	Name           string         `json:"name" gorm:"type:varchar(255);not null"`
+	// the last time the user changed the name.
+	NameChangedAt *time.Time `json:"-" gorm:"type:datetime;default:null"`
+	// set as soon as the user opened a link sent to the email address.
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt" gorm:"type:datetime;default:null"`
 	// never return the wallet address of the user.
-	WalletAddress         *string `json:"-" gorm:"type:varchar(255);unique"`
-	DeviceId              *string `json:"-" gorm:"type:varchar(255);unique"`
+	WalletAddress *string `json:"-" gorm:"type:varchar(255);unique"`
+	// set as soon as the user proved the ownership of the wallet - using connectWallet or sign-in with ethereum.
+	// only a proven wallet receives the transferred nfts.
+	WalletVerifiedAt *time.Time `json:"-" gorm:"type:datetime;default:null"`
+	// Deprecated: replaced by the devices. Only used to migrate the device ids into the devices table.
+	DeviceId *string `json:"-" gorm:"type:varchar(255);unique"`
+	// Deprecated: replaced by the sessions. Only used to migrate logins which happened before the sessions existed.
 	RefreshToken          string  `json:"-" gorm:"type:varchar(255);not null;unique"`
 	PushNotificationToken *string `json:"-" gorm:"type:varchar(255)"`
+	Role                  Role    `json:"role" gorm:"type:varchar(255);not null;default:USER"`
+	// banned users can neither login nor use their sessions.
+	BannedAt  *time.Time `json:"bannedAt" gorm:"type:datetime;default:null"`
+	BanReason *string    `json:"banReason" gorm:"type:varchar(255);default:null"`
+}
+
+func (u *User) HasRole(role Role) bool {
+	// admins are allowed to do everything.
+	return u.Role == role || u.Role == AdminRole
+}
+
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
 }
@@ -0,0 +1,28 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func TestHasRole(t *testing.T) {
+	user := models.User{Role: models.UserRole}
+	assert.True(t, user.HasRole(models.UserRole))
+	assert.False(t, user.HasRole(models.AdminRole))
+
+	// admins are allowed to do everything.
+	admin := models.User{Role: models.AdminRole}
+	assert.True(t, admin.HasRole(models.UserRole))
+	assert.True(t, admin.HasRole(models.AdminRole))
+}
+
+func TestIsRole(t *testing.T) {
+	role, err := models.IsRole("ADMIN")
+	assert.Nil(t, err)
+	assert.Equal(t, models.AdminRole, role)
+
+	_, err = models.IsRole("admin")
+	assert.NotNil(t, err)
+}
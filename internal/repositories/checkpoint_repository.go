@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CheckpointRepository interface {
+	// returns gorm.ErrRecordNotFound if no block of the contract was processed yet.
+	GetCheckpoint(contract string) (models.BlockCheckpoint, error)
+	SaveCheckpoint(checkpoint *models.BlockCheckpoint) error
+	IsProcessed(txHash string, logIndex uint) (bool, error)
+	// marking a log twice is no error.
+	MarkProcessed(log *models.ProcessedLog) error
+}
+
+type GormCheckpointRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCheckpointRepository(db *gorm.DB) CheckpointRepository {
+	return &GormCheckpointRepository{db: db}
+}
+
+func (rep *GormCheckpointRepository) GetCheckpoint(contract string) (models.BlockCheckpoint, error) {
+	var checkpoint models.BlockCheckpoint
+	err := rep.db.Where("contract = ?", contract).First(&checkpoint).Error
+	return checkpoint, err
+}
+
+func (rep *GormCheckpointRepository) SaveCheckpoint(checkpoint *models.BlockCheckpoint) error {
+	return rep.db.Save(checkpoint).Error
+}
+
+func (rep *GormCheckpointRepository) IsProcessed(txHash string, logIndex uint) (bool, error) {
+	var count int64
+	err := rep.db.Model(&models.ProcessedLog{}).Where("tx_hash = ? AND log_index = ?", txHash, logIndex).Count(&count).Error
+	return count > 0, err
+}
+
+func (rep *GormCheckpointRepository) MarkProcessed(log *models.ProcessedLog) error {
+	return rep.db.Clauses(clause.OnConflict{DoNothing: true}).Create(log).Error
+}
@@ -1,24 +1,47 @@
 package repositories
 
 import (
+	"errors"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/google/uuid"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// returned if the owner holds the max amount of pending reservations.
+var ErrReservationLimit = errors.New("too many pending reservations")
+
 type CryptogotchiRepository interface {
 	Repository[models.Cryptogotchi]
+	GetByIds(ids []string) ([]models.Cryptogotchi, error)
+	// returns the cryptogotchies of all owners - including the inactive ones.
+	GetByOwnerIds(ownerIds []string) ([]models.Cryptogotchi, error)
 	GetCryptogotchiByUint256(tokenId string) (models.Cryptogotchi, error)
 	GetCryptogotchiesByUserId(userId string) ([]models.Cryptogotchi, error)
 	GetLeaderboard() ([]models.Cryptogotchi, error)
 	GetCachedLeaderboard(offset, limit int) ([]models.Cryptogotchi, error)
+	// the keyset paginated version of GetCachedLeaderboard.
+	GetLeaderboardAfter(after *RankKey, limit int) ([]models.Cryptogotchi, error)
 	GetCryptogotchies(query *input.SearchQuery, offset, limit int) ([]models.Cryptogotchi, error)
+	GetCryptogotchiesAfter(query *input.SearchQuery, after *RankKey, limit int) ([]models.Cryptogotchi, error)
 	Create(m *models.Cryptogotchi) error
 	GetCryptogotchiesWithPredictedDeathDateBetween(start, end time.Time) ([]models.Cryptogotchi, error)
+	// the reservations of the owner which did not expire yet - the first to expire first.
+	GetPendingReservations(ownerId string, now time.Time) ([]models.Cryptogotchi, error)
+	// creates the inactive cryptogotchi unless its owner holds maxPending reservations already.
+	// returns ErrReservationLimit together with the pending reservations in that case.
+	CreateReservation(crypt *models.Cryptogotchi, maxPending int, now time.Time) ([]models.Cryptogotchi, error)
+	// deletes the cryptogotchies whose reservation expired before the nft was minted.
+	DeleteExpiredReservations(now time.Time) (int64, error)
+	// compares case insensitive. The cryptogotchi with the excluded id is ignored.
+	IsNameTaken(name string, excludeId uuid.UUID) (bool, error)
+	// the cryptogotchies received by the wallet before a user connected it are assigned to the user.
+	AssignWalletCryptogotchies(walletAddress string, ownerId uuid.UUID) (int64, error)
 }
 
 type GormCryptogotchiRepository struct {
@@ -43,6 +66,16 @@ func (rep *GormCryptogotchiRepository) GetCryptogotchies(query *input.SearchQuer
 	return cryptogotchies, err
 }
 
+func (rep *GormCryptogotchiRepository) GetCryptogotchiesAfter(query *input.SearchQuery, after *RankKey, limit int) ([]models.Cryptogotchi, error) {
+	var cryptogotchies []models.Cryptogotchi
+	q := rep.db.Scopes(onlyActive, afterRank(after)).Limit(limit)
+	if query != nil {
+		q.Where("name LIKE ?", "%"+query.Name+"%")
+	}
+	err := q.Find(&cryptogotchies).Error
+	return cryptogotchies, err
+}
+
 func (rep *GormCryptogotchiRepository) GetCryptogotchiByUint256(tokenId string) (models.Cryptogotchi, error) {
 	bigInt := math.MustParseBig256(tokenId)
 
@@ -54,6 +87,12 @@ func (rep *GormCryptogotchiRepository) GetCryptogotchiByUint256(tokenId string)
 	return rep.GetById(id.String())
 }
 
+func (rep *GormCryptogotchiRepository) IsNameTaken(name string, excludeId uuid.UUID) (bool, error) {
+	var count int64
+	err := rep.db.Model(&models.Cryptogotchi{}).Where("LOWER(name) = LOWER(?) AND id != ?", name, excludeId).Count(&count).Error
+	return count > 0, err
+}
+
 func (rep *GormCryptogotchiRepository) Save(m *models.Cryptogotchi) error {
 	return rep.db.Save(m).Error
 }
@@ -74,6 +113,26 @@ func (rep *GormCryptogotchiRepository) GetById(id string) (models.Cryptogotchi,
 	return cryptogotchi, err
 }
 
+func (rep *GormCryptogotchiRepository) GetByIds(ids []string) ([]models.Cryptogotchi, error) {
+	var cryptogotchies []models.Cryptogotchi
+	err := rep.db.Where("id IN ?", ids).Find(&cryptogotchies).Error
+	return cryptogotchies, err
+}
+
+func (rep *GormCryptogotchiRepository) GetByOwnerIds(ownerIds []string) ([]models.Cryptogotchi, error) {
+	var cryptogotchies []models.Cryptogotchi
+	err := rep.db.Where("owner_id IN ?", ownerIds).Order("created_at ASC").Find(&cryptogotchies).Error
+	return cryptogotchies, err
+}
+
+func (rep *GormCryptogotchiRepository) AssignWalletCryptogotchies(walletAddress string, ownerId uuid.UUID) (int64, error) {
+	res := rep.db.Model(&models.Cryptogotchi{}).Where("owner_wallet_address = ?", walletAddress).Updates(map[string]interface{}{
+		"owner_id":             ownerId.String(),
+		"owner_wallet_address": nil,
+	})
+	return res.RowsAffected, res.Error
+}
+
 func (rep *GormCryptogotchiRepository) GetCryptogotchiesWithPredictedDeathDateBetween(start, end time.Time) ([]models.Cryptogotchi, error) {
 	var cryptogotchies []models.Cryptogotchi
 	err := rep.db.Where("predicted_death_date >= ? AND predicted_death_date < ?", start, end).Find(&cryptogotchies).Error
@@ -91,3 +150,43 @@ func (rep *GormCryptogotchiRepository) GetCachedLeaderboard(offset, limit int) (
 	err := rep.db.Where("predicted_death_date > ? AND `rank` > -1", time.Now()).Order("`rank` asc").Offset(offset).Limit(limit).Find(&cryptogotchies).Error
 	return cryptogotchies, err
 }
+
+func (rep *GormCryptogotchiRepository) GetLeaderboardAfter(after *RankKey, limit int) ([]models.Cryptogotchi, error) {
+	var cryptogotchies []models.Cryptogotchi
+	err := rep.db.Scopes(afterRank(after)).Where("predicted_death_date > ? AND `rank` > -1", time.Now()).Limit(limit).Find(&cryptogotchies).Error
+	return cryptogotchies, err
+}
+
+func (rep *GormCryptogotchiRepository) GetPendingReservations(ownerId string, now time.Time) ([]models.Cryptogotchi, error) {
+	var cryptogotchies []models.Cryptogotchi
+	err := rep.db.Where("owner_id = ? AND active = ? AND reserved_until > ?", ownerId, false, now).Order("reserved_until ASC").Find(&cryptogotchies).Error
+	return cryptogotchies, err
+}
+
+func (rep *GormCryptogotchiRepository) CreateReservation(crypt *models.Cryptogotchi, maxPending int, now time.Time) ([]models.Cryptogotchi, error) {
+	var pending []models.Cryptogotchi
+	err := rep.db.Transaction(func(tx *gorm.DB) error {
+		// the lock on the owner serializes parallel purchases - the count stays valid until the insert.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", crypt.OwnerId).First(&models.User{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ? AND active = ? AND reserved_until > ?", crypt.OwnerId, false, now).Order("reserved_until ASC").Find(&pending).Error; err != nil {
+			return err
+		}
+		if len(pending) >= maxPending {
+			return ErrReservationLimit
+		}
+		if err := tx.Create(crypt).Error; err != nil {
+			return err
+		}
+		// gorm replaces the zero value with the default of the column.
+		crypt.Active = false
+		return tx.Model(crypt).Update("active", false).Error
+	})
+	return pending, err
+}
+
+func (rep *GormCryptogotchiRepository) DeleteExpiredReservations(now time.Time) (int64, error) {
+	res := rep.db.Where("active = ? AND reserved_until <= ?", false, now).Delete(&models.Cryptogotchi{})
+	return res.RowsAffected, res.Error
+}
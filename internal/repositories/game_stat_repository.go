@@ -8,6 +8,8 @@ import (
 type GameStatRepository interface {
 	Repository[models.GameStat]
 	FindAllByUserId(userId string) ([]models.GameStat, error)
+	// returns the games with an implausible score - the newest first.
+	GetFlagged(offset, limit int) ([]models.GameStat, error)
 }
 
 type GormGameStatRepository struct {
@@ -33,3 +35,9 @@ func (rep *GormGameStatRepository) GetById(id string) (models.GameStat, error) {
 	err := rep.db.Where("id = ?", id).Find(&gameStat).Error
 	return gameStat, err
 }
+
+func (rep *GormGameStatRepository) GetFlagged(offset, limit int) ([]models.GameStat, error) {
+	var gameStats []models.GameStat
+	err := rep.db.Where("flagged_reason IS NOT NULL").Order("created_at desc").Offset(offset).Limit(limit).Find(&gameStats).Error
+	return gameStats, err
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the sort key of the last row of a page - the next page starts after it.
+// the id breaks ties, so no row is skipped or returned twice while the rows are updated.
+type RankKey struct {
+	Rank int       `json:"rank"`
+	Id   uuid.UUID `json:"id"`
+}
+
+type TimeKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Id        uuid.UUID `json:"id"`
+}
+
+// orders by (rank, id) ascending and starts after the key. A nil key starts at the first row.
+func afterRank(key *RankKey) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Order("`rank` asc").Order("id asc")
+		if key == nil {
+			return db
+		}
+		// expanded instead of a row comparison - mysql does not use the index for (a, b) > (?, ?).
+		return db.Where("(`rank` > ? OR (`rank` = ? AND id > ?))", key.Rank, key.Rank, key.Id)
+	}
+}
+
+// orders by (created_at, id) and starts after the key. A nil key starts at the first row.
+func afterTime(key *TimeKey, desc bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if desc {
+			db = db.Order("created_at desc").Order("id desc")
+		} else {
+			db = db.Order("created_at asc").Order("id asc")
+		}
+		if key == nil {
+			return db
+		}
+		if desc {
+			return db.Where("(created_at < ? OR (created_at = ? AND id < ?))", key.CreatedAt, key.CreatedAt, key.Id)
+		}
+		return db.Where("(created_at > ? OR (created_at = ? AND id > ?))", key.CreatedAt, key.CreatedAt, key.Id)
+	}
+}
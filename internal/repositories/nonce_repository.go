@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type NonceRepository interface {
+	Save(nonce *models.Nonce) error
+	// deletes the nonce and returns it. Returns gorm.ErrRecordNotFound if the nonce does not exist,
+	// is expired, was already used or belongs to another user.
+	// pass nil as user id for nonces which are not bound to a user.
+	Consume(value string, purpose models.NoncePurpose, userId *uuid.UUID) (models.Nonce, error)
+	// same as Consume - but the nonce might belong to any user. The caller gets the user from the returned nonce.
+	ConsumeForAnyUser(value string, purpose models.NoncePurpose) (models.Nonce, error)
+	DeleteExpired() error
+}
+
+type GormNonceRepository struct {
+	db *gorm.DB
+}
+
+func NewGormNonceRepository(db *gorm.DB) NonceRepository {
+	return &GormNonceRepository{db: db}
+}
+
+func (rep *GormNonceRepository) Save(nonce *models.Nonce) error {
+	return rep.db.Save(nonce).Error
+}
+
+func (rep *GormNonceRepository) Consume(value string, purpose models.NoncePurpose, userId *uuid.UUID) (models.Nonce, error) {
+	q := rep.db.Where("value = ? AND purpose = ? AND expires_at > ?", value, purpose, time.Now())
+	if userId != nil {
+		q = q.Where("user_id = ?", userId.String())
+	} else {
+		q = q.Where("user_id IS NULL")
+	}
+	return rep.consume(q)
+}
+
+func (rep *GormNonceRepository) ConsumeForAnyUser(value string, purpose models.NoncePurpose) (models.Nonce, error) {
+	return rep.consume(rep.db.Where("value = ? AND purpose = ? AND expires_at > ?", value, purpose, time.Now()))
+}
+
+func (rep *GormNonceRepository) consume(q *gorm.DB) (models.Nonce, error) {
+	var nonce models.Nonce
+	err := q.First(&nonce).Error
+	if err != nil {
+		return nonce, err
+	}
+	// the delete decides - two concurrent requests might have found the nonce.
+	res := rep.db.Delete(&nonce)
+	if res.Error != nil {
+		return nonce, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nonce, gorm.ErrRecordNotFound
+	}
+	return nonce, nil
+}
+
+func (rep *GormNonceRepository) DeleteExpired() error {
+	return rep.db.Where("expires_at <= ?", time.Now()).Delete(&models.Nonce{}).Error
+}
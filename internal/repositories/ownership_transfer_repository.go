@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OwnershipTransferRepository interface {
+	// storing the transfer of the same log twice is no error.
+	Create(transfer *models.OwnershipTransfer) error
+	// oldest first.
+	GetByCryptogotchiIds(cryptogotchiIds []string) ([]models.OwnershipTransfer, error)
+}
+
+type GormOwnershipTransferRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOwnershipTransferRepository(db *gorm.DB) OwnershipTransferRepository {
+	return &GormOwnershipTransferRepository{db: db}
+}
+
+func (rep *GormOwnershipTransferRepository) Create(transfer *models.OwnershipTransfer) error {
+	return rep.db.Clauses(clause.OnConflict{DoNothing: true}).Create(transfer).Error
+}
+
+func (rep *GormOwnershipTransferRepository) GetByCryptogotchiIds(cryptogotchiIds []string) ([]models.OwnershipTransfer, error) {
+	var transfers []models.OwnershipTransfer
+	err := rep.db.Where("cryptogotchi_id IN ?", cryptogotchiIds).Order("block_number ASC, log_index ASC").Find(&transfers).Error
+	return transfers, err
+}
@@ -8,6 +8,8 @@ import (
 type EventRepository interface {
 	Save(record *models.Event) error
 	GetPaginated(cryptogotchiId string, offset int, amount int) ([]models.Event, error)
+	// the newest events first - like GetPaginated.
+	GetPageAfter(cryptogotchiId string, after *TimeKey, amount int) ([]models.Event, error)
 }
 
 type GormEventRepository struct {
@@ -27,3 +29,9 @@ func (rep *GormEventRepository) GetPaginated(cryptogotchiId string, offset int,
 	err := rep.db.Where("cryptogotchi_id = ?", cryptogotchiId).Order("created_at desc").Offset(offset).Limit(amount).Find(&events).Error
 	return events, err
 }
+
+func (rep *GormEventRepository) GetPageAfter(cryptogotchiId string, after *TimeKey, amount int) ([]models.Event, error) {
+	var events []models.Event
+	err := rep.db.Scopes(afterTime(after, true)).Where("cryptogotchi_id = ?", cryptogotchiId).Limit(amount).Find(&events).Error
+	return events, err
+}
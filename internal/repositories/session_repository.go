@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// returned if a refresh token gets rotated a second time.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+type SessionRepository interface {
+	Repository[models.Session]
+	// creates the session together with its first refresh token.
+	Create(session *models.Session, refreshToken *models.RefreshToken) error
+	// returns the refresh token including its session.
+	GetRefreshTokenByHash(hash string) (models.RefreshToken, error)
+	// marks the old token as used and stores the new one.
+	// returns ErrRefreshTokenReused if the old token was already used.
+	Rotate(session *models.Session, old *models.RefreshToken, new *models.RefreshToken) error
+	GetActiveByUserId(userId string) ([]models.Session, error)
+	Revoke(session *models.Session) error
+	// revokes every active session of the user.
+	RevokeByUserId(userId string) error
+	DeleteExpired() error
+}
+
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSessionRepository(db *gorm.DB) SessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+func (rep *GormSessionRepository) GetById(id string) (models.Session, error) {
+	var session models.Session
+	err := rep.db.Where("id = ?", id).First(&session).Error
+	return session, err
+}
+
+func (rep *GormSessionRepository) Save(session *models.Session) error {
+	return rep.db.Save(session).Error
+}
+
+func (rep *GormSessionRepository) Create(session *models.Session, refreshToken *models.RefreshToken) error {
+	return rep.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		refreshToken.SessionId = session.Id
+		return tx.Omit("Session").Create(refreshToken).Error
+	})
+}
+
+func (rep *GormSessionRepository) GetRefreshTokenByHash(hash string) (models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+	err := rep.db.Preload("Session").Where("hash = ?", hash).First(&refreshToken).Error
+	return refreshToken, err
+}
+
+func (rep *GormSessionRepository) Rotate(session *models.Session, old *models.RefreshToken, new *models.RefreshToken) error {
+	return rep.db.Transaction(func(tx *gorm.DB) error {
+		// the condition on used_at decides if two requests rotate the same token concurrently.
+		res := tx.Model(&models.RefreshToken{}).Where("id = ? AND used_at IS NULL", old.Id).Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+		new.SessionId = session.Id
+		if err := tx.Omit("Session").Create(new).Error; err != nil {
+			return err
+		}
+		return tx.Model(session).Updates(map[string]interface{}{
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		}).Error
+	})
+}
+
+func (rep *GormSessionRepository) GetActiveByUserId(userId string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := rep.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).Order("last_used_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+func (rep *GormSessionRepository) Revoke(session *models.Session) error {
+	now := time.Now()
+	session.RevokedAt = &now
+	return rep.db.Model(session).Update("revoked_at", now).Error
+}
+
+func (rep *GormSessionRepository) RevokeByUserId(userId string) error {
+	return rep.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userId).Update("revoked_at", time.Now()).Error
+}
+
+// the refresh tokens are deleted by the database.
+func (rep *GormSessionRepository) DeleteExpired() error {
+	return rep.db.Where("expires_at <= ?", time.Now()).Delete(&models.Session{}).Error
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"strings"
 
+	"github.com/google/uuid"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gorm.io/gorm"
@@ -10,11 +11,23 @@ import (
 
 type UserRepository interface {
 	Repository[models.User]
+	GetByIds(ids []string) ([]models.User, error)
+	// returns the user owning the device.
 	GetByDeviceId(deviceId string) (models.User, error)
+	GetDevices(userId string) ([]models.Device, error)
+	AddDevice(user *models.User, deviceId string) error
+	// removes all devices of the user and adds the provided one.
+	ReplaceDevices(user *models.User, deviceId string) error
 	GetByWalletAddress(address string) (models.User, error)
+	// only returns the user if the ownership of the wallet got proven.
+	GetByVerifiedWalletAddress(address string) (models.User, error)
 	GetByRefreshToken(refreshToken string) (models.User, error)
 	GetUsers(query *input.SearchQuery, offset, limit int) ([]models.User, error)
+	// ordered by the registration.
+	GetUsersAfter(query *input.SearchQuery, after *TimeKey, limit int) ([]models.User, error)
 	GetByEmail(email string) (models.User, error)
+	// compares case insensitive. The user with the excluded id is ignored.
+	IsNameTaken(name string, excludeId uuid.UUID) (bool, error)
 	Delete(*models.User) error
 }
 
@@ -37,10 +50,26 @@ func (rep *GormUserRepository) GetUsers(query *input.SearchQuery, offset, limit
 
 }
 
+func (rep *GormUserRepository) GetUsersAfter(query *input.SearchQuery, after *TimeKey, limit int) ([]models.User, error) {
+	var users []models.User
+	q := rep.db.Scopes(afterTime(after, false)).Limit(limit)
+	if query != nil {
+		q.Where("name LIKE ?", "%"+query.Name+"%")
+	}
+	err := q.Find(&users).Error
+	return users, err
+}
+
 func (rep *GormUserRepository) Delete(u *models.User) error {
 	return rep.db.Delete(u).Error
 }
 
+func (rep *GormUserRepository) IsNameTaken(name string, excludeId uuid.UUID) (bool, error) {
+	var count int64
+	err := rep.db.Model(&models.User{}).Where("LOWER(name) = LOWER(?) AND id != ?", name, excludeId).Count(&count).Error
+	return count > 0, err
+}
+
 func (rep *GormUserRepository) GetByEmail(email string) (models.User, error) {
 	var user models.User
 	return user, rep.db.Where("email = ?", email).First(&user).Error
@@ -48,22 +77,53 @@ func (rep *GormUserRepository) GetByEmail(email string) (models.User, error) {
 
 func (rep *GormUserRepository) GetByDeviceId(deviceId string) (models.User, error) {
 	var user models.User
-	err := rep.db.Preload("Cryptogotchies").Preload("Cryptogotchies.Events", orderEventsASC).Preload("Cryptogotchies.GameStats").Where("device_id = ?", deviceId).First(&user).Error
+	err := rep.db.Joins("JOIN devices ON devices.user_id = users.id").Where("devices.device_id = ?", deviceId).First(&user).Error
 	return user, err
 }
 
+func (rep *GormUserRepository) GetDevices(userId string) ([]models.Device, error) {
+	var devices []models.Device
+	err := rep.db.Where("user_id = ?", userId).Order("created_at asc").Find(&devices).Error
+	return devices, err
+}
+
+func (rep *GormUserRepository) AddDevice(user *models.User, deviceId string) error {
+	return rep.db.Create(&models.Device{UserId: user.Id, DeviceId: deviceId}).Error
+}
+
+func (rep *GormUserRepository) ReplaceDevices(user *models.User, deviceId string) error {
+	return rep.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.Id).Delete(&models.Device{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Device{UserId: user.Id, DeviceId: deviceId}).Error
+	})
+}
+
 func (rep *GormUserRepository) GetByWalletAddress(address string) (models.User, error) {
 	var user models.User
-	err := rep.db.Preload("Cryptogotchies").Preload("Cryptogotchies.Events", orderEventsASC).Preload("Cryptogotchies.GameStats").Where("wallet_address = ?", strings.ToLower(address)).First(&user).Error
+	err := rep.db.Where("wallet_address = ?", strings.ToLower(address)).First(&user).Error
+	return user, err
+}
+
+func (rep *GormUserRepository) GetByVerifiedWalletAddress(address string) (models.User, error) {
+	var user models.User
+	err := rep.db.Where("wallet_address = ? AND wallet_verified_at IS NOT NULL", strings.ToLower(address)).First(&user).Error
 	return user, err
 }
 
 func (rep *GormUserRepository) GetById(id string) (models.User, error) {
 	var user models.User
-	err := rep.db.Preload("Cryptogotchies").Preload("Cryptogotchies.Events", orderEventsASC).Preload("Cryptogotchies.GameStats").Where("id = ?", id).First(&user).Error
+	err := rep.db.Where("id = ?", id).First(&user).Error
 	return user, err
 }
 
+func (rep *GormUserRepository) GetByIds(ids []string) ([]models.User, error) {
+	var users []models.User
+	err := rep.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 func (rep *GormUserRepository) Save(user *models.User) error {
 	return rep.db.Save(user).Error
 }
@@ -1,55 +1,71 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"math/big"
 	"strconv"
 
 	"image/png"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 
 	"os"
 	"strings"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 
 	sentryhttp "github.com/getsentry/sentry-go/http"
 	"github.com/go-chi/chi/v5"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/generated"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/controller"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/generator"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/mail"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/service"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/leader"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/pubsub"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/ratelimit"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
 	"gorm.io/gorm"
 )
 
 type GraphqlServer struct {
-	db                *gorm.DB
-	tokenSvc          service.TokenSvc
-	userSvc           service.UserSvc
-	cryptogotchiSvc   service.CryptogotchiSvc
-	koiGenerator      generator.Generator
-	dragonGenerator   generator.Generator
-	leaderElection    leader.LeaderElection
-	cryptokoiListener *cryptokoi.CryptoKoiEventListener
-	logger            *logrus.Entry
+	db              *gorm.DB
+	tokenSvc        service.TokenSvc
+	userSvc         service.UserSvc
+	authSvc         service.AuthSvc
+	cryptogotchiSvc service.CryptogotchiSvc
+	koiGenerator    generator.Generator
+	dragonGenerator generator.Generator
+	koiPreloader    *generator.ReloadingPreloader
+	dragonPreloader *generator.ReloadingPreloader
+	leaderElection  leader.LeaderElection
+	logger          *logrus.Entry
 }
 
 type responseData struct {
@@ -72,6 +88,16 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.responseData.status = statusCode       // capture status code
 }
 
+// websocket connections take over the underlying connection.
+func (r *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	r.responseData.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
 func loggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		now := time.Now()
@@ -87,6 +113,73 @@ func loggerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// resolves the user of the access token. Used by the auth middleware and the websocket initialization.
+// returns the id of the session as well.
+func (s *GraphqlServer) authenticate(token string) (*models.User, string, error) {
+	// parse the token from the request
+	// remove the bearer prefix
+	claims, err := s.tokenSvc.ParseToken(strings.Replace(strings.Replace(token, "Bearer ", "", -1), "bearer ", "", -1))
+	if err != nil {
+		// invalid token
+		// log it.
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
+			// Token is either expired or not active yet
+			s.logger.Infof("token is either expired or not active yet: %s", err)
+			return nil, "", apperror.Wrap(apperror.Unauthenticated, err, "token is either expired or not active yet")
+		}
+		s.logger.Errorf("invalid token: %s", err)
+		return nil, "", apperror.Wrap(apperror.Unauthenticated, err, "invalid token")
+	}
+
+	mapClaims := claims.(jwt.MapClaims)
+	userId, _ := mapClaims["sub"].(string)
+	sessionId, ok := mapClaims["sid"].(string)
+	if !ok || userId == "" {
+		// tokens issued before the sessions existed - the client needs to refresh them.
+		s.logger.Info("token does not belong to a session")
+		return nil, "", apperror.New(apperror.Unauthenticated, "token does not belong to a session")
+	}
+
+	// a revoked session invalidates its access tokens immediately.
+	session, err := s.authSvc.GetActiveSession(sessionId)
+	if errors.Is(err, service.ErrSessionNotActive) || (err == nil && session.UserId.String() != userId) {
+		s.logger.Infof("session %s is not active", sessionId)
+		return nil, "", apperror.New(apperror.Unauthenticated, "session is revoked or expired")
+	}
+	if err != nil {
+		s.logger.Errorf("could not fetch session: %s", err)
+		return nil, "", apperror.Wrap(apperror.Internal, err, "could not fetch session from database")
+	}
+
+	// get the user from the token
+	user, err := s.userSvc.GetById(userId)
+	if err != nil {
+		// invalid user
+		// log it.
+		s.logger.Errorf("invalid user: %s", err)
+		return nil, "", apperror.Wrap(apperror.Internal, err, "could not fetch user from database")
+	}
+	// the sessions are revoked when banning - just to be safe.
+	if user.IsBanned() {
+		s.logger.Warnf("banned user %s used a session", user.Id)
+		return nil, "", apperror.Wrap(apperror.Forbidden, service.ErrUserBanned, service.ErrUserBanned.Error())
+	}
+	return &user, session.Id.String(), nil
+}
+
+// anonymous graphql requests are rate limited by the ip of the client.
+func clientIpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), config.CLIENT_IP_CTX_KEY, http_util.ClientIp(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func withUser(ctx context.Context, user *models.User, sessionId string) context.Context {
+	ctx = context.WithValue(ctx, config.USER_CTX_KEY, user)
+	return context.WithValue(ctx, config.SESSION_CTX_KEY, sessionId)
+}
+
 // the auth middleware will set the current logged in user into the context
 func (s *GraphqlServer) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -94,53 +187,41 @@ func (s *GraphqlServer) authMiddleware(next http.Handler) http.Handler {
 		token := r.Header.Get("Authorization")
 
 		if token == "" {
-			s.logger.Warn("auth middleware called without token")
+			// anonymous requests are allowed - the graphql directives decide which fields are public.
 			next.ServeHTTP(w, r)
-			// http_util.WriteHttpError(w, http.StatusUnauthorized, "no token provided")
-			return
-		}
-
-		// parse the token from the request
-		// remove the bearer prefix
-		claims, err := s.tokenSvc.ParseToken(strings.Replace(strings.Replace(token, "Bearer ", "", -1), "bearer ", "", -1))
-		if err != nil {
-			// invalid token
-			// log it.
-			if ve, ok := err.(*jwt.ValidationError); ok {
-				if ve.Errors&jwt.ValidationErrorMalformed != 0 {
-					s.logger.Errorf("invalid token: %s", err)
-					http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("invalid token: %e", err))
-				} else if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
-					// Token is either expired or not active yet
-					s.logger.Infof("token is either expired or not active yet: %s", err)
-					http_util.WriteHttpError(w, http.StatusUnauthorized, fmt.Sprintf("token is either expired or not active yet: %e", err))
-				} else {
-					s.logger.Errorf("invalid token: %s", err)
-					http_util.WriteHttpError(w, http.StatusInternalServerError, fmt.Sprintf("invalid token: %e", err))
-				}
-			}
 			return
 		}
 
-		// get the user from the token
-		user, err := s.userSvc.GetById(claims.(jwt.MapClaims)["sub"].(string))
+		user, sessionId, err := s.authenticate(token)
 		if err != nil {
-			// invalid user
-			// log it.
-			s.logger.Errorf("invalid user: %s", err)
-			http_util.WriteHttpError(w, http.StatusInternalServerError, "could not fetch user from database")
+			http_util.WriteError(w, r, err)
 			return
 		}
-
-		oldCtx := r.Context()
-		newCtx := context.WithValue(oldCtx, config.USER_CTX_KEY, &user)
-		next.ServeHTTP(w, r.WithContext(newCtx))
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user, sessionId)))
 	})
 }
 
+// browsers can not set headers on websocket connections - the token is sent with the connection_init message instead.
+func (s *GraphqlServer) websocketInit(ctx context.Context, initPayload transport.InitPayload) (context.Context, error) {
+	token := initPayload.Authorization()
+	if token == "" {
+		return ctx, nil
+	}
+	user, sessionId, err := s.authenticate(token)
+	if err != nil {
+		return ctx, err
+	}
+	return withUser(ctx, user, sessionId), nil
+}
+
 func graphqlTimeout(timeout time.Duration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" {
+				// websocket connections live as long as the subscriptions.
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer func() {
 				cancel()
@@ -153,8 +234,9 @@ func graphqlTimeout(timeout time.Duration) func(next http.Handler) http.Handler
 	}
 }
 func NewGraphqlServer(db *gorm.DB, imagesBasePath string) Server {
-	koiPreloader := generator.NewMemoryPreloader(imagesBasePath + "/koi")
-	dragonPreloader := generator.NewMemoryPreloader(imagesBasePath + "/dragon")
+	// the asset directories might change at runtime - see the /admin/assets routes.
+	koiPreloader := generator.NewReloadingPreloader(imagesBasePath + "/koi")
+	dragonPreloader := generator.NewReloadingPreloader(imagesBasePath + "/dragon")
 
 	// build the caches during bootstrap in a non blocking way
 	go koiPreloader.BuildCachesForSizes([]int{200, 350, 1024})
@@ -164,11 +246,45 @@ func NewGraphqlServer(db *gorm.DB, imagesBasePath string) Server {
 		db:              db,
 		koiGenerator:    generator.NewGenerator(koiPreloader),
 		dragonGenerator: generator.NewGenerator(dragonPreloader),
+		koiPreloader:    koiPreloader,
+		dragonPreloader: dragonPreloader,
 		logger:          orchardclient.Logger.WithField("component", "GraphqlServer"),
 	}
 }
 
-func (s *GraphqlServer) imageHandlerFactory(defaultSize int, drawBackgroundColor bool) http.HandlerFunc {
+// parses the compositing options from the query parameters.
+// example: ?background=gradient&padding=40&mask=rounded&radius=60
+func parseFrameOptions(r *http.Request, size int) (generator.FrameOptions, error) {
+	query := r.URL.Query()
+	var opts generator.FrameOptions
+	var err error
+
+	opts.Background, err = generator.IsBackgroundType(query.Get("background"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Mask, err = generator.IsMaskType(query.Get("mask"))
+	if err != nil {
+		return opts, err
+	}
+
+	if padding := query.Get("padding"); padding != "" {
+		opts.Padding, err = strconv.Atoi(padding)
+		if err != nil || opts.Padding < 0 || opts.Padding*2 >= size {
+			return opts, fmt.Errorf("invalid padding")
+		}
+	}
+
+	if radius := query.Get("radius"); radius != "" {
+		opts.CornerRadius, err = strconv.Atoi(radius)
+		if err != nil || opts.CornerRadius < 0 {
+			return opts, fmt.Errorf("invalid radius")
+		}
+	}
+	return opts, nil
+}
+
+func (s *GraphqlServer) imageHandlerFactory(defaultSize int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenId := chi.URLParam(r, "tokenId")
 		t := r.URL.Query().Get("type")
@@ -180,7 +296,7 @@ func (s *GraphqlServer) imageHandlerFactory(defaultSize int, drawBackgroundColor
 			// not only digits - use as hex.
 			tmp, err := util.UuidToUint256(tokenId)
 			if err != nil {
-				http_util.WriteHttpError(w, http.StatusBadRequest, "invalid tokenId")
+				http_util.WriteProblem(w, r, http.StatusBadRequest, apperror.Validation, "invalid tokenId")
 				return
 			}
 
@@ -195,12 +311,18 @@ func (s *GraphqlServer) imageHandlerFactory(defaultSize int, drawBackgroundColor
 		if sz := r.URL.Query().Get("size"); sz != "" {
 			size, err = strconv.Atoi(sz)
 			if err != nil {
-				http_util.WriteHttpError(w, http.StatusBadRequest, "invalid size")
+				http_util.WriteProblem(w, r, http.StatusBadRequest, apperror.Validation, "invalid size")
 				return
 			}
 
 		}
 
+		frameOptions, err := parseFrameOptions(r, size)
+		if err != nil {
+			http_util.WriteProblem(w, r, http.StatusBadRequest, apperror.Validation, err.Error())
+			return
+		}
+
 		// decide wether to generate a koi or a dragon
 		if t == "koi" {
 			img, koi = s.koiGenerator.TokenId2Image(tokenId, size)
@@ -208,14 +330,7 @@ func (s *GraphqlServer) imageHandlerFactory(defaultSize int, drawBackgroundColor
 			img, koi = s.dragonGenerator.TokenId2Image(tokenId, size)
 		}
 
-		if drawBackgroundColor {
-			primaryColor := koi.GetAttributes().PrimaryColor
-			for y := 0; y < img.Bounds().Max.Y; y++ {
-				for x := 0; x < img.Bounds().Max.X; x++ {
-					img.(*image.RGBA).Set(x, y, primaryColor)
-				}
-			}
-		}
+		img = generator.Frame(img, koi.GetAttributes().PrimaryColor, frameOptions)
 
 		w.Header().Set("Content-Type", "image/png")
 
@@ -251,29 +366,242 @@ func (s *GraphqlServer) getLeaderboardUpdateRoutine() leader.Listener {
 	})
 }
 
-func (s *GraphqlServer) getBlockchainListener() leader.Listener {
-	return leader.NewListener(func(cancelChan <-chan struct{}) {
-		eventChan := s.cryptokoiListener.StartListener()
-		for {
-			select {
-			case <-cancelChan:
-				return
-			case ev := <-eventChan:
-				crypt, err := s.cryptogotchiSvc.GetCryptogotchiByUint256(ev.TokenId)
+// the defaults are used for every variable which is not set.
+func (s *GraphqlServer) getTransferSyncConfig(contractAddress string) service.TransferSyncConfig {
+	config := service.DefaultTransferSyncConfig(strings.ToLower(contractAddress))
+	if confirmations := os.Getenv("BLOCK_CONFIRMATIONS"); confirmations != "" {
+		confirmationsInt, err := strconv.ParseUint(confirmations, 10, 64)
+		orchardclient.FailOnError(err, "could not parse block confirmations")
+		config.Confirmations = confirmationsInt
+	}
+	if startBlock := os.Getenv("CONTRACT_START_BLOCK"); startBlock != "" {
+		startBlockInt, err := strconv.ParseUint(startBlock, 10, 64)
+		orchardclient.FailOnError(err, "could not parse contract start block")
+		config.StartBlock = startBlockInt
+	}
+	if chunkSize := os.Getenv("LOG_CHUNK_SIZE"); chunkSize != "" {
+		chunkSizeInt, err := strconv.ParseUint(chunkSize, 10, 64)
+		orchardclient.FailOnError(err, "could not parse log chunk size")
+		if chunkSizeInt == 0 {
+			s.logger.Fatal("LOG_CHUNK_SIZE needs to be greater than 0")
+		}
+		config.ChunkSize = chunkSizeInt
+	}
+	if interval := os.Getenv("CHAIN_POLL_INTERVAL"); interval != "" {
+		intervalInt, err := strconv.Atoi(interval)
+		orchardclient.FailOnError(err, "could not parse chain poll interval")
+		config.PollInterval = time.Second * time.Duration(intervalInt)
+	}
+	return config
+}
 
-				if err != nil {
-					s.logger.Error(err)
-					continue
-				}
+// the sign-in message needs to be bound to this api.
+// defaults to the host of the IMAGE_BASE_URL.
+func (s *GraphqlServer) getSiweConfig(imageBaseUrl string, chainId int) service.SiweConfig {
+	uri := os.Getenv("SIWE_URI")
+	if uri == "" {
+		uri = imageBaseUrl
+	}
+	domain := os.Getenv("SIWE_DOMAIN")
+	if domain == "" {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Host == "" {
+			s.logger.Fatal("could not determine the sign-in domain. Set SIWE_DOMAIN.")
+		}
+		domain = parsed.Host
+	}
+	return service.SiweConfig{
+		Domain:  domain,
+		URI:     uri,
+		ChainId: chainId,
+	}
+}
 
-				err = s.cryptogotchiSvc.MarkAsNft(&crypt)
-				if err != nil {
-					s.logger.Error(err)
-					continue
-				}
-			}
+// sends the mails using SMTP if SMTP_HOST is set.
+// otherwise the mails are only logged and written to MAIL_DIR - meant for local development.
+func (s *GraphqlServer) getMailSender() mail.Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		s.logger.Warn("SMTP_HOST is not set - mails are only logged")
+		return mail.NewLogSender(os.Getenv("MAIL_DIR"))
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = fmt.Sprint(587)
+	}
+	portInt, err := strconv.Atoi(port)
+	orchardclient.FailOnError(err, "could not parse smtp port")
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		s.logger.Fatal("MAIL_FROM is not set")
+	}
+	return mail.NewSMTPSender(host, portInt, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+}
+
+// the defaults are used for every variable which is not set.
+func (s *GraphqlServer) getNamePolicy() service.NamePolicy {
+	policy := service.DefaultNamePolicy()
+	if minLength := os.Getenv("NAME_MIN_LENGTH"); minLength != "" {
+		minLengthInt, err := strconv.Atoi(minLength)
+		orchardclient.FailOnError(err, "could not parse name min length")
+		policy.MinLength = minLengthInt
+	}
+	if maxLength := os.Getenv("NAME_MAX_LENGTH"); maxLength != "" {
+		maxLengthInt, err := strconv.Atoi(maxLength)
+		orchardclient.FailOnError(err, "could not parse name max length")
+		policy.MaxLength = maxLengthInt
+	}
+	if cooldown := os.Getenv("RENAME_COOLDOWN"); cooldown != "" {
+		cooldownInt, err := strconv.Atoi(cooldown)
+		orchardclient.FailOnError(err, "could not parse rename cooldown")
+		policy.RenameCooldown = time.Second * time.Duration(cooldownInt)
+	}
+	if denylistPath := os.Getenv("NAME_DENYLIST_PATH"); denylistPath != "" {
+		denylist, err := service.LoadDenylist(denylistPath)
+		orchardclient.FailOnError(err, "could not load name denylist")
+		policy.Denylist = denylist
+	}
+	policy.UniqueCryptogotchiNames = os.Getenv("UNIQUE_CRYPTOGOTCHI_NAMES") == "true"
+	policy.UniqueUserNames = os.Getenv("UNIQUE_USER_NAMES") == "true"
+	return policy
+}
+
+func (s *GraphqlServer) getReservationPolicy() service.ReservationPolicy {
+	policy := service.DefaultReservationPolicy()
+	if window := os.Getenv("RESERVATION_WINDOW"); window != "" {
+		windowInt, err := strconv.Atoi(window)
+		orchardclient.FailOnError(err, "could not parse reservation window")
+		policy.Window = time.Second * time.Duration(windowInt)
+	}
+	if maxPending := os.Getenv("MAX_PENDING_RESERVATIONS"); maxPending != "" {
+		maxPendingInt, err := strconv.Atoi(maxPending)
+		orchardclient.FailOnError(err, "could not parse max pending reservations")
+		if maxPendingInt < 1 {
+			s.logger.Fatal("MAX_PENDING_RESERVATIONS needs to be greater than 0")
 		}
-	})
+		policy.MaxPending = maxPendingInt
+	}
+	return policy
+}
+
+// a voucher of a reservation expires with the reservation at the latest.
+func (s *GraphqlServer) getVoucherTtl() time.Duration {
+	ttl := os.Getenv("VOUCHER_TTL")
+	if ttl == "" {
+		return time.Hour
+	}
+	ttlInt, err := strconv.Atoi(ttl)
+	orchardclient.FailOnError(err, "could not parse voucher ttl")
+	return time.Second * time.Duration(ttlInt)
+}
+
+// the subscriptions only receive the updates of this replica if REDIS_ADDR is not set.
+func (s *GraphqlServer) getPubSub() pubsub.PubSub {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		s.logger.Info("REDIS_ADDR is not set - live updates are not shared between replicas")
+		return pubsub.NewMemoryPubSub()
+	}
+	prefix := os.Getenv("REDIS_PREFIX")
+	if prefix == "" {
+		prefix = "crypto-koi:"
+	}
+	return pubsub.NewRedisPubSub(addr, os.Getenv("REDIS_PASSWORD"), prefix)
+}
+
+func envInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	valueInt, err := strconv.Atoi(value)
+	orchardclient.FailOnError(err, fmt.Sprintf("could not parse %s", name))
+	return valueInt
+}
+
+// clients may only send the queries of the manifest if PERSISTED_QUERIES_PATH is set.
+func (s *GraphqlServer) useQueryLimits(srv *handler.Server) {
+	srv.Use(extension.FixedComplexityLimit(envInt("GRAPHQL_MAX_COMPLEXITY", 3000)))
+	srv.Use(graph.DepthLimit{MaxDepth: envInt("GRAPHQL_MAX_DEPTH", 10)})
+
+	path := os.Getenv("PERSISTED_QUERIES_PATH")
+	if path == "" {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: lru.New(100),
+		})
+		return
+	}
+	persistedQueries, err := graph.LoadPersistedQueries(path, os.Getenv("PERSISTED_QUERIES_ONLY") == "true")
+	orchardclient.FailOnError(err, "could not load persisted queries")
+	srv.Use(persistedQueries)
+}
+
+// the policies of the routes and graphql fields - RATE_LIMITS overrides single policies.
+var defaultRateLimits = map[string]ratelimit.Policy{
+	"nonce":              {Limit: 30, Period: time.Minute},
+	"login":              {Limit: 20, Period: time.Minute},
+	"register":           {Limit: 5, Period: time.Hour},
+	"auth":               {Limit: 10, Period: time.Minute},
+	"walletChallenge":    {Limit: 20, Period: time.Minute},
+	"createCryptogotchi": {Limit: 3, Period: time.Hour},
+	"getNftSignature":    {Limit: 10, Period: time.Minute},
+	"startGame":          {Limit: 30, Period: time.Minute},
+}
+
+// the buckets are shared between the replicas if REDIS_ADDR is set.
+func (s *GraphqlServer) getRateLimiter() *ratelimit.Limiter {
+	policies := make(map[string]ratelimit.Policy)
+	for name, policy := range defaultRateLimits {
+		policies[name] = policy
+	}
+	overrides, err := ratelimit.ParsePolicies(os.Getenv("RATE_LIMITS"))
+	orchardclient.FailOnError(err, "could not parse RATE_LIMITS")
+	for name, policy := range overrides {
+		policies[name] = policy
+	}
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return ratelimit.NewLimiter(ratelimit.NewMemoryStore(), policies)
+	}
+	prefix := os.Getenv("REDIS_PREFIX")
+	if prefix == "" {
+		prefix = "crypto-koi:"
+	}
+	return ratelimit.NewLimiter(ratelimit.NewRedisStore(addr, os.Getenv("REDIS_PASSWORD"), prefix+"ratelimit:"), policies)
+}
+
+// every instance watches its own asset directories - they are not shared between pods.
+func (s *GraphqlServer) watchAssets() {
+	interval := os.Getenv("ASSET_WATCH_INTERVAL")
+	if interval == "" {
+		interval = fmt.Sprint(30)
+	}
+	intervalInt, err := strconv.Atoi(interval)
+	orchardclient.FailOnError(err, "could not parse asset watch interval")
+	if intervalInt <= 0 {
+		s.logger.Info("asset watcher disabled")
+		return
+	}
+	// the watchers run as long as the process does.
+	cancelChan := make(chan struct{})
+	go s.koiPreloader.Watch(time.Second*time.Duration(intervalInt), cancelChan)
+	go s.dragonPreloader.Watch(time.Second*time.Duration(intervalInt), cancelChan)
+}
+
+// new keys are picked up without a restart - see KEY_DIRECTORY.
+func (s *GraphqlServer) watchKeys() {
+	interval := os.Getenv("KEY_WATCH_INTERVAL")
+	if interval == "" {
+		interval = fmt.Sprint(60)
+	}
+	intervalInt, err := strconv.Atoi(interval)
+	orchardclient.FailOnError(err, "could not parse key watch interval")
+	if intervalInt <= 0 || os.Getenv("KEY_DIRECTORY") == "" {
+		return
+	}
+	// the watcher runs as long as the process does.
+	go s.tokenSvc.Watch(time.Second*time.Duration(intervalInt), make(chan struct{}))
 }
 
 func (s *GraphqlServer) getLeaderElection() leader.LeaderElection {
@@ -313,27 +641,40 @@ func (s *GraphqlServer) Start() {
 		Repanic: true,
 	})
 	// register all middlewares
+	// the request id is part of every problem document - register it first.
+	router.Use(middleware.RequestID)
 	router.Use(loggerMiddleware)
 
 	// allow cross origin request
 	router.Use(cors.AllowAll().Handler)
 
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.AllowContentType("application/json"))
+	router.Use(http_util.Recoverer)
+	// zip is only used to upload asset packs.
+	router.Use(middleware.AllowContentType("application/json", "application/zip"))
 
 	router.Use(middleware.RealIP)
-	router.Use(middleware.RequestID)
 
 	router.Use(sentryMiddleware.Handle)
 
-	router.Get("/images/{tokenId}", s.imageHandlerFactory(1024, false))
-	router.Get("/thumbnails/{tokenId}", s.imageHandlerFactory(200, false))
+	router.Get("/images/{tokenId}", s.imageHandlerFactory(1024))
+	router.Get("/thumbnails/{tokenId}", s.imageHandlerFactory(200))
+
+	chainIdEnv := os.Getenv("CHAIN_ID")
+	if chainIdEnv == "" {
+		s.logger.Fatal("CHAIN_ID is not set")
+	}
+	chainId, err := strconv.ParseInt(chainIdEnv, 10, 64)
+	if err != nil {
+		s.logger.Fatal(err)
+	}
 
 	// init all repositories
 	cryptogotchiRepository := repositories.NewGormCryptogotchiRepository(s.db)
 	eventRepository := repositories.NewGormEventRepository(s.db)
 	userRepository := repositories.NewGormUserRepository(s.db)
 	gameRepository := repositories.NewGormGameStatRepository(s.db)
+	nonceRepository := repositories.NewGormNonceRepository(s.db)
+	sessionRepository := repositories.NewGormSessionRepository(s.db)
 
 	// init all services
 	tokenSvc := service.NewTokenService()
@@ -343,20 +684,33 @@ func (s *GraphqlServer) Start() {
 	}
 
 	notificationSvc := service.NewNotificationSvc(apiKey)
-	userSvc := service.NewUserService(userRepository)
-	authSvc := service.NewAuthService(userRepository, tokenSvc)
+	namePolicy := s.getNamePolicy()
+	userSvc := service.NewUserService(userRepository, namePolicy)
+	// the links sent by mail are opened by the app.
+	emailLinkBaseUrl := os.Getenv("EMAIL_LINK_BASE_URL")
+	if emailLinkBaseUrl == "" {
+		emailLinkBaseUrl = imageBaseUrl
+	}
+	authSvc := service.NewAuthService(userRepository, nonceRepository, sessionRepository, tokenSvc, s.getMailSender(), emailLinkBaseUrl, s.getSiweConfig(imageBaseUrl, int(chainId)))
 	eventSvc := service.NewEventService(eventRepository)
 	gameSvc := service.NewGameService(gameRepository, eventSvc, tokenSvc)
+	liveUpdateSvc := service.NewLiveUpdateService(s.getPubSub())
 	// init all controllers
-	cryptogotchiSvc := service.NewCryptogotchiService(cryptogotchiRepository, userRepository, notificationSvc)
-	authController := controller.NewAuthController(userRepository, cryptogotchiSvc, authSvc)
+	cryptogotchiSvc := service.NewCryptogotchiService(cryptogotchiRepository, userRepository, repositories.NewGormOwnershipTransferRepository(s.db), notificationSvc, namePolicy, s.getReservationPolicy(), liveUpdateSvc)
+	// WALLET_ADDRESS_LOGIN=true only during the migration of old clients to sign-in with ethereum.
+	authController := controller.NewAuthController(userRepository, cryptogotchiSvc, authSvc, os.Getenv("WALLET_ADDRESS_LOGIN") == "true")
 	openseaController := controller.NewOpenseaController(imageBaseUrl, eventRepository, cryptogotchiSvc)
+	cardController := controller.NewCardController(imageBaseUrl, &s.koiGenerator, cryptogotchiSvc)
+	keyController := controller.NewKeyController(tokenSvc)
 
 	// set services to server instance for middleware and listeners
 	s.tokenSvc = tokenSvc
 	s.userSvc = userSvc
+	s.authSvc = authSvc
 	s.cryptogotchiSvc = cryptogotchiSvc
 
+	limiter := s.getRateLimiter()
+
 	// add all routes.
 	if isDev {
 		router.Handle("/", playground.Handler("GraphQL playground", "/query"))
@@ -364,9 +718,24 @@ func (s *GraphqlServer) Start() {
 	router.Group(func(r chi.Router) {
 		// make sure to stop processing after 10 seconds.
 		r.Use(middleware.Timeout(10 * time.Second))
-		r.Post("/auth/login", authController.Login)
-		r.Post("/auth/register", authController.Register)
+		// every nonce gets stored until it expires.
+		r.With(http_util.RateLimit(limiter, "nonce")).Get("/auth/nonce", authController.Nonce)
+		r.With(http_util.RateLimit(limiter, "login")).Post("/auth/login", authController.Login)
+		r.With(http_util.RateLimit(limiter, "register")).Post("/auth/register", authController.Register)
+		// not limited - many clients might share an ip and a refresh token can not be guessed.
 		r.Post("/auth/refresh", authController.Refresh)
+		r.Group(func(r chi.Router) {
+			// send mails or accept codes.
+			r.Use(http_util.RateLimit(limiter, "auth"))
+			r.Post("/auth/email/verify", authController.VerifyEmail)
+			r.Post("/auth/magic-link", authController.RequestMagicLink)
+			r.Post("/auth/magic-link/login", authController.LoginWithMagicLink)
+			r.Post("/auth/recovery", authController.RequestRecovery)
+			r.Post("/auth/recovery/complete", authController.Recover)
+			r.Post("/auth/pairing", authController.RedeemPairingCode)
+		})
+		// other services verify our tokens using these keys.
+		r.Get("/.well-known/jwks.json", keyController.GetJWKS)
 	})
 
 	router.Route("/v1", func(r chi.Router) {
@@ -375,10 +744,37 @@ func (s *GraphqlServer) Start() {
 		// opensea.io integration.
 		// gets called by their API and wallet applications.
 		r.Get("/tokens/{tokenId}", openseaController.GetCryptogotchi)
-		r.Get("/images/{tokenId}", s.imageHandlerFactory(350, false))
+		r.Get("/images/{tokenId}", s.imageHandlerFactory(350))
 		r.Get("/fakes/{tokenId}", openseaController.GetFakeCryptogotchi)
+		// social share cards
+		r.Get("/cards/{tokenId}", cardController.GetCard)
 	})
 
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(10 * time.Second))
+		// carries the open graph tags pointing to the share card.
+		r.Get("/share/{tokenId}", cardController.GetSharePage)
+	})
+
+	s.watchAssets()
+	s.watchKeys()
+	// the admin routes are only available if a token is configured.
+	if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+		assetController := controller.NewAssetController(adminToken, map[string]*generator.ReloadingPreloader{
+			"koi":    s.koiPreloader,
+			"dragon": s.dragonPreloader,
+		})
+		router.Route("/admin/assets", func(r chi.Router) {
+			r.Use(assetController.AdminMiddleware)
+			r.Get("/", assetController.GetStatus)
+			r.Post("/{type}/reload", assetController.Reload)
+			// no timeout - validating and caching a pack takes a while.
+			r.Post("/{type}/pack", assetController.UploadPack)
+		})
+	} else {
+		s.logger.Info("ADMIN_TOKEN is not set - admin routes are disabled")
+	}
+
 	privateKey := os.Getenv("PRIVATE_KEY")
 	if privateKey == "" {
 		s.logger.Fatal("PRIVATE_KEY environment variable is not defined")
@@ -415,29 +811,57 @@ func (s *GraphqlServer) Start() {
 	wsBinding, err := cryptokoi.NewCryptoKoiBinding(common.HexToAddress(contractAddress), ethWsClient)
 	orchardclient.FailOnError(err, "Failed to instantiate a CryptoKoi contract binding (WS)")
 
-	cryptokoiApi := cryptokoi.NewCryptokoiApi(privateKey, httpBinding)
-	s.cryptokoiListener = cryptokoi.NewCryptoKoiEventListener(wsBinding)
+	cryptokoiApi := cryptokoi.NewCryptokoiApi(privateKey, httpBinding, cryptokoi.VoucherDomain{
+		ChainId:           big.NewInt(chainId),
+		VerifyingContract: common.HexToAddress(contractAddress),
+	})
+	transferSource := cryptokoi.NewChainTransferSource(ethHttpClient, httpBinding, cryptokoi.NewCryptoKoiEventListener(wsBinding))
+	transferSyncSvc := service.NewTransferSyncService(transferSource, repositories.NewGormCheckpointRepository(s.db), cryptogotchiSvc, s.getTransferSyncConfig(contractAddress))
 
 	s.leaderElection = s.getLeaderElection()
 	// start the listener.
-	s.leaderElection.AddListener(s.getBlockchainListener())
+	s.leaderElection.AddListener(transferSyncSvc.GetListener())
 	s.leaderElection.AddListener(s.getLeaderboardUpdateRoutine())
 	s.leaderElection.AddListener(cryptogotchiSvc.GetNotificationListener())
+	s.leaderElection.AddListener(authSvc.GetCleanupListener())
+	s.leaderElection.AddListener(cryptogotchiSvc.GetReservationCleanupListener())
 	// start all listeners
 	go s.leaderElection.RunElection()
 
-	chainIdEnv := os.Getenv("CHAIN_ID")
-	if chainIdEnv == "" {
-		s.logger.Fatal("CHAIN_ID is not set")
-	}
-	chainId, err := strconv.ParseInt(chainIdEnv, 10, 64)
-	if err != nil {
-		s.logger.Fatal(err)
-	}
-
 	// attach the graphql handler to the router
-	resolver := graph.NewResolver(int(chainId), s.userSvc, eventSvc, cryptogotchiSvc, gameSvc, authSvc, cryptokoiApi, s.koiGenerator)
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &resolver}))
+	resolver := graph.NewResolver(int(chainId), s.getVoucherTtl(), os.Getenv("LEGACY_VOUCHERS") == "true", s.userSvc, eventSvc, cryptogotchiSvc, gameSvc, authSvc, liveUpdateSvc, cryptokoiApi, s.koiGenerator)
+	schema := generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &resolver,
+		Directives: graph.Directives(),
+		Complexity: graph.Complexity(),
+	})
+	// a root field without an auth directive would be public by accident.
+	orchardclient.FailOnError(graph.ValidateAuthDirectives(schema.Schema()), "invalid graphql schema")
+	// like handler.NewDefaultServer - the websocket transport authenticates using the connection_init payload.
+	srv := handler.New(schema)
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              s.websocketInit,
+		Upgrader: websocket.Upgrader{
+			// like the cors middleware - every origin is allowed.
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+	s.useQueryLimits(srv)
+	srv.Use(graph.RateLimit{Limiter: limiter})
+	loaderOperations, loaderRootFields := graph.LoaderMiddleware(s.userSvc, cryptogotchiSvc)
+	srv.AroundOperations(loaderOperations)
+	srv.AroundRootFields(loaderRootFields)
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.SetRecoverFunc(graph.RecoverFunc)
 
 	// authorized routes
 	router.Group(func(r chi.Router) {
@@ -445,12 +869,14 @@ func (s *GraphqlServer) Start() {
 		r.Use(graphqlTimeout(10 * time.Second))
 		// attach the auth middleware to the router
 		r.Use(s.authMiddleware)
+		r.Use(clientIpMiddleware)
 		r.Handle("/query", srv)
 	})
 
 	router.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware)
 		r.Delete("/auth", authController.DestroyAccount)
+		r.With(http_util.RateLimit(limiter, "auth")).Post("/auth/email/verification", authController.RequestEmailVerification)
 	})
 
 	s.logger.Infof("connect to http://localhost:%s/ for GraphQL playground", port)
@@ -2,29 +2,95 @@ package service
 
 import (
 	"crypto/ecdsa"
+	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_dto"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/mail"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/siwe"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/leader"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+	"gorm.io/gorm"
 )
 
+// the time a client has to sign the login message.
+const LOGIN_NONCE_TTL = 10 * time.Minute
+
+const siweStatement = "Sign in to CryptoKoi."
+
+// the values a Sign-In with Ethereum message needs to contain.
+type SiweConfig struct {
+	// the host of the api - for example: api.crypto-koi.io
+	Domain  string
+	URI     string
+	ChainId int
+}
+
 type AuthSvc interface {
 	repositories.UserRepository
-	CreateTokenForUser(user *models.User) (http_dto.TokenResponse, error)
+	// starts a new session. The device is shown to the user when listing the sessions.
+	// returns ErrUserBanned for banned users.
+	CreateTokenForUser(user *models.User, device string) (http_dto.TokenResponse, error)
+	// rotates the refresh token of the session.
+	RefreshSession(refreshToken, device string) (http_dto.TokenResponse, error)
+	GetActiveSession(sessionId string) (models.Session, error)
+	GetSessions(userId uuid.UUID) ([]models.Session, error)
+	RevokeSession(user *models.User, sessionId string) error
 	GetSigningKey() *ecdsa.PrivateKey
+	CreateLoginChallenge() (http_dto.NonceResponse, error)
+	// returns the user who proved the ownership of the wallet which signed the message.
+	LoginWithSignature(message, signature string) (models.User, error)
+	// removes expired nonces and sessions.
+	GetCleanupListener() leader.Listener
+	// the links sent by mail can only be used once.
+	SendVerificationEmail(user *models.User) error
+	VerifyEmail(token string) (models.User, error)
+	SendMagicLink(email string) error
+	LoginWithMagicLink(token string) (models.User, error)
+	SendRecoveryEmail(email string) error
+	// binds a new device id to the user owning the recovery link.
+	RecoverAccount(token string, deviceId string) (models.User, error)
+	// returns a short code which links another device to the user - formatted like ABCD-EFGH.
+	CreatePairingCode(user *models.User) (string, time.Time, error)
+	// adds the device to the user who created the code and returns the user.
+	RedeemPairingCode(code string, deviceId string) (models.User, error)
+	// returns the message the wallet needs to sign to prove its ownership.
+	CreateWalletChallenge(user *models.User, walletAddress string) (string, time.Time, error)
+	// returns an error if the message was not created for the user and the wallet or was not signed by the wallet.
+	VerifyWalletOwnership(user *models.User, walletAddress, message, signature string) error
+	// revokes all sessions of the user.
+	BanUser(user *models.User, reason string) error
+	UnbanUser(user *models.User) error
 }
 
 type AuthService struct {
 	repositories.UserRepository
-	tokenSvc TokenSvc
+	nonceRep   repositories.NonceRepository
+	sessionRep repositories.SessionRepository
+	tokenSvc   TokenSvc
+	mailSender mail.Sender
+	// the links sent by mail point to this url - usually the app.
+	linkBaseUrl string
+	siweConfig  SiweConfig
+	logger      *logrus.Entry
 }
 
-func NewAuthService(rep repositories.UserRepository, tokenSvc TokenSvc) AuthSvc {
+func NewAuthService(rep repositories.UserRepository, nonceRep repositories.NonceRepository, sessionRep repositories.SessionRepository, tokenSvc TokenSvc, mailSender mail.Sender, linkBaseUrl string, siweConfig SiweConfig) AuthSvc {
 	return &AuthService{
 		UserRepository: rep,
+		nonceRep:       nonceRep,
+		sessionRep:     sessionRep,
 		tokenSvc:       tokenSvc,
+		mailSender:     mailSender,
+		linkBaseUrl:    strings.TrimSuffix(linkBaseUrl, "/"),
+		siweConfig:     siweConfig,
+		logger:         orchardclient.Logger.WithField("component", "AuthService"),
 	}
 }
 
@@ -32,30 +98,85 @@ func (svc *AuthService) GetSigningKey() *ecdsa.PrivateKey {
 	return svc.tokenSvc.GetSigningKey()
 }
 
-func (svc *AuthService) generateRefreshToken() string {
-	return uuid.NewString()
+// creates a nonce and returns everything the client needs to build the sign-in message.
+func (svc *AuthService) CreateLoginChallenge() (http_dto.NonceResponse, error) {
+	value, err := util.RandomNonce()
+	if err != nil {
+		return http_dto.NonceResponse{}, err
+	}
+	now := time.Now()
+	nonce := models.Nonce{
+		Value:     value,
+		Purpose:   models.LoginNoncePurpose,
+		ExpiresAt: now.Add(LOGIN_NONCE_TTL),
+	}
+	if err := svc.nonceRep.Save(&nonce); err != nil {
+		return http_dto.NonceResponse{}, err
+	}
+
+	return http_dto.NonceResponse{
+		Nonce:          nonce.Value,
+		Domain:         svc.siweConfig.Domain,
+		URI:            svc.siweConfig.URI,
+		Version:        "1",
+		ChainId:        svc.siweConfig.ChainId,
+		Statement:      siweStatement,
+		IssuedAt:       now.UTC().Format(time.RFC3339),
+		ExpirationTime: nonce.ExpiresAt.UTC().Format(time.RFC3339),
+	}, nil
 }
 
-func (svc *AuthService) CreateTokenForUser(user *models.User) (http_dto.TokenResponse, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:  user.Id.String(),
-		Issuer:   "clodhopper",
-		Audience: []string{"cattleshow-app"},
+func (svc *AuthService) LoginWithSignature(message, signature string) (models.User, error) {
+	m, err := siwe.Verify(message, signature)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := m.Validate(svc.siweConfig.Domain, svc.siweConfig.ChainId, time.Now()); err != nil {
+		return models.User{}, err
 	}
 
-	t, err := svc.tokenSvc.CreateSignedToken(claims)
+	// the nonce can only be used once.
+	if _, err := svc.nonceRep.Consume(m.Nonce, models.LoginNoncePurpose, nil); err != nil {
+		if db.IsNotFound(err) {
+			return models.User{}, siwe.ErrInvalidNonce
+		}
+		return models.User{}, err
+	}
 
-	if err != nil {
-		return http_dto.TokenResponse{}, err
+	user, err := svc.GetByVerifiedWalletAddress(m.Address.Hex())
+	if !db.IsNotFound(err) {
+		return user, err
 	}
 
-	// create a refresh token for the user.
-	user.RefreshToken = svc.generateRefreshToken()
-	// store the token inside the database
-	svc.Save(user)
+	// the wallet might be stored for a user who never proved it - anyone could enter any address before.
+	// the signer is the owner of the wallet: it does not belong to that user.
+	unproven, err := svc.GetByWalletAddress(m.Address.Hex())
+	if err != nil {
+		return unproven, err
+	}
+	svc.logger.Warnf("removing the unproven wallet [%s] from user %s", m.Address.Hex(), unproven.Id)
+	unproven.WalletAddress = nil
+	if err := svc.Save(&unproven); err != nil {
+		return models.User{}, err
+	}
+	return models.User{}, gorm.ErrRecordNotFound
+}
 
-	return http_dto.TokenResponse{
-		AccessToken:  t,
-		RefreshToken: user.RefreshToken,
-	}, nil
+// removes expired nonces and sessions from time to time.
+func (svc *AuthService) GetCleanupListener() leader.Listener {
+	return leader.NewListener(func(cancelChan <-chan struct{}) {
+		for {
+			select {
+			case <-cancelChan:
+				return
+			case <-time.After(LOGIN_NONCE_TTL):
+				if err := svc.nonceRep.DeleteExpired(); err != nil {
+					svc.logger.Errorf("could not delete expired nonces: %s", err)
+				}
+				if err := svc.sessionRep.DeleteExpired(); err != nil {
+					svc.logger.Errorf("could not delete expired sessions: %s", err)
+				}
+			}
+		}
+	})
 }
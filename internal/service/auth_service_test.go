@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/siwe"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+func newSiweTest(users ...*models.User) *AuthService {
+	return &AuthService{
+		UserRepository: &memoryUserRepository{users: users, devices: make(map[string]uuid.UUID)},
+		nonceRep:       &memoryNonceRepository{nonces: make(map[string]models.Nonce)},
+		siweConfig:     SiweConfig{Domain: "api.crypto-koi.io", URI: "https://api.crypto-koi.io", ChainId: 137},
+		logger:         orchardclient.Logger.WithField("component", "AuthService"),
+	}
+}
+
+// returns the message and its signature.
+func signIn(t *testing.T, svc *AuthService, key *ecdsa.PrivateKey) (string, string) {
+	challenge, err := svc.CreateLoginChallenge()
+	assert.Nil(t, err)
+	issuedAt, _ := time.Parse(time.RFC3339, challenge.IssuedAt)
+	expiresAt, _ := time.Parse(time.RFC3339, challenge.ExpirationTime)
+	message := siwe.Message{
+		Domain:         challenge.Domain,
+		Address:        crypto.PubkeyToAddress(key.PublicKey),
+		Statement:      challenge.Statement,
+		URI:            challenge.URI,
+		Version:        challenge.Version,
+		ChainId:        challenge.ChainId,
+		Nonce:          challenge.Nonce,
+		IssuedAt:       issuedAt,
+		ExpirationTime: &expiresAt,
+	}.String()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	assert.Nil(t, err)
+	sig[64] += 27
+	return message, hexutil.Encode(sig)
+}
+
+func TestLoginWithSignatureOfProvenWallet(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	user := walletUser(strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex()))
+	svc := newSiweTest(user)
+
+	loggedIn, err := svc.LoginWithSignature(signIn(t, svc, key))
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, loggedIn.Id)
+}
+
+func TestLoginWithSignatureRemovesUnprovenWallet(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	walletAddress := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	// registered the wallet of someone else without proving it.
+	squatter := &models.User{Base: models.Base{Id: uuid.New()}, WalletAddress: &walletAddress}
+	svc := newSiweTest(squatter)
+
+	_, err := svc.LoginWithSignature(signIn(t, svc, key))
+	assert.True(t, db.IsNotFound(err))
+	stored, _ := svc.GetById(squatter.Id.String())
+	assert.Nil(t, stored.WalletAddress)
+	assert.Nil(t, stored.WalletVerifiedAt)
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+var ErrUserBanned = errors.New("the user is banned")
+
+// revokes all sessions of the user. The user can not login until unbanned.
+func (svc *AuthService) BanUser(user *models.User, reason string) error {
+	now := time.Now()
+	user.BannedAt = &now
+	user.BanReason = &reason
+	if err := svc.Save(user); err != nil {
+		return err
+	}
+	svc.logger.Infof("banned user %s: %s", user.Id, reason)
+	return svc.sessionRep.RevokeByUserId(user.Id.String())
+}
+
+func (svc *AuthService) UnbanUser(user *models.User) error {
+	user.BannedAt = nil
+	user.BanReason = nil
+	return svc.Save(user)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanUserRevokesSessions(t *testing.T) {
+	svc, user := newSessionTest(t)
+	res, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.BanUser(user, "cheating"))
+	assert.True(t, user.IsBanned())
+	assert.Equal(t, "cheating", *user.BanReason)
+
+	sessions, err := svc.GetSessions(user.Id)
+	assert.Nil(t, err)
+	assert.Empty(t, sessions)
+	_, err = svc.RefreshSession(res.RefreshToken, "test-device")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestBannedUserCanNotLogin(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.BanUser(user, "cheating"))
+
+	_, err := svc.CreateTokenForUser(user, "test-device")
+	assert.ErrorIs(t, err, ErrUserBanned)
+	// the legacy refresh token does not allow to login either.
+	_, err = svc.RefreshSession("legacy-token", "test-device")
+	assert.ErrorIs(t, err, ErrUserBanned)
+
+	assert.Nil(t, svc.UnbanUser(user))
+	assert.Nil(t, user.BanReason)
+	_, err = svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/graph/input"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/config"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
@@ -22,20 +24,37 @@ type CryptogotchiSvc interface {
 	GenerateCryptogotchiForUser(user *models.User, active bool) (models.Cryptogotchi, error)
 	GenerateWithFixedTokenId(user *models.User, id uuid.UUID, active bool) (models.Cryptogotchi, error)
 	MarkAsNft(crypt *models.Cryptogotchi) error
+	// creates an inactive cryptogotchi which is reserved for the user - see ReservationPolicy.
+	Reserve(user *models.User) (models.Cryptogotchi, error)
+	// handles a transfer log of the contract - the mint included. See ownership.go
+	ApplyTransfer(ev cryptokoi.CryptoKoiEvent) error
+	// assigns the cryptogotchies the wallet of the user received before it was proven.
+	ClaimWalletCryptogotchies(user *models.User) error
+	// oldest first.
+	GetOwnershipTransfers(cryptogotchiIds []string) ([]models.OwnershipTransfer, error)
+	GetReservationCleanupListener() leader.Listener
 	GetNotificationListener() leader.Listener
 	UpdateRanks() error
+	// applies the name policy - see NamePolicy.
+	Rename(crypt *models.Cryptogotchi, name string) error
+	// overwrites the state of the cryptogotchi - used by the admins.
+	Correct(crypt *models.Cryptogotchi, correction input.CryptogotchiCorrection) error
 }
 
 type CryptogotchiService struct {
 	repositories.CryptogotchiRepository
 	userRep                  repositories.UserRepository
+	transferRep              repositories.OwnershipTransferRepository
 	logger                   *logrus.Entry
 	timeBetweenNotifications time.Duration
 	notificationSvc          NotificationService
 	notifications            config.PreloadedNotifications
+	namePolicy               NamePolicy
+	reservationPolicy        ReservationPolicy
+	liveUpdateSvc            LiveUpdateSvc
 }
 
-func NewCryptogotchiService(rep repositories.CryptogotchiRepository, userRep repositories.UserRepository, notificationSvc NotificationService) CryptogotchiSvc {
+func NewCryptogotchiService(rep repositories.CryptogotchiRepository, userRep repositories.UserRepository, transferRep repositories.OwnershipTransferRepository, notificationSvc NotificationService, namePolicy NamePolicy, reservationPolicy ReservationPolicy, liveUpdateSvc LiveUpdateSvc) CryptogotchiSvc {
 	logger := orchardclient.Logger.WithField("component", "CryptogotchiService")
 	notifications := config.GetNotifications()
 	return &CryptogotchiService{
@@ -45,6 +64,10 @@ func NewCryptogotchiService(rep repositories.CryptogotchiRepository, userRep rep
 		notificationSvc:          notificationSvc,
 		notifications:            notifications,
 		userRep:                  userRep,
+		transferRep:              transferRep,
+		namePolicy:               namePolicy,
+		reservationPolicy:        reservationPolicy,
+		liveUpdateSvc:            liveUpdateSvc,
 	}
 }
 
@@ -53,17 +76,48 @@ func (svc *CryptogotchiService) UpdateRanks() error {
 	if err != nil {
 		return err
 	}
+	changed := false
 	for rank, el := range elements {
+		if el.Rank == rank+1 {
+			continue
+		}
 		el.Rank = rank + 1
 		err = svc.Save(&el)
 		if err != nil {
 			return err
 		}
+		changed = true
+		svc.liveUpdateSvc.CryptogotchiUpdated(el.Id)
+	}
+	if changed {
+		svc.liveUpdateSvc.LeaderboardChanged()
 	}
 	return nil
 }
 
 func (svc *CryptogotchiService) GenerateWithFixedTokenId(user *models.User, id uuid.UUID, active bool) (models.Cryptogotchi, error) {
+	return svc.generate(user, id, active, nil)
+}
+
+// the user is nil for a minted cryptogotchi whose wallet belongs to no user.
+func (svc *CryptogotchiService) generate(user *models.User, id uuid.UUID, active bool, reservedUntil *time.Time) (models.Cryptogotchi, error) {
+	newCrypt, err := newCryptogotchi(user, id, active, reservedUntil)
+	if err != nil {
+		return newCrypt, err
+	}
+	if err := svc.Create(&newCrypt); err != nil {
+		return newCrypt, err
+	}
+	if !active {
+		// gorm replaces the zero value with the default of the column.
+		newCrypt.Active = false
+		err = svc.Save(&newCrypt)
+	}
+	return newCrypt, err
+}
+
+// the token id defines the koi and its name.
+func newCryptogotchi(user *models.User, id uuid.UUID, active bool, reservedUntil *time.Time) (models.Cryptogotchi, error) {
 	foodValue := config.DEFAULT_FOOD_VALUE
 	foodDrainValue := config.DEFAULT_FOOD_DRAIN
 	now := time.Now()
@@ -77,21 +131,26 @@ func (svc *CryptogotchiService) GenerateWithFixedTokenId(user *models.User, id u
 	koi := cryptokoi.NewKoi(tokenId.String())
 	name := strings.Title((koi.GetAttributes().KoiType))
 
+	var ownerId *uuid.UUID
+	if user != nil {
+		userId := user.Id
+		ownerId = &userId
+	}
 	newCrypt := models.Cryptogotchi{
 		// TODO: generate a random name
 		Base: models.Base{
 			Id: id,
 		},
 		Name:               util.Str(name),
-		OwnerId:            user.Id,
+		OwnerId:            ownerId,
 		Food:               foodValue,
 		Active:             active,
 		FoodDrain:          foodDrainValue,
 		PredictedDeathDate: now.Add(time.Duration(foodValue/foodDrainValue) * time.Minute),
 		SnapshotValid:      now,
+		ReservedUntil:      reservedUntil,
 	}
-	err = svc.Create(&newCrypt)
-	return newCrypt, err
+	return newCrypt, nil
 }
 
 func (svc *CryptogotchiService) GenerateCryptogotchiForUser(user *models.User, active bool) (models.Cryptogotchi, error) {
@@ -105,7 +164,51 @@ func (svc *CryptogotchiService) GenerateCryptogotchiForUser(user *models.User, a
 func (svc *CryptogotchiService) MarkAsNft(crypt *models.Cryptogotchi) error {
 	crypt.IsValidNft = true
 	crypt.Active = true
-	return svc.Save(crypt)
+	crypt.ReservedUntil = nil
+	if err := svc.Save(crypt); err != nil {
+		return err
+	}
+	svc.liveUpdateSvc.CryptogotchiUpdated(crypt.Id)
+	return nil
+}
+
+func (svc *CryptogotchiService) Rename(crypt *models.Cryptogotchi, name string) error {
+	name, err := svc.namePolicy.checkRename(name, crypt.NameChangedAt, svc.namePolicy.UniqueCryptogotchiNames, func(name string) (bool, error) {
+		return svc.IsNameTaken(name, crypt.Id)
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	crypt.Name = &name
+	crypt.NameChangedAt = &now
+	if err := svc.Save(crypt); err != nil {
+		return err
+	}
+	svc.liveUpdateSvc.CryptogotchiUpdated(crypt.Id)
+	return nil
+}
+
+func (svc *CryptogotchiService) Correct(crypt *models.Cryptogotchi, correction input.CryptogotchiCorrection) error {
+	if correction.Food != nil {
+		crypt.SetFood(*correction.Food, time.Now())
+	}
+	if correction.Name != nil {
+		name := strings.TrimSpace(*correction.Name)
+		if name == "" {
+			return apperror.NewValidation("name must not be empty")
+		}
+		crypt.Name = &name
+	}
+	if correction.IsValidNft != nil {
+		crypt.IsValidNft = *correction.IsValidNft
+	}
+	svc.logger.Infof("corrected cryptogotchi %s", crypt.Id)
+	if err := svc.Save(crypt); err != nil {
+		return err
+	}
+	svc.liveUpdateSvc.CryptogotchiUpdated(crypt.Id)
+	return nil
 }
 
 func (svc *CryptogotchiService) getUserForNotifications(phase string) ([]models.User, error) {
@@ -117,14 +220,18 @@ func (svc *CryptogotchiService) getUserForNotifications(phase string) ([]models.
 		return nil, err
 	}
 
-	users := make([]models.User, len(cryptogotchies))
+	users := make([]models.User, 0, len(cryptogotchies))
 
-	for i, crypt := range cryptogotchies {
+	for _, crypt := range cryptogotchies {
+		// the wallet of the owner is not connected to a user - nobody to notify.
+		if crypt.OwnerId == nil {
+			continue
+		}
 		user, err := svc.userRep.GetById(crypt.OwnerId.String())
 		if err != nil {
 			return nil, err
 		}
-		users[i] = user
+		users = append(users, user)
 	}
 	return users, nil
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+// the time the user has to type the code into the other device.
+const PAIRING_CODE_TTL = 5 * time.Minute
+
+// no ambiguous characters like 0 and O or 1 and I.
+// 32 characters - every random byte maps to a character with the same probability.
+const pairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const pairingCodeLength = 8
+
+var (
+	ErrInvalidPairingCode = errors.New("the pairing code is invalid, expired or already used")
+	ErrDeviceIdTaken      = errors.New("the device id belongs to another user")
+)
+
+func newPairingCode() (string, error) {
+	b := make([]byte, pairingCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, pairingCodeLength)
+	for i := range b {
+		code[i] = pairingCodeAlphabet[int(b[i])%len(pairingCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// users might type the code in lower case or without the dash.
+func normalizePairingCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(code))
+}
+
+// the code is displayed as ABCD-EFGH.
+func formatPairingCode(code string) string {
+	return code[:pairingCodeLength/2] + "-" + code[pairingCodeLength/2:]
+}
+
+// creates a code the user can redeem on another device to login there.
+func (svc *AuthService) CreatePairingCode(user *models.User) (string, time.Time, error) {
+	code, err := newPairingCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	nonce := models.Nonce{
+		Value:     code,
+		Purpose:   models.PairingNoncePurpose,
+		UserId:    &user.Id,
+		ExpiresAt: time.Now().Add(PAIRING_CODE_TTL),
+	}
+	if err := svc.nonceRep.Save(&nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	return formatPairingCode(code), nonce.ExpiresAt, nil
+}
+
+// adds the device to the user which created the code.
+func (svc *AuthService) RedeemPairingCode(code string, deviceId string) (models.User, error) {
+	if deviceId == "" {
+		return models.User{}, fmt.Errorf("device id is required")
+	}
+	owner, err := svc.GetByDeviceId(deviceId)
+	if err != nil && !db.IsNotFound(err) {
+		return models.User{}, err
+	}
+	deviceExists := err == nil
+
+	nonce, err := svc.nonceRep.ConsumeForAnyUser(normalizePairingCode(code), models.PairingNoncePurpose)
+	if db.IsNotFound(err) {
+		return models.User{}, ErrInvalidPairingCode
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := svc.GetById(nonce.UserId.String())
+	if err != nil {
+		return models.User{}, err
+	}
+	if deviceExists {
+		if owner.Id != user.Id {
+			return models.User{}, ErrDeviceIdTaken
+		}
+		// the device is already linked.
+		return user, nil
+	}
+	return user, svc.AddDevice(&user, deviceId)
+}
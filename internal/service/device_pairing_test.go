@@ -0,0 +1,73 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func TestNewPairingCode(t *testing.T) {
+	code, err := newPairingCode()
+	assert.Nil(t, err)
+	assert.Len(t, code, pairingCodeLength)
+	for _, c := range code {
+		assert.True(t, strings.ContainsRune(pairingCodeAlphabet, c))
+	}
+}
+
+func TestNormalizePairingCode(t *testing.T) {
+	assert.Equal(t, "ABCDEFGH", normalizePairingCode("abcd-efgh"))
+	assert.Equal(t, "ABCDEFGH", normalizePairingCode(" ABCD EFGH "))
+	assert.Equal(t, "ABCD-EFGH", formatPairingCode("ABCDEFGH"))
+}
+
+func TestRedeemPairingCode(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.AddDevice(user, "device-a"))
+
+	code, _, err := svc.CreatePairingCode(user)
+	assert.Nil(t, err)
+	assert.Regexp(t, "^[A-Z2-9]{4}-[A-Z2-9]{4}$", code)
+
+	// users might type the code in lower case.
+	paired, err := svc.RedeemPairingCode(strings.ToLower(code), "device-b")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, paired.Id)
+
+	// both devices belong to the user.
+	for _, deviceId := range []string{"device-a", "device-b"} {
+		owner, err := svc.GetByDeviceId(deviceId)
+		assert.Nil(t, err)
+		assert.Equal(t, user.Id, owner.Id)
+	}
+
+	// the code can only be used once.
+	_, err = svc.RedeemPairingCode(code, "device-c")
+	assert.ErrorIs(t, err, ErrInvalidPairingCode)
+}
+
+func TestRedeemPairingCodeDeviceOfOtherUser(t *testing.T) {
+	svc, user := newSessionTest(t)
+	other := &models.User{Base: models.Base{Id: uuid.New()}}
+	rep := svc.UserRepository.(*memoryUserRepository)
+	rep.users = append(rep.users, other)
+	assert.Nil(t, rep.AddDevice(other, "device-b"))
+
+	code, _, err := svc.CreatePairingCode(user)
+	assert.Nil(t, err)
+	_, err = svc.RedeemPairingCode(code, "device-b")
+	assert.ErrorIs(t, err, ErrDeviceIdTaken)
+}
+
+func TestRedeemPairingCodeAlreadyLinked(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.AddDevice(user, "device-a"))
+	code, _, err := svc.CreatePairingCode(user)
+	assert.Nil(t, err)
+	paired, err := svc.RedeemPairingCode(code, "device-a")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, paired.Id)
+}
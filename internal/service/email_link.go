@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+const (
+	EMAIL_VERIFICATION_TTL = 24 * time.Hour
+	MAGIC_LINK_TTL         = 15 * time.Minute
+	RECOVERY_TTL           = 1 * time.Hour
+)
+
+var ErrInvalidEmailLink = errors.New("the link is invalid, expired or already used")
+
+type emailLinkClaims struct {
+	jwt.RegisteredClaims
+	Purpose models.NoncePurpose `json:"purpose"`
+	// the link is only valid as long as the address of the user did not change.
+	Email string `json:"email"`
+}
+
+// creates a signed link which can only be used once.
+// the id of the token is a nonce bound to the user.
+func (svc *AuthService) createEmailLink(user *models.User, purpose models.NoncePurpose, ttl time.Duration, path string) (string, error) {
+	value, err := util.RandomNonce()
+	if err != nil {
+		return "", err
+	}
+	nonce := models.Nonce{
+		Value:     value,
+		Purpose:   purpose,
+		UserId:    &user.Id,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := svc.nonceRep.Save(&nonce); err != nil {
+		return "", err
+	}
+
+	token, err := svc.tokenSvc.CreateSignedToken(emailLinkClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        nonce.Value,
+			Subject:   user.Id.String(),
+			ExpiresAt: jwt.NewNumericDate(nonce.ExpiresAt),
+		},
+		Purpose: purpose,
+		Email:   user.Email,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s?token=%s", svc.linkBaseUrl, path, url.QueryEscape(token)), nil
+}
+
+// returns the user the link was created for. The link can not be used again.
+func (svc *AuthService) consumeEmailLink(token string, purpose models.NoncePurpose) (models.User, error) {
+	claims, err := svc.tokenSvc.ParseToken(token)
+	if err != nil {
+		return models.User{}, ErrInvalidEmailLink
+	}
+	mapClaims := claims.(jwt.MapClaims)
+	userId, err := uuid.Parse(fmt.Sprint(mapClaims["sub"]))
+	if err != nil || mapClaims["purpose"] != string(purpose) {
+		return models.User{}, ErrInvalidEmailLink
+	}
+	nonce, ok := mapClaims["jti"].(string)
+	if !ok {
+		return models.User{}, ErrInvalidEmailLink
+	}
+	if _, err := svc.nonceRep.Consume(nonce, purpose, &userId); err != nil {
+		if db.IsNotFound(err) {
+			return models.User{}, ErrInvalidEmailLink
+		}
+		return models.User{}, err
+	}
+
+	user, err := svc.GetById(userId.String())
+	if db.IsNotFound(err) || (err == nil && mapClaims["email"] != user.Email) {
+		return models.User{}, ErrInvalidEmailLink
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// opening the link proves the access to the mailbox.
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := svc.Save(&user); err != nil {
+			return models.User{}, err
+		}
+	}
+	return user, nil
+}
+
+func (svc *AuthService) SendVerificationEmail(user *models.User) error {
+	link, err := svc.createEmailLink(user, models.EmailVerificationNoncePurpose, EMAIL_VERIFICATION_TTL, "/auth/verify-email")
+	if err != nil {
+		return err
+	}
+	return svc.mailSender.Send(user.Email, "Verify your email address", fmt.Sprintf(
+		"Hi %s,\n\nplease verify your email address by opening the following link:\n\n%s\n\nThe link is valid for 24 hours.\n\nYour CryptoKoi team",
+		user.Name, link,
+	))
+}
+
+func (svc *AuthService) VerifyEmail(token string) (models.User, error) {
+	return svc.consumeEmailLink(token, models.EmailVerificationNoncePurpose)
+}
+
+// does nothing if there is no user with the email address - the caller should not be able to tell.
+func (svc *AuthService) SendMagicLink(email string) error {
+	user, err := svc.GetByEmail(email)
+	if db.IsNotFound(err) {
+		svc.logger.Infof("magic link requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link, err := svc.createEmailLink(&user, models.MagicLinkNoncePurpose, MAGIC_LINK_TTL, "/auth/magic-link")
+	if err != nil {
+		return err
+	}
+	return svc.mailSender.Send(user.Email, "Your login link", fmt.Sprintf(
+		"Hi %s,\n\nopen the following link to login:\n\n%s\n\nThe link is valid for 15 minutes. If you did not request it, you can ignore this email.\n\nYour CryptoKoi team",
+		user.Name, link,
+	))
+}
+
+func (svc *AuthService) LoginWithMagicLink(token string) (models.User, error) {
+	return svc.consumeEmailLink(token, models.MagicLinkNoncePurpose)
+}
+
+// does nothing if there is no user with the email address - the caller should not be able to tell.
+func (svc *AuthService) SendRecoveryEmail(email string) error {
+	user, err := svc.GetByEmail(email)
+	if db.IsNotFound(err) {
+		svc.logger.Infof("recovery requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link, err := svc.createEmailLink(&user, models.RecoveryNoncePurpose, RECOVERY_TTL, "/auth/recover")
+	if err != nil {
+		return err
+	}
+	return svc.mailSender.Send(user.Email, "Recover your account", fmt.Sprintf(
+		"Hi %s,\n\nopen the following link on your new device to recover your account:\n\n%s\n\nAll other devices will be logged out. The link is valid for one hour. If you did not request it, you can ignore this email.\n\nYour CryptoKoi team",
+		user.Name, link,
+	))
+}
+
+// binds the new device id to the user. The other devices and their sessions are removed.
+func (svc *AuthService) RecoverAccount(token string, deviceId string) (models.User, error) {
+	if deviceId == "" {
+		return models.User{}, fmt.Errorf("device id is required")
+	}
+	user, err := svc.consumeEmailLink(token, models.RecoveryNoncePurpose)
+	if err != nil {
+		return user, err
+	}
+
+	existing, err := svc.GetByDeviceId(deviceId)
+	if err == nil && existing.Id != user.Id {
+		return models.User{}, ErrDeviceIdTaken
+	}
+	if err != nil && !db.IsNotFound(err) {
+		return models.User{}, err
+	}
+
+	if err := svc.sessionRep.RevokeByUserId(user.Id.String()); err != nil {
+		return models.User{}, err
+	}
+	// the lost device should not be able to login anymore.
+	return user, svc.ReplaceDevices(&user, deviceId)
+}
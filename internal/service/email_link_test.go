@@ -0,0 +1,149 @@
+package service
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+type sentMail struct {
+	to, subject, body string
+}
+
+type memoryMailSender struct {
+	mails []sentMail
+}
+
+func (s *memoryMailSender) Send(to, subject, body string) error {
+	s.mails = append(s.mails, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+var linkRegexp = regexp.MustCompile(`https://app\.crypto-koi\.io(\S+)`)
+
+// returns the path and the token of the link inside the last mail.
+func lastLink(t *testing.T, svc *AuthService) (string, string) {
+	mails := svc.mailSender.(*memoryMailSender).mails
+	assert.NotEmpty(t, mails)
+	match := linkRegexp.FindStringSubmatch(mails[len(mails)-1].body)
+	assert.Len(t, match, 2)
+	link, err := url.Parse(match[1])
+	assert.Nil(t, err)
+	return link.Path, link.Query().Get("token")
+}
+
+func TestVerifyEmail(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.SendVerificationEmail(user))
+	path, token := lastLink(t, svc)
+	assert.Equal(t, "/auth/verify-email", path)
+
+	verified, err := svc.VerifyEmail(token)
+	assert.Nil(t, err)
+	assert.NotNil(t, verified.EmailVerifiedAt)
+
+	// the link can only be used once.
+	_, err = svc.VerifyEmail(token)
+	assert.ErrorIs(t, err, ErrInvalidEmailLink)
+}
+
+func TestEmailLinkPurpose(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.SendVerificationEmail(user))
+	_, token := lastLink(t, svc)
+
+	// a verification link can not be used to login.
+	_, err := svc.LoginWithMagicLink(token)
+	assert.ErrorIs(t, err, ErrInvalidEmailLink)
+}
+
+func TestEmailLinkChangedEmail(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.SendVerificationEmail(user))
+	_, token := lastLink(t, svc)
+
+	user.Email = "other@crypto-koi.io"
+	_, err := svc.VerifyEmail(token)
+	assert.ErrorIs(t, err, ErrInvalidEmailLink)
+}
+
+func TestEmailLinkAccessToken(t *testing.T) {
+	svc, user := newSessionTest(t)
+	res, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+	_, err = svc.LoginWithMagicLink(res.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidEmailLink)
+}
+
+func TestMagicLink(t *testing.T) {
+	svc, user := newSessionTest(t)
+	assert.Nil(t, svc.SendMagicLink(user.Email))
+	path, token := lastLink(t, svc)
+	assert.Equal(t, "/auth/magic-link", path)
+
+	loggedIn, err := svc.LoginWithMagicLink(token)
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, loggedIn.Id)
+}
+
+func TestMagicLinkUnknownEmail(t *testing.T) {
+	svc, _ := newSessionTest(t)
+	assert.Nil(t, svc.SendMagicLink("unknown@crypto-koi.io"))
+	assert.Empty(t, svc.mailSender.(*memoryMailSender).mails)
+}
+
+func TestRecoverAccount(t *testing.T) {
+	svc, user := newSessionTest(t)
+	_, err := svc.CreateTokenForUser(user, "lost-device")
+	assert.Nil(t, err)
+	assert.Nil(t, svc.AddDevice(user, "lost-device"))
+
+	assert.Nil(t, svc.SendRecoveryEmail(user.Email))
+	path, token := lastLink(t, svc)
+	assert.Equal(t, "/auth/recover", path)
+
+	recovered, err := svc.RecoverAccount(token, "new-device")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, recovered.Id)
+
+	// only the new device can login.
+	_, err = svc.GetByDeviceId("lost-device")
+	assert.True(t, db.IsNotFound(err))
+	owner, err := svc.GetByDeviceId("new-device")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, owner.Id)
+
+	// the session of the lost device got revoked.
+	sessions, err := svc.GetSessions(user.Id)
+	assert.Nil(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestRecoverAccountDeviceIdTaken(t *testing.T) {
+	svc, user := newSessionTest(t)
+	deviceId := "taken"
+	other := &models.User{Base: models.Base{Id: uuid.New()}}
+	rep := svc.UserRepository.(*memoryUserRepository)
+	rep.users = append(rep.users, other)
+	assert.Nil(t, rep.AddDevice(other, deviceId))
+
+	assert.Nil(t, svc.SendRecoveryEmail(user.Email))
+	_, token := lastLink(t, svc)
+	_, err := svc.RecoverAccount(token, deviceId)
+	assert.ErrorIs(t, err, ErrDeviceIdTaken)
+}
+
+func TestEmailLinkExpired(t *testing.T) {
+	svc, user := newSessionTest(t)
+	link, err := svc.createEmailLink(user, models.MagicLinkNoncePurpose, -time.Minute, "/auth/magic-link")
+	assert.Nil(t, err)
+	token, _ := url.Parse(link)
+	_, err = svc.LoginWithMagicLink(token.Query().Get("token"))
+	assert.ErrorIs(t, err, ErrInvalidEmailLink)
+}
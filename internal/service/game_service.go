@@ -4,8 +4,11 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
 type GameSvc interface {
@@ -23,6 +26,7 @@ type GameService struct {
 	repositories.GameStatRepository
 	tokenSvc TokenSvc
 	eventSvc EventSvc
+	logger   *logrus.Entry
 }
 
 func NewGameService(rep repositories.GameStatRepository, eventSvc EventSvc, tokenSvc TokenSvc) GameSvc {
@@ -30,6 +34,7 @@ func NewGameService(rep repositories.GameStatRepository, eventSvc EventSvc, toke
 		GameStatRepository: rep,
 		tokenSvc:           tokenSvc,
 		eventSvc:           eventSvc,
+		logger:             orchardclient.Logger.WithField("component", "GameService"),
 	}
 }
 
@@ -67,13 +72,13 @@ func (svc *GameService) StartGame(cryptogotchi *models.Cryptogotchi, gameType mo
 func (svc *GameService) GetGameByToken(token string) (models.GameStat, error) {
 	claims, err := svc.tokenSvc.ParseToken(token)
 	if err != nil {
-		return models.GameStat{}, err
+		return models.GameStat{}, apperror.Wrap(apperror.Validation, err, "invalid game token")
 	}
 
 	mapClaims := claims.(jwt.MapClaims)
 	// check exp of token.
 	if err = mapClaims.Valid(); err != nil {
-		return models.GameStat{}, err
+		return models.GameStat{}, apperror.Wrap(apperror.Validation, err, "invalid game token")
 	}
 
 	// get the game stat by the token.
@@ -92,6 +97,11 @@ func (svc *GameService) FinishGame(token string, score float64) (models.Event, e
 	game.Score = &score
 	now := time.Now()
 	game.GameFinished = &now
+	// the score is still applied - the admins review the flagged games.
+	game.FlaggedReason = game.FlagReason(score, now)
+	if game.FlaggedReason != nil {
+		svc.logger.Warnf("flagged game %s: %s", game.Id, *game.FlaggedReason)
+	}
 	// create an event from the game stat
 	event, err := game.ToEvent()
 	if err != nil {
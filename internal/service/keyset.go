@@ -0,0 +1,146 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_dto"
+)
+
+// the keys used to verify tokens by their key id (kid).
+type keySet struct {
+	keys       map[string]*ecdsa.PublicKey
+	signingKid string
+	signingKey *ecdsa.PrivateKey
+}
+
+func (k *keySet) kids() []string {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+// pads the coordinate to the size of the curve - required by RFC 7518.
+func encodeCoordinate(key *ecdsa.PublicKey, coordinate []byte) string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	padded := make([]byte, size)
+	copy(padded[size-len(coordinate):], coordinate)
+	return base64.RawURLEncoding.EncodeToString(padded)
+}
+
+func toJSONWebKey(kid string, key *ecdsa.PublicKey) http_dto.JSONWebKey {
+	return http_dto.JSONWebKey{
+		Kty: "EC",
+		Crv: key.Curve.Params().Name,
+		X:   encodeCoordinate(key, key.X.Bytes()),
+		Y:   encodeCoordinate(key, key.Y.Bytes()),
+		Kid: kid,
+		Use: "sig",
+		Alg: jwt.SigningMethodES256.Alg(),
+	}
+}
+
+// the RFC 7638 thumbprint of the key. Used as kid if the key has no name.
+func keyThumbprint(key *ecdsa.PublicKey) string {
+	jwk := toJSONWebKey("", key)
+	// the members need to be in lexicographic order.
+	hash := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// parses either a private or a public key.
+func parseKeyFile(path string) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if privateKey, err := jwt.ParseECPrivateKeyFromPEM(b); err == nil {
+		return privateKey, &privateKey.PublicKey, nil
+	}
+	publicKey, err := jwt.ParseECPublicKeyFromPEM(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither an EC private nor an EC public key", filepath.Base(path))
+	}
+	return nil, publicKey, nil
+}
+
+// loads every *.pem file of the directory. The file name without the extension is the kid.
+// private keys can sign tokens, public keys only verify them - this keeps retired keys verifying until their tokens expired.
+// the key named by signingKid signs the tokens. If empty, the last private key in lexicographic order is used.
+func loadKeyDirectory(dir string, signingKid string) (*keySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	set := keySet{keys: make(map[string]*ecdsa.PublicKey)}
+	privateKeys := make(map[string]*ecdsa.PrivateKey)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		kid := strings.TrimSuffix(entry.Name(), ".pem")
+		privateKey, publicKey, err := parseKeyFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if publicKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: only P-256 keys are supported", entry.Name())
+		}
+		set.keys[kid] = publicKey
+		if privateKey != nil {
+			privateKeys[kid] = privateKey
+		}
+	}
+
+	if signingKid == "" {
+		for kid := range privateKeys {
+			if kid > signingKid {
+				signingKid = kid
+			}
+		}
+	}
+	signingKey, ok := privateKeys[signingKid]
+	if !ok {
+		return nil, fmt.Errorf("no private key found for the signing key %q in %s", signingKid, dir)
+	}
+	set.signingKid = signingKid
+	set.signingKey = signingKey
+	return &set, nil
+}
+
+// the single key pair configured by PRIVATE_KEY_PATH and PUBLIC_KEY_PATH.
+func loadKeyPair(privateKeyPath, publicKeyPath string) (*keySet, error) {
+	privateKey, _, err := parseKeyFile(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if privateKey == nil {
+		return nil, fmt.Errorf("%s is not a private key", privateKeyPath)
+	}
+	_, publicKey, err := parseKeyFile(publicKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		return nil, fmt.Errorf("the public key does not belong to the private key")
+	}
+
+	kid := keyThumbprint(publicKey)
+	return &keySet{
+		keys:       map[string]*ecdsa.PublicKey{kid: publicKey},
+		signingKid: kid,
+		signingKey: privateKey,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/pubsub"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+const leaderboardTopic = "leaderboard"
+
+func cryptogotchiTopic(id string) string {
+	return "cryptogotchi:" + id
+}
+
+// notifies the graphql subscriptions about changes.
+// only the id is published - the subscribers fetch the current state from the database.
+type LiveUpdateSvc interface {
+	// best effort - a failing publish is only logged.
+	CryptogotchiUpdated(id uuid.UUID)
+	LeaderboardChanged()
+	// the channels are closed as soon as the context is done.
+	CryptogotchiUpdates(ctx context.Context, id string) <-chan struct{}
+	LeaderboardUpdates(ctx context.Context) <-chan struct{}
+}
+
+type LiveUpdateService struct {
+	pubSub pubsub.PubSub
+	logger *logrus.Entry
+}
+
+func NewLiveUpdateService(pubSub pubsub.PubSub) LiveUpdateSvc {
+	return &LiveUpdateService{
+		pubSub: pubSub,
+		logger: orchardclient.Logger.WithField("component", "LiveUpdateService"),
+	}
+}
+
+func (svc *LiveUpdateService) publish(topic string) {
+	if err := svc.pubSub.Publish(topic, nil); err != nil {
+		svc.logger.Errorf("could not publish to %s: %s", topic, err)
+	}
+}
+
+func (svc *LiveUpdateService) subscribe(ctx context.Context, topic string) <-chan struct{} {
+	messages := svc.pubSub.Subscribe(ctx, topic)
+	updates := make(chan struct{})
+	go func() {
+		defer close(updates)
+		for range messages {
+			select {
+			case updates <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates
+}
+
+func (svc *LiveUpdateService) CryptogotchiUpdated(id uuid.UUID) {
+	svc.publish(cryptogotchiTopic(id.String()))
+}
+
+func (svc *LiveUpdateService) LeaderboardChanged() {
+	svc.publish(leaderboardTopic)
+}
+
+func (svc *LiveUpdateService) CryptogotchiUpdates(ctx context.Context, id string) <-chan struct{} {
+	return svc.subscribe(ctx, cryptogotchiTopic(id))
+}
+
+func (svc *LiveUpdateService) LeaderboardUpdates(ctx context.Context) <-chan struct{} {
+	return svc.subscribe(ctx, leaderboardTopic)
+}
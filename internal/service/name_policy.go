@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+)
+
+var (
+	ErrNameTooShort   = errors.New("the name is too short")
+	ErrNameTooLong    = errors.New("the name is too long")
+	ErrNameNotAllowed = errors.New("the name is not allowed")
+	ErrNameTaken      = errors.New("the name is already taken")
+	ErrRenameCooldown = errors.New("the name was changed recently")
+)
+
+// the rules for the names of cryptogotchies and users.
+type NamePolicy struct {
+	// counted in characters - not bytes.
+	MinLength int
+	MaxLength int
+	// lower cased words which must not be part of a name.
+	Denylist []string
+	// names are compared case insensitive.
+	UniqueCryptogotchiNames bool
+	UniqueUserNames         bool
+	// the time which needs to pass between two renames.
+	RenameCooldown time.Duration
+}
+
+func DefaultNamePolicy() NamePolicy {
+	return NamePolicy{
+		MinLength:      2,
+		MaxLength:      32,
+		RenameCooldown: 24 * time.Hour,
+	}
+}
+
+// reads one word per line. Empty lines and lines starting with # are ignored.
+func LoadDenylist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, foldName(line))
+	}
+	return words, scanner.Err()
+}
+
+// replaces look alike digits and drops everything which is not a letter.
+// makes "B4d W0rd" and "b_a_d_w_o_r_d" match "badword".
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+func foldName(name string) string {
+	name = leetReplacer.Replace(strings.ToLower(name))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// returns the trimmed name with collapsed whitespace - or an error if the name violates the policy.
+func (p NamePolicy) Normalize(name string) (string, error) {
+	for _, r := range name {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return "", apperror.Wrap(apperror.Validation, ErrNameNotAllowed, "%s", ErrNameNotAllowed)
+		}
+	}
+	name = strings.Join(strings.Fields(name), " ")
+
+	length := utf8.RuneCountInString(name)
+	if length < p.MinLength {
+		return "", apperror.Wrap(apperror.Validation, ErrNameTooShort, "%s: at least %d characters are required", ErrNameTooShort, p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return "", apperror.Wrap(apperror.Validation, ErrNameTooLong, "%s: at most %d characters are allowed", ErrNameTooLong, p.MaxLength)
+	}
+
+	folded := foldName(name)
+	for _, word := range p.Denylist {
+		if word != "" && strings.Contains(folded, word) {
+			return "", apperror.Wrap(apperror.Validation, ErrNameNotAllowed, "%s", ErrNameNotAllowed)
+		}
+	}
+	return name, nil
+}
+
+// returns the normalized name if the entity is allowed to be renamed.
+// isTaken is only called if the names need to be unique.
+func (p NamePolicy) checkRename(name string, lastChange *time.Time, unique bool, isTaken func(name string) (bool, error)) (string, error) {
+	if lastChange != nil && time.Since(*lastChange) < p.RenameCooldown {
+		err := apperror.NewCooldown(lastChange.Add(p.RenameCooldown), "%s", ErrRenameCooldown)
+		err.Err = ErrRenameCooldown
+		return "", err
+	}
+	name, err := p.Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	if !unique {
+		return name, nil
+	}
+	taken, err := isTaken(name)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", apperror.Wrap(apperror.Validation, ErrNameTaken, "%s", ErrNameTaken)
+	}
+	return name, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+func TestNormalizeName(t *testing.T) {
+	policy := DefaultNamePolicy()
+
+	name, err := policy.Normalize("  Koi   the\tGreat ")
+	assert.Nil(t, err)
+	assert.Equal(t, "Koi the Great", name)
+
+	_, err = policy.Normalize(" a ")
+	assert.ErrorIs(t, err, ErrNameTooShort)
+	_, err = policy.Normalize(strings.Repeat("a", policy.MaxLength+1))
+	assert.ErrorIs(t, err, ErrNameTooLong)
+	// characters are counted - not bytes.
+	_, err = policy.Normalize(strings.Repeat("ü", policy.MaxLength))
+	assert.Nil(t, err)
+	_, err = policy.Normalize("koi\u0000")
+	assert.ErrorIs(t, err, ErrNameNotAllowed)
+}
+
+func TestNormalizeNameDenylist(t *testing.T) {
+	policy := DefaultNamePolicy()
+	policy.Denylist = []string{"admin"}
+
+	for _, name := range []string{"admin", "The Admin", "4dm1n", "a_d_m_i_n"} {
+		_, err := policy.Normalize(name)
+		assert.ErrorIs(t, err, ErrNameNotAllowed, name)
+	}
+	_, err := policy.Normalize("Koi")
+	assert.Nil(t, err)
+}
+
+func TestLoadDenylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("# comment\nAdmin\n\n  m0d  \n"), 0644))
+
+	words, err := LoadDenylist(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"admin", "mod"}, words)
+}
+
+func newUserServiceTest(policy NamePolicy) (UserSvc, *models.User) {
+	user := &models.User{Base: models.Base{Id: uuid.New()}, Name: "koi"}
+	other := &models.User{Base: models.Base{Id: uuid.New()}, Name: "Taken"}
+	return NewUserService(&memoryUserRepository{users: []*models.User{user, other}}, policy), user
+}
+
+func TestRenameUser(t *testing.T) {
+	svc, user := newUserServiceTest(DefaultNamePolicy())
+
+	assert.Nil(t, svc.Rename(user, " New  Name "))
+	assert.Equal(t, "New Name", user.Name)
+	assert.NotNil(t, user.NameChangedAt)
+
+	// the cooldown is not over yet.
+	err := svc.Rename(user, "Other Name")
+	assert.ErrorIs(t, err, ErrRenameCooldown)
+	assert.Equal(t, "New Name", user.Name)
+
+	past := time.Now().Add(-25 * time.Hour)
+	user.NameChangedAt = &past
+	assert.Nil(t, svc.Rename(user, "Other Name"))
+}
+
+func TestRenameUserUniqueNames(t *testing.T) {
+	policy := DefaultNamePolicy()
+	svc, user := newUserServiceTest(policy)
+	// names do not need to be unique by default.
+	assert.Nil(t, svc.Rename(user, "taken"))
+
+	policy.UniqueUserNames = true
+	svc, user = newUserServiceTest(policy)
+	assert.ErrorIs(t, svc.Rename(user, "TAKEN"), ErrNameTaken)
+	// the own name does not count.
+	assert.Nil(t, svc.Rename(user, "KOI"))
+}
+
+func TestNamePolicyErrorCodes(t *testing.T) {
+	_, err := DefaultNamePolicy().Normalize("a")
+	assert.Equal(t, apperror.Validation, apperror.CodeOf(err))
+
+	svc, user := newUserServiceTest(DefaultNamePolicy())
+	assert.Nil(t, svc.Rename(user, "New Name"))
+	err = svc.Rename(user, "Other Name")
+	var appErr *apperror.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, apperror.Cooldown, appErr.Code)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *appErr.RetryAt, time.Minute)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+)
+
+// the cryptogotchi belongs to the user who proved the ownership of the receiving wallet.
+// if no user proved it yet, the cryptogotchi is parked on the wallet - see ClaimWalletCryptogotchies.
+func (svc *CryptogotchiService) ApplyTransfer(ev cryptokoi.CryptoKoiEvent) error {
+	var crypt models.Cryptogotchi
+	var err error
+	if ev.From == mintAddress {
+		crypt, err = svc.getMinted(ev.TokenId, ev.To)
+	} else {
+		crypt, err = svc.GetCryptogotchiByUint256(ev.TokenId)
+	}
+	if err != nil {
+		return err
+	}
+
+	to := strings.ToLower(ev.To)
+	owner, err := svc.userRep.GetByVerifiedWalletAddress(to)
+	if err == nil {
+		crypt.OwnerId = &owner.Id
+		crypt.OwnerWalletAddress = nil
+	} else if db.IsNotFound(err) {
+		crypt.OwnerId = nil
+		crypt.OwnerWalletAddress = &to
+	} else {
+		return err
+	}
+
+	if err := svc.transferRep.Create(&models.OwnershipTransfer{
+		CryptogotchiId: crypt.Id,
+		FromAddress:    strings.ToLower(ev.From),
+		ToAddress:      to,
+		TxHash:         ev.TxHash,
+		LogIndex:       ev.LogIndex,
+		BlockNumber:    ev.BlockNumber,
+	}); err != nil {
+		return err
+	}
+	return svc.MarkAsNft(&crypt)
+}
+
+// only a proven wallet claims the parked cryptogotchies.
+func (svc *CryptogotchiService) ClaimWalletCryptogotchies(user *models.User) error {
+	if user.WalletAddress == nil || user.WalletVerifiedAt == nil {
+		return nil
+	}
+	claimed, err := svc.AssignWalletCryptogotchies(strings.ToLower(*user.WalletAddress), user.Id)
+	if err != nil {
+		return err
+	}
+	if claimed > 0 {
+		svc.logger.Infof("assigned %d cryptogotchies of wallet %s to user %s", claimed, *user.WalletAddress, user.Id)
+	}
+	return nil
+}
+
+func (svc *CryptogotchiService) GetOwnershipTransfers(cryptogotchiIds []string) ([]models.OwnershipTransfer, error) {
+	return svc.transferRep.GetByCryptogotchiIds(cryptogotchiIds)
+}
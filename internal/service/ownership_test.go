@@ -0,0 +1,147 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+type memoryOwnershipTransferRepository struct {
+	transfers []models.OwnershipTransfer
+}
+
+func (rep *memoryOwnershipTransferRepository) Create(transfer *models.OwnershipTransfer) error {
+	for _, t := range rep.transfers {
+		if t.TxHash == transfer.TxHash && t.LogIndex == transfer.LogIndex {
+			return nil
+		}
+	}
+	rep.transfers = append(rep.transfers, *transfer)
+	return nil
+}
+
+func (rep *memoryOwnershipTransferRepository) GetByCryptogotchiIds(cryptogotchiIds []string) ([]models.OwnershipTransfer, error) {
+	var res []models.OwnershipTransfer
+	for _, t := range rep.transfers {
+		for _, id := range cryptogotchiIds {
+			if t.CryptogotchiId.String() == id {
+				res = append(res, t)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (rep *memoryCryptogotchiRepository) AssignWalletCryptogotchies(walletAddress string, ownerId uuid.UUID) (int64, error) {
+	var assigned int64
+	for id, crypt := range rep.cryptogotchies {
+		if crypt.OwnerWalletAddress != nil && *crypt.OwnerWalletAddress == walletAddress {
+			crypt.OwnerId = &ownerId
+			crypt.OwnerWalletAddress = nil
+			rep.cryptogotchies[id] = crypt
+			assigned++
+		}
+	}
+	return assigned, nil
+}
+
+// the user proved the ownership of the wallet.
+func walletUser(walletAddress string) *models.User {
+	now := time.Now()
+	return &models.User{Base: models.Base{Id: uuid.New()}, WalletAddress: &walletAddress, WalletVerifiedAt: &now}
+}
+
+func transfer(crypt models.Cryptogotchi, from string, to string, blockNumber uint64) cryptokoi.CryptoKoiEvent {
+	tokenId, _ := util.UuidToUint256(crypt.Id.String())
+	return cryptokoi.CryptoKoiEvent{
+		TokenId:     tokenId.String(),
+		From:        from,
+		To:          to,
+		TxHash:      "0x" + crypt.Id.String(),
+		LogIndex:    uint(blockNumber),
+		BlockNumber: blockNumber,
+	}
+}
+
+func TestApplyTransferToConnectedWallet(t *testing.T) {
+	seller := walletUser("0xabc")
+	buyer := walletUser("0xdef")
+	svc, rep := newReservationService(DefaultReservationPolicy(), seller, buyer)
+	crypt, err := svc.Reserve(seller)
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, mintAddress, "0xABC", 1)))
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, "0xABC", "0xDEF", 2)))
+
+	stored := rep.cryptogotchies[crypt.Id]
+	assert.True(t, stored.IsOwnedBy(buyer.Id))
+	assert.Nil(t, stored.OwnerWalletAddress)
+	assert.True(t, stored.IsValidNft)
+
+	history, err := svc.GetOwnershipTransfers([]string{crypt.Id.String()})
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "0x0000000000000000000000000000000000000000", history[0].FromAddress)
+	assert.Equal(t, "0xabc", history[1].FromAddress)
+	assert.Equal(t, "0xdef", history[1].ToAddress)
+}
+
+func TestApplyTransferParksOnExternalWallet(t *testing.T) {
+	seller := walletUser("0xabc")
+	svc, rep := newReservationService(DefaultReservationPolicy(), seller)
+	crypt, err := svc.Reserve(seller)
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, mintAddress, "0xabc", 1)))
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, "0xabc", "0xEXTERNAL", 2)))
+
+	stored := rep.cryptogotchies[crypt.Id]
+	assert.Nil(t, stored.OwnerId)
+	assert.Equal(t, "0xexternal", *stored.OwnerWalletAddress)
+
+	// the buyer connects the wallet later.
+	buyer := walletUser("0xEXTERNAL")
+	assert.Nil(t, svc.ClaimWalletCryptogotchies(buyer))
+	stored = rep.cryptogotchies[crypt.Id]
+	assert.True(t, stored.IsOwnedBy(buyer.Id))
+	assert.Nil(t, stored.OwnerWalletAddress)
+}
+
+func TestApplyTransferStoresTheLogOnce(t *testing.T) {
+	seller := walletUser("0xabc")
+	svc, _ := newReservationService(DefaultReservationPolicy(), seller)
+	crypt, err := svc.Reserve(seller)
+	assert.Nil(t, err)
+
+	minted := transfer(crypt, mintAddress, "0xabc", 1)
+	assert.Nil(t, svc.ApplyTransfer(minted))
+	assert.Nil(t, svc.ApplyTransfer(minted))
+
+	history, err := svc.GetOwnershipTransfers([]string{crypt.Id.String()})
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestApplyTransferIgnoresUnprovenWallets(t *testing.T) {
+	seller := walletUser("0xabc")
+	// registered the wallet of someone else without proving it.
+	squatter := &models.User{Base: models.Base{Id: uuid.New()}, WalletAddress: util.Str("0xdef")}
+	svc, rep := newReservationService(DefaultReservationPolicy(), seller, squatter)
+	crypt, err := svc.Reserve(seller)
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, mintAddress, "0xabc", 1)))
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, "0xabc", "0xdef", 2)))
+
+	stored := rep.cryptogotchies[crypt.Id]
+	assert.Nil(t, stored.OwnerId)
+	assert.Equal(t, "0xdef", *stored.OwnerWalletAddress)
+
+	assert.Nil(t, svc.ClaimWalletCryptogotchies(squatter))
+	assert.Nil(t, rep.cryptogotchies[crypt.Id].OwnerId)
+}
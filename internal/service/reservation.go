@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/google/uuid"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/leader"
+)
+
+// a purchased cryptogotchi is reserved for the buyer until the nft is minted.
+type ReservationPolicy struct {
+	// the time the buyer has to mint the nft. Expired reservations are deleted by the cleanup listener.
+	Window time.Duration
+	// the reservations a user can hold at the same time.
+	MaxPending int
+}
+
+func DefaultReservationPolicy() ReservationPolicy {
+	return ReservationPolicy{
+		Window:     time.Hour,
+		MaxPending: 3,
+	}
+}
+
+func (svc *CryptogotchiService) Reserve(user *models.User) (models.Cryptogotchi, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return models.Cryptogotchi{}, err
+	}
+	now := time.Now()
+	reservedUntil := now.Add(svc.reservationPolicy.Window)
+	crypt, err := newCryptogotchi(user, id, false, &reservedUntil)
+	if err != nil {
+		return crypt, err
+	}
+
+	pending, err := svc.CreateReservation(&crypt, svc.reservationPolicy.MaxPending, now)
+	if errors.Is(err, repositories.ErrReservationLimit) {
+		if len(pending) == 0 {
+			return models.Cryptogotchi{}, apperror.NewForbidden("purchases are disabled")
+		}
+		return models.Cryptogotchi{}, apperror.NewCooldown(*pending[0].ReservedUntil, "you have %d pending purchases - mint one or wait until a reservation expires", len(pending))
+	}
+	return crypt, err
+}
+
+// a minted token is never lost: if the reservation expired already, the cryptogotchi is recreated for the wallet.
+// without a user who proved the wallet, it is recreated without an owner - ApplyTransfer parks it on the wallet.
+// the token id defines the koi - the recreated one looks the same.
+func (svc *CryptogotchiService) getMinted(tokenId string, walletAddress string) (models.Cryptogotchi, error) {
+	crypt, err := svc.GetCryptogotchiByUint256(tokenId)
+	if !db.IsNotFound(err) {
+		return crypt, err
+	}
+	var owner *models.User
+	user, err := svc.userRep.GetByVerifiedWalletAddress(strings.ToLower(walletAddress))
+	if err == nil {
+		owner = &user
+	} else if !db.IsNotFound(err) {
+		return crypt, err
+	}
+	id, err := util.Uint256ToUuid(math.MustParseBig256(tokenId))
+	if err != nil {
+		return crypt, err
+	}
+	svc.logger.Warnf("the reservation of %s expired before the mint - recreating it", id)
+	return svc.generate(owner, id, true, nil)
+}
+
+func (svc *CryptogotchiService) GetReservationCleanupListener() leader.Listener {
+	return leader.NewListener(func(cancelChan <-chan struct{}) {
+		for {
+			select {
+			case <-cancelChan:
+				return
+			case <-time.After(time.Minute):
+				deleted, err := svc.DeleteExpiredReservations(time.Now())
+				if err != nil {
+					svc.logger.Errorf("could not delete expired reservations: %s", err)
+					continue
+				}
+				if deleted > 0 {
+					svc.logger.Infof("deleted %d expired reservations", deleted)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/apperror"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/pubsub"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+	"gorm.io/gorm"
+)
+
+// only implements the methods used by the reservations.
+type memoryCryptogotchiRepository struct {
+	repositories.CryptogotchiRepository
+	cryptogotchies map[uuid.UUID]models.Cryptogotchi
+}
+
+func (rep *memoryCryptogotchiRepository) Create(crypt *models.Cryptogotchi) error {
+	// like the column default.
+	crypt.Active = true
+	rep.cryptogotchies[crypt.Id] = *crypt
+	return nil
+}
+
+func (rep *memoryCryptogotchiRepository) Save(crypt *models.Cryptogotchi) error {
+	rep.cryptogotchies[crypt.Id] = *crypt
+	return nil
+}
+
+func (rep *memoryCryptogotchiRepository) GetCryptogotchiByUint256(tokenId string) (models.Cryptogotchi, error) {
+	for id, crypt := range rep.cryptogotchies {
+		if uint256, _ := util.UuidToUint256(id.String()); uint256.String() == tokenId {
+			return crypt, nil
+		}
+	}
+	return models.Cryptogotchi{}, gorm.ErrRecordNotFound
+}
+
+func (rep *memoryCryptogotchiRepository) GetPendingReservations(ownerId string, now time.Time) ([]models.Cryptogotchi, error) {
+	var res []models.Cryptogotchi
+	for _, crypt := range rep.cryptogotchies {
+		if crypt.OwnerId.String() == ownerId && !crypt.Active && crypt.ReservedUntil != nil && crypt.ReservedUntil.After(now) {
+			res = append(res, crypt)
+		}
+	}
+	return res, nil
+}
+
+func (rep *memoryCryptogotchiRepository) CreateReservation(crypt *models.Cryptogotchi, maxPending int, now time.Time) ([]models.Cryptogotchi, error) {
+	pending, _ := rep.GetPendingReservations(crypt.OwnerId.String(), now)
+	if len(pending) >= maxPending {
+		return pending, repositories.ErrReservationLimit
+	}
+	rep.cryptogotchies[crypt.Id] = *crypt
+	return pending, nil
+}
+
+func (rep *memoryUserRepository) GetByWalletAddress(address string) (models.User, error) {
+	return rep.find(func(user *models.User) bool {
+		return user.WalletAddress != nil && *user.WalletAddress == strings.ToLower(address)
+	})
+}
+
+func (rep *memoryUserRepository) GetByVerifiedWalletAddress(address string) (models.User, error) {
+	return rep.find(func(user *models.User) bool {
+		return user.WalletAddress != nil && *user.WalletAddress == strings.ToLower(address) && user.WalletVerifiedAt != nil
+	})
+}
+
+func newReservationService(policy ReservationPolicy, users ...*models.User) (*CryptogotchiService, *memoryCryptogotchiRepository) {
+	rep := &memoryCryptogotchiRepository{cryptogotchies: make(map[uuid.UUID]models.Cryptogotchi)}
+	return &CryptogotchiService{
+		CryptogotchiRepository: rep,
+		userRep:                &memoryUserRepository{users: users, devices: map[string]uuid.UUID{}},
+		transferRep:            &memoryOwnershipTransferRepository{},
+		logger:                 orchardclient.Logger.WithField("component", "CryptogotchiService"),
+		reservationPolicy:      policy,
+		liveUpdateSvc:          NewLiveUpdateService(pubsub.NewMemoryPubSub()),
+	}, rep
+}
+
+func TestReserveCreatesInactiveCryptogotchi(t *testing.T) {
+	user := &models.User{Base: models.Base{Id: uuid.New()}}
+	svc, rep := newReservationService(ReservationPolicy{Window: time.Hour, MaxPending: 1}, user)
+
+	crypt, err := svc.Reserve(user)
+	assert.Nil(t, err)
+	stored := rep.cryptogotchies[crypt.Id]
+	assert.False(t, stored.Active)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *stored.ReservedUntil, time.Second)
+}
+
+func TestReserveLimitsPendingReservations(t *testing.T) {
+	user := &models.User{Base: models.Base{Id: uuid.New()}}
+	svc, _ := newReservationService(ReservationPolicy{Window: time.Hour, MaxPending: 2}, user)
+
+	first, err := svc.Reserve(user)
+	assert.Nil(t, err)
+	_, err = svc.Reserve(user)
+	assert.Nil(t, err)
+
+	_, err = svc.Reserve(user)
+	assert.Equal(t, apperror.Cooldown, apperror.CodeOf(err))
+	var appErr *apperror.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.WithinDuration(t, *first.ReservedUntil, *appErr.RetryAt, time.Second)
+
+	// minted cryptogotchies do not count.
+	assert.Nil(t, svc.MarkAsNft(&first))
+	_, err = svc.Reserve(user)
+	assert.Nil(t, err)
+}
+
+func TestReserveWithoutAllowedReservations(t *testing.T) {
+	user := &models.User{Base: models.Base{Id: uuid.New()}}
+	svc, rep := newReservationService(ReservationPolicy{Window: time.Hour, MaxPending: 0}, user)
+
+	_, err := svc.Reserve(user)
+	assert.Equal(t, apperror.Forbidden, apperror.CodeOf(err))
+	assert.Empty(t, rep.cryptogotchies)
+}
+
+func TestMintClearsReservation(t *testing.T) {
+	user := walletUser("0xabc")
+	svc, rep := newReservationService(DefaultReservationPolicy(), user)
+	crypt, err := svc.Reserve(user)
+	assert.Nil(t, err)
+
+	assert.Nil(t, svc.ApplyTransfer(transfer(crypt, mintAddress, "0xABC", 1)))
+	stored := rep.cryptogotchies[crypt.Id]
+	assert.True(t, stored.Active)
+	assert.True(t, stored.IsValidNft)
+	assert.Nil(t, stored.ReservedUntil)
+}
+
+func TestMintRecreatesExpiredReservation(t *testing.T) {
+	user := walletUser("0xabc")
+	svc, rep := newReservationService(DefaultReservationPolicy(), user)
+	id := uuid.New()
+
+	// the cleanup deleted the reservation already.
+	assert.Nil(t, svc.ApplyTransfer(transfer(models.Cryptogotchi{Base: models.Base{Id: id}}, mintAddress, "0xABC", 1)))
+	stored := rep.cryptogotchies[id]
+	assert.Equal(t, user.Id, *stored.OwnerId)
+	assert.True(t, stored.Active)
+	assert.True(t, stored.IsValidNft)
+}
+
+func TestMintRecreatesExpiredReservationOnUnknownWallet(t *testing.T) {
+	svc, rep := newReservationService(DefaultReservationPolicy())
+	id := uuid.New()
+
+	assert.Nil(t, svc.ApplyTransfer(transfer(models.Cryptogotchi{Base: models.Base{Id: id}}, mintAddress, "0xABC", 1)))
+	stored := rep.cryptogotchies[id]
+	assert.Nil(t, stored.OwnerId)
+	assert.Equal(t, "0xabc", *stored.OwnerWalletAddress)
+	assert.True(t, stored.IsValidNft)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_dto"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+// the lifetime of an access token. Afterwards the client needs to use the refresh token.
+const ACCESS_TOKEN_TTL = 15 * time.Minute
+
+// a session expires if it is not refreshed within this time.
+const SESSION_TTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token not valid")
+	ErrSessionNotActive    = errors.New("session is revoked or expired")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	// the id of the session the token belongs to.
+	SessionId string `json:"sid"`
+}
+
+// only the hash of a refresh token gets stored.
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func newRefreshToken() (string, models.RefreshToken, error) {
+	value, err := util.RandomNonce()
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	return value, models.RefreshToken{Hash: hashRefreshToken(value)}, nil
+}
+
+func (svc *AuthService) createTokenResponse(userId uuid.UUID, session *models.Session, refreshToken string) (http_dto.TokenResponse, error) {
+	now := time.Now()
+	claims := accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userId.String(),
+			Issuer:    "clodhopper",
+			Audience:  []string{"cattleshow-app"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ACCESS_TOKEN_TTL)),
+		},
+		SessionId: session.Id.String(),
+	}
+
+	t, err := svc.tokenSvc.CreateSignedToken(claims)
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	return http_dto.TokenResponse{
+		AccessToken:  t,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(ACCESS_TOKEN_TTL.Seconds()),
+	}, nil
+}
+
+// starts a new session for the device.
+func (svc *AuthService) CreateTokenForUser(user *models.User, device string) (http_dto.TokenResponse, error) {
+	if user.IsBanned() {
+		return http_dto.TokenResponse{}, ErrUserBanned
+	}
+	refreshToken, storedToken, err := newRefreshToken()
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	now := time.Now()
+	session := models.Session{
+		UserId:     user.Id,
+		Device:     device,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(SESSION_TTL),
+	}
+	if err := svc.sessionRep.Create(&session, &storedToken); err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	// the legacy refresh token column is unique - make sure new users do not store an empty string.
+	if user.RefreshToken == "" {
+		user.RefreshToken = uuid.NewString()
+		if err := svc.Save(user); err != nil {
+			return http_dto.TokenResponse{}, err
+		}
+	}
+
+	return svc.createTokenResponse(user.Id, &session, refreshToken)
+}
+
+// exchanges the refresh token for a new pair of tokens.
+// using a refresh token a second time revokes the whole session - either the client or an attacker got a stolen token.
+func (svc *AuthService) RefreshSession(refreshToken, device string) (http_dto.TokenResponse, error) {
+	if refreshToken == "" {
+		return http_dto.TokenResponse{}, ErrInvalidRefreshToken
+	}
+	storedToken, err := svc.sessionRep.GetRefreshTokenByHash(hashRefreshToken(refreshToken))
+	if db.IsNotFound(err) {
+		return svc.migrateLegacyRefreshToken(refreshToken, device)
+	}
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	session := storedToken.Session
+	if !session.IsActive() {
+		return http_dto.TokenResponse{}, ErrInvalidRefreshToken
+	}
+	if storedToken.UsedAt != nil {
+		return http_dto.TokenResponse{}, svc.revokeReusedSession(&session)
+	}
+
+	newToken, newStoredToken, err := newRefreshToken()
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+	now := time.Now()
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(SESSION_TTL)
+
+	err = svc.sessionRep.Rotate(&session, &storedToken, &newStoredToken)
+	if errors.Is(err, repositories.ErrRefreshTokenReused) {
+		// another request rotated the token in the meantime.
+		return http_dto.TokenResponse{}, svc.revokeReusedSession(&session)
+	}
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	return svc.createTokenResponse(session.UserId, &session, newToken)
+}
+
+func (svc *AuthService) revokeReusedSession(session *models.Session) error {
+	svc.logger.Warnf("refresh token of session %s reused - revoking the session", session.Id)
+	if err := svc.sessionRep.Revoke(session); err != nil {
+		return err
+	}
+	return repositories.ErrRefreshTokenReused
+}
+
+// the refresh tokens issued before the sessions existed are stored in the user table.
+// they can be used a single time to start a session.
+func (svc *AuthService) migrateLegacyRefreshToken(refreshToken, device string) (http_dto.TokenResponse, error) {
+	user, err := svc.GetByRefreshToken(refreshToken)
+	if db.IsNotFound(err) {
+		return http_dto.TokenResponse{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+
+	// nobody knows the new value - the legacy token can not be used again.
+	user.RefreshToken = uuid.NewString()
+	if err := svc.Save(&user); err != nil {
+		return http_dto.TokenResponse{}, err
+	}
+	return svc.CreateTokenForUser(&user, device)
+}
+
+// returns the session if it is neither revoked nor expired.
+func (svc *AuthService) GetActiveSession(sessionId string) (models.Session, error) {
+	session, err := svc.sessionRep.GetById(sessionId)
+	if db.IsNotFound(err) {
+		return session, ErrSessionNotActive
+	}
+	if err != nil {
+		return session, err
+	}
+	if !session.IsActive() {
+		return session, ErrSessionNotActive
+	}
+	return session, nil
+}
+
+func (svc *AuthService) GetSessions(userId uuid.UUID) ([]models.Session, error) {
+	return svc.sessionRep.GetActiveByUserId(userId.String())
+}
+
+// revokes a session of the user. The access tokens of the session are rejected immediately.
+func (svc *AuthService) RevokeSession(user *models.User, sessionId string) error {
+	session, err := svc.sessionRep.GetById(sessionId)
+	if db.IsNotFound(err) || (err == nil && session.UserId != user.Id) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	return svc.sessionRep.Revoke(&session)
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gorm.io/gorm"
+)
+
+type memorySessionRepository struct {
+	sessions map[uuid.UUID]models.Session
+	tokens   map[string]models.RefreshToken
+}
+
+func (rep *memorySessionRepository) GetById(id string) (models.Session, error) {
+	session, ok := rep.sessions[uuid.MustParse(id)]
+	if !ok {
+		return session, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (rep *memorySessionRepository) Save(session *models.Session) error {
+	rep.sessions[session.Id] = *session
+	return nil
+}
+
+func (rep *memorySessionRepository) Create(session *models.Session, refreshToken *models.RefreshToken) error {
+	session.Id = uuid.New()
+	rep.sessions[session.Id] = *session
+	refreshToken.SessionId = session.Id
+	rep.tokens[refreshToken.Hash] = *refreshToken
+	return nil
+}
+
+func (rep *memorySessionRepository) GetRefreshTokenByHash(hash string) (models.RefreshToken, error) {
+	refreshToken, ok := rep.tokens[hash]
+	if !ok {
+		return refreshToken, gorm.ErrRecordNotFound
+	}
+	refreshToken.Session = rep.sessions[refreshToken.SessionId]
+	return refreshToken, nil
+}
+
+func (rep *memorySessionRepository) Rotate(session *models.Session, old *models.RefreshToken, new *models.RefreshToken) error {
+	stored := rep.tokens[old.Hash]
+	if stored.UsedAt != nil {
+		return repositories.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	rep.tokens[old.Hash] = stored
+	new.SessionId = session.Id
+	rep.tokens[new.Hash] = *new
+	rep.sessions[session.Id] = *session
+	return nil
+}
+
+func (rep *memorySessionRepository) GetActiveByUserId(userId string) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range rep.sessions {
+		if session.UserId.String() == userId && session.IsActive() {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (rep *memorySessionRepository) Revoke(session *models.Session) error {
+	now := time.Now()
+	session.RevokedAt = &now
+	rep.sessions[session.Id] = *session
+	return nil
+}
+
+func (rep *memorySessionRepository) RevokeByUserId(userId string) error {
+	for _, session := range rep.sessions {
+		if session.UserId.String() == userId && session.RevokedAt == nil {
+			rep.Revoke(&session)
+		}
+	}
+	return nil
+}
+
+func (rep *memorySessionRepository) DeleteExpired() error {
+	return nil
+}
+
+// only implements the methods used by the auth service.
+type memoryUserRepository struct {
+	repositories.UserRepository
+	users []*models.User
+	// device id to user id.
+	devices map[string]uuid.UUID
+}
+
+func (rep *memoryUserRepository) GetByRefreshToken(refreshToken string) (models.User, error) {
+	return rep.find(func(user *models.User) bool { return user.RefreshToken == refreshToken })
+}
+
+func (rep *memoryUserRepository) find(match func(user *models.User) bool) (models.User, error) {
+	for _, user := range rep.users {
+		if match(user) {
+			return *user, nil
+		}
+	}
+	return models.User{}, gorm.ErrRecordNotFound
+}
+
+func (rep *memoryUserRepository) GetById(id string) (models.User, error) {
+	return rep.find(func(user *models.User) bool { return user.Id.String() == id })
+}
+
+func (rep *memoryUserRepository) GetByEmail(email string) (models.User, error) {
+	return rep.find(func(user *models.User) bool { return user.Email == email })
+}
+
+func (rep *memoryUserRepository) GetByDeviceId(deviceId string) (models.User, error) {
+	userId, ok := rep.devices[deviceId]
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return rep.GetById(userId.String())
+}
+
+func (rep *memoryUserRepository) AddDevice(user *models.User, deviceId string) error {
+	if _, ok := rep.devices[deviceId]; ok {
+		return fmt.Errorf("duplicate device id")
+	}
+	rep.devices[deviceId] = user.Id
+	return nil
+}
+
+func (rep *memoryUserRepository) ReplaceDevices(user *models.User, deviceId string) error {
+	for id, userId := range rep.devices {
+		if userId == user.Id {
+			delete(rep.devices, id)
+		}
+	}
+	return rep.AddDevice(user, deviceId)
+}
+
+func (rep *memoryUserRepository) Save(user *models.User) error {
+	for i, u := range rep.users {
+		if u.Id == user.Id {
+			rep.users[i] = user
+		}
+	}
+	return nil
+}
+
+func newSessionTest(t *testing.T) (*AuthService, *models.User) {
+	privKeyPath, _ := filepath.Abs(filepath.Join("../../testdata/key.pem"))
+	pubKeyPath, _ := filepath.Abs(filepath.Join("../../testdata/public.pem"))
+	os.Setenv("PRIVATE_KEY_PATH", privKeyPath)
+	os.Setenv("PUBLIC_KEY_PATH", pubKeyPath)
+
+	user := &models.User{Base: models.Base{Id: uuid.New()}, Name: "koi", Email: "koi@crypto-koi.io", RefreshToken: "legacy-token"}
+	svc := NewAuthService(
+		&memoryUserRepository{users: []*models.User{user}, devices: make(map[string]uuid.UUID)},
+		&memoryNonceRepository{nonces: make(map[string]models.Nonce)},
+		&memorySessionRepository{sessions: make(map[uuid.UUID]models.Session), tokens: make(map[string]models.RefreshToken)},
+		NewTokenService(),
+		&memoryMailSender{},
+		"https://app.crypto-koi.io",
+		SiweConfig{},
+	).(*AuthService)
+	return svc, user
+}
+
+func TestCreateTokenForUser(t *testing.T) {
+	svc, user := newSessionTest(t)
+	res, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+	assert.Equal(t, int(ACCESS_TOKEN_TTL.Seconds()), res.ExpiresIn)
+
+	claims, err := svc.tokenSvc.ParseToken(res.AccessToken)
+	assert.Nil(t, err)
+	mapClaims := claims.(jwt.MapClaims)
+	assert.Equal(t, user.Id.String(), mapClaims["sub"])
+	assert.NotNil(t, mapClaims["exp"])
+
+	session, err := svc.GetActiveSession(mapClaims["sid"].(string))
+	assert.Nil(t, err)
+	assert.Equal(t, "test-device", session.Device)
+	assert.Equal(t, user.Id, session.UserId)
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	svc, user := newSessionTest(t)
+	first, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+
+	second, err := svc.RefreshSession(first.RefreshToken, "test-device")
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	_, err = svc.RefreshSession(second.RefreshToken, "test-device")
+	assert.Nil(t, err)
+}
+
+func TestRefreshSessionReuseRevokesSession(t *testing.T) {
+	svc, user := newSessionTest(t)
+	first, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+	second, err := svc.RefreshSession(first.RefreshToken, "test-device")
+	assert.Nil(t, err)
+
+	// the first token got rotated already.
+	_, err = svc.RefreshSession(first.RefreshToken, "test-device")
+	assert.ErrorIs(t, err, repositories.ErrRefreshTokenReused)
+
+	// the whole session is revoked - including the newest token.
+	_, err = svc.RefreshSession(second.RefreshToken, "test-device")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	sessions, err := svc.GetSessions(user.Id)
+	assert.Nil(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestRefreshSessionUnknownToken(t *testing.T) {
+	svc, _ := newSessionTest(t)
+	_, err := svc.RefreshSession("unknown", "test-device")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshSessionMigratesLegacyToken(t *testing.T) {
+	svc, user := newSessionTest(t)
+	res, err := svc.RefreshSession("legacy-token", "test-device")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, res.RefreshToken)
+
+	// the legacy token can only be used once.
+	_, err = svc.RefreshSession("legacy-token", "test-device")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	sessions, err := svc.GetSessions(user.Id)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestRevokeSession(t *testing.T) {
+	svc, user := newSessionTest(t)
+	res, err := svc.CreateTokenForUser(user, "test-device")
+	assert.Nil(t, err)
+	sessions, _ := svc.GetSessions(user.Id)
+	assert.Len(t, sessions, 1)
+
+	// other users can not revoke the session.
+	other := &models.User{Base: models.Base{Id: uuid.New()}}
+	assert.ErrorIs(t, svc.RevokeSession(other, sessions[0].Id.String()), ErrSessionNotFound)
+
+	assert.Nil(t, svc.RevokeSession(user, sessions[0].Id.String()))
+	_, err = svc.GetActiveSession(sessions[0].Id.String())
+	assert.ErrorIs(t, err, ErrSessionNotActive)
+	_, err = svc.RefreshSession(res.RefreshToken, "test-device")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func (rep *memoryUserRepository) IsNameTaken(name string, excludeId uuid.UUID) (bool, error) {
+	_, err := rep.find(func(user *models.User) bool { return strings.EqualFold(user.Name, name) && user.Id != excludeId })
+	return err == nil, nil
+}
@@ -2,9 +2,15 @@ package service
 
 import (
 	"crypto/ecdsa"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/http_dto"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
@@ -12,51 +18,124 @@ type TokenSvc interface {
 	CreateSignedToken(claims jwt.Claims) (string, error)
 	GetSigningKey() *ecdsa.PrivateKey
 	ParseToken(token string) (jwt.Claims, error)
+	// returns all verification keys - other services use them to verify our tokens.
+	GetJWKS() http_dto.JSONWebKeySet
+	// reloads the key directory until the cancel channel is closed.
+	Watch(interval time.Duration, cancelChan <-chan struct{})
 }
 
 type TokenService struct {
-	privateKey *ecdsa.PrivateKey
-	publicKey  *ecdsa.PublicKey
+	// empty if the key pair is configured using PRIVATE_KEY_PATH and PUBLIC_KEY_PATH.
+	keyDirectory string
+	signingKid   string
+
+	mut    sync.RWMutex
+	keys   *keySet
+	logger *logrus.Entry
 }
 
+// loads the keys from KEY_DIRECTORY. Falls back to the single key pair of PRIVATE_KEY_PATH and PUBLIC_KEY_PATH.
 func NewTokenService() TokenSvc {
-	privateKeyPath := os.Getenv("PRIVATE_KEY_PATH")
-	publicKeyPath := os.Getenv("PUBLIC_KEY_PATH")
-
-	publicKeyBytes, err := os.ReadFile(publicKeyPath)
-	orchardclient.FailOnError(err, "Failed to read public key")
-
-	publicKey, err := jwt.ParseECPublicKeyFromPEM(publicKeyBytes)
-	orchardclient.FailOnError(err, "Failed to parse public key")
+	svc := &TokenService{
+		keyDirectory: os.Getenv("KEY_DIRECTORY"),
+		signingKid:   os.Getenv("SIGNING_KEY_ID"),
+		logger:       orchardclient.Logger.WithField("component", "TokenService"),
+	}
 
-	pem, err := os.ReadFile(privateKeyPath)
-	orchardclient.FailOnError(err, "Failed to read private key")
-	privateKey, err := jwt.ParseECPrivateKeyFromPEM(pem)
-	orchardclient.FailOnError(err, "Failed to parse private key")
-	return &TokenService{
-		privateKey: privateKey,
-		publicKey:  publicKey,
+	var err error
+	if svc.keyDirectory != "" {
+		svc.keys, err = loadKeyDirectory(svc.keyDirectory, svc.signingKid)
+		orchardclient.FailOnError(err, "Failed to load the key directory")
+	} else {
+		svc.keys, err = loadKeyPair(os.Getenv("PRIVATE_KEY_PATH"), os.Getenv("PUBLIC_KEY_PATH"))
+		orchardclient.FailOnError(err, "Failed to load the key pair")
 	}
+	svc.logger.Infof("signing tokens with key %s", svc.keys.signingKid)
+	return svc
+}
+
+func (svc *TokenService) getKeys() *keySet {
+	svc.mut.RLock()
+	defer svc.mut.RUnlock()
+	return svc.keys
 }
 
 func (svc *TokenService) CreateSignedToken(claims jwt.Claims) (string, error) {
+	keys := svc.getKeys()
 	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = keys.signingKid
 
-	return token.SignedString(svc.privateKey)
+	return token.SignedString(keys.signingKey)
 }
 
 func (svc *TokenService) GetSigningKey() *ecdsa.PrivateKey {
-	return svc.privateKey
+	return svc.getKeys().signingKey
 }
 
 func (svc *TokenService) ParseToken(token string) (jwt.Claims, error) {
-	t, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return svc.publicKey, nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
+	keys := svc.getKeys()
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
 
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			// tokens issued before the key rotation carry no kid.
+			return keys.signingKey.Public(), nil
+		}
+		key, ok := keys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key: %s", kid)
+		}
+		return key, nil
+	}
+
+	t, err := parser.Parse(token, keyFunc)
 	if err != nil {
 		return nil, err
 	}
 
 	return t.Claims, err
 }
+
+func (svc *TokenService) GetJWKS() http_dto.JSONWebKeySet {
+	keys := svc.getKeys()
+	set := http_dto.JSONWebKeySet{Keys: make([]http_dto.JSONWebKey, 0, len(keys.keys))}
+	for _, kid := range keys.kids() {
+		set.Keys = append(set.Keys, toJSONWebKey(kid, keys.keys[kid]))
+	}
+	return set
+}
+
+// reads the key directory again. The current keys stay in use if the directory is invalid.
+func (svc *TokenService) Reload() error {
+	if svc.keyDirectory == "" {
+		return nil
+	}
+	keys, err := loadKeyDirectory(svc.keyDirectory, svc.signingKid)
+	if err != nil {
+		return err
+	}
+
+	svc.mut.Lock()
+	defer svc.mut.Unlock()
+	if strings.Join(keys.kids(), ",") != strings.Join(svc.keys.kids(), ",") || keys.signingKid != svc.keys.signingKid {
+		svc.logger.Infof("key directory changed - verifying %v, signing with %s", keys.kids(), keys.signingKid)
+	}
+	svc.keys = keys
+	return nil
+}
+
+func (svc *TokenService) Watch(interval time.Duration, cancelChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cancelChan:
+			return
+		case <-ticker.C:
+			if err := svc.Reload(); err != nil {
+				svc.logger.Errorf("could not reload the key directory: %s", err)
+			}
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package service_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"os"
 	"path/filepath"
 	"testing"
@@ -31,3 +36,97 @@ func TestTokenService(t *testing.T) {
 	assert.Equal(t, "1234567890", parsed.(jwt.MapClaims)["sub"])
 
 }
+
+func writeKey(t *testing.T, dir, kid string, public bool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	var block *pem.Block
+	if public {
+		b, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		assert.Nil(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: b}
+	} else {
+		b, err := x509.MarshalECPrivateKey(key)
+		assert.Nil(t, err)
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0600))
+}
+
+func kidOf(t *testing.T, token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	assert.Nil(t, err)
+	return parsed.Header["kid"].(string)
+}
+
+func TestTokenServiceKeyPairKid(t *testing.T) {
+	privKeyPath, _ := filepath.Abs(filepath.Join("../../testdata/key.pem"))
+	pubKeyPath, _ := filepath.Abs(filepath.Join("../../testdata/public.pem"))
+	os.Setenv("PRIVATE_KEY_PATH", privKeyPath)
+	os.Setenv("PUBLIC_KEY_PATH", pubKeyPath)
+	tokenSvc := service.NewTokenService()
+
+	signedToken, err := tokenSvc.CreateSignedToken(jwt.MapClaims{"sub": "1234567890"})
+	assert.Nil(t, err)
+
+	jwks := tokenSvc.GetJWKS()
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, jwks.Keys[0].Kid, kidOf(t, signedToken))
+	assert.Equal(t, "P-256", jwks.Keys[0].Crv)
+	assert.Equal(t, "ES256", jwks.Keys[0].Alg)
+}
+
+func TestTokenServiceKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2026-01", false)
+	t.Setenv("KEY_DIRECTORY", dir)
+	tokenSvc := service.NewTokenService()
+
+	oldToken, err := tokenSvc.CreateSignedToken(jwt.MapClaims{"sub": "old"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2026-01", kidOf(t, oldToken))
+
+	// the new key signs all further tokens - the old one is kept for verification only.
+	writeKey(t, dir, "2026-02", false)
+	assert.Nil(t, tokenSvc.(*service.TokenService).Reload())
+
+	newToken, err := tokenSvc.CreateSignedToken(jwt.MapClaims{"sub": "new"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2026-02", kidOf(t, newToken))
+
+	claims, err := tokenSvc.ParseToken(oldToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", claims.(jwt.MapClaims)["sub"])
+	_, err = tokenSvc.ParseToken(newToken)
+	assert.Nil(t, err)
+	assert.Len(t, tokenSvc.GetJWKS().Keys, 2)
+
+	// removing the old key invalidates its tokens.
+	assert.Nil(t, os.Remove(filepath.Join(dir, "2026-01.pem")))
+	assert.Nil(t, tokenSvc.(*service.TokenService).Reload())
+	_, err = tokenSvc.ParseToken(oldToken)
+	assert.NotNil(t, err)
+}
+
+func TestTokenServiceSigningKeyId(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "a", false)
+	writeKey(t, dir, "b", false)
+	writeKey(t, dir, "c", true)
+	t.Setenv("KEY_DIRECTORY", dir)
+	t.Setenv("SIGNING_KEY_ID", "a")
+	tokenSvc := service.NewTokenService()
+
+	token, err := tokenSvc.CreateSignedToken(jwt.MapClaims{"sub": "1234567890"})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", kidOf(t, token))
+	assert.Len(t, tokenSvc.GetJWKS().Keys, 3)
+
+	// public keys can not sign - the current keys stay in use.
+	assert.Nil(t, os.Remove(filepath.Join(dir, "a.pem")))
+	writeKey(t, dir, "a", true)
+	assert.NotNil(t, tokenSvc.(*service.TokenService).Reload())
+	token, err = tokenSvc.CreateSignedToken(jwt.MapClaims{"sub": "1234567890"})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", kidOf(t, token))
+}
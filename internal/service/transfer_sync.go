@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/leader"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+// the sender of the transfer event emitted by a mint.
+var mintAddress = common.Address{}.String()
+
+type TransferSyncConfig struct {
+	// the checkpoint is stored per contract address.
+	Contract string
+	// a log is processed once this many blocks were mined on top of its block.
+	// a reorg deeper than this would be missed.
+	Confirmations uint64
+	// the block of the contract deployment. Used if there is no checkpoint yet.
+	StartBlock uint64
+	// the max block range of a single FilterTransfer request - most rpc providers limit it.
+	ChunkSize uint64
+	// the chain is polled in this interval - the live logs of the websocket only speed it up.
+	PollInterval time.Duration
+}
+
+func DefaultTransferSyncConfig(contract string) TransferSyncConfig {
+	return TransferSyncConfig{
+		Contract:      contract,
+		Confirmations: 12,
+		StartBlock:    0,
+		ChunkSize:     2000,
+		PollInterval:  15 * time.Second,
+	}
+}
+
+type TransferSyncSvc interface {
+	// processes every confirmed transfer after the checkpoint.
+	Sync(ctx context.Context) error
+	// runs on the leader only.
+	GetListener() leader.Listener
+}
+
+type TransferSyncService struct {
+	source          cryptokoi.TransferSource
+	checkpointRep   repositories.CheckpointRepository
+	cryptogotchiSvc CryptogotchiSvc
+	config          TransferSyncConfig
+	logger          *logrus.Entry
+}
+
+func NewTransferSyncService(source cryptokoi.TransferSource, checkpointRep repositories.CheckpointRepository, cryptogotchiSvc CryptogotchiSvc, config TransferSyncConfig) TransferSyncSvc {
+	return &TransferSyncService{
+		source:          source,
+		checkpointRep:   checkpointRep,
+		cryptogotchiSvc: cryptogotchiSvc,
+		config:          config,
+		logger:          orchardclient.Logger.WithField("component", "TransferSyncService"),
+	}
+}
+
+func (svc *TransferSyncService) Sync(ctx context.Context) error {
+	head, err := svc.source.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < svc.config.Confirmations {
+		return nil
+	}
+	confirmed := head - svc.config.Confirmations
+
+	from := svc.config.StartBlock
+	checkpoint, err := svc.checkpointRep.GetCheckpoint(svc.config.Contract)
+	if err == nil {
+		from = checkpoint.BlockNumber + 1
+	} else if !db.IsNotFound(err) {
+		return err
+	}
+
+	if confirmed >= from && confirmed-from >= svc.config.ChunkSize {
+		svc.logger.Infof("backfilling the blocks %d - %d", from, confirmed)
+	}
+
+	for from <= confirmed {
+		to := from + svc.config.ChunkSize - 1
+		if to > confirmed {
+			to = confirmed
+		}
+		events, err := svc.source.FilterTransfers(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			if err := svc.process(ev); err != nil {
+				// the next sync starts at the block of the failed log.
+				// the logs of the block processed before are skipped.
+				if ev.BlockNumber > from {
+					if saveErr := svc.saveCheckpoint(ev.BlockNumber - 1); saveErr != nil {
+						svc.logger.Error(saveErr)
+					}
+				}
+				return err
+			}
+		}
+		if err := svc.saveCheckpoint(to); err != nil {
+			return err
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+func (svc *TransferSyncService) saveCheckpoint(blockNumber uint64) error {
+	return svc.checkpointRep.SaveCheckpoint(&models.BlockCheckpoint{
+		Contract:    svc.config.Contract,
+		BlockNumber: blockNumber,
+	})
+}
+
+func (svc *TransferSyncService) process(ev cryptokoi.CryptoKoiEvent) error {
+	if ev.Removed {
+		return nil
+	}
+	processed, err := svc.checkpointRep.IsProcessed(ev.TxHash, ev.LogIndex)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	err = svc.cryptogotchiSvc.ApplyTransfer(ev)
+	if db.IsNotFound(err) {
+		// retrying does not help - the token is unknown.
+		svc.logger.Warnf("skipping the transfer of token %s in %s: %s", ev.TokenId, ev.TxHash, err)
+	} else if err != nil {
+		return err
+	}
+
+	return svc.checkpointRep.MarkProcessed(&models.ProcessedLog{
+		TxHash:      ev.TxHash,
+		LogIndex:    ev.LogIndex,
+		BlockNumber: ev.BlockNumber,
+	})
+}
+
+func (svc *TransferSyncService) GetListener() leader.Listener {
+	return leader.NewListener(func(cancelChan <-chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-cancelChan
+			cancel()
+		}()
+
+		liveChan := svc.source.Notify(cancelChan)
+		ticker := time.NewTicker(svc.config.PollInterval)
+		defer ticker.Stop()
+		for {
+			// catches up on start - the transfers of the time without a leader are processed as well.
+			if err := svc.Sync(ctx); err != nil && ctx.Err() == nil {
+				svc.logger.Errorf("could not sync the transfers: %s", err)
+			}
+			select {
+			case <-cancelChan:
+				return
+			case <-liveChan:
+			case <-ticker.C:
+			}
+		}
+	})
+}
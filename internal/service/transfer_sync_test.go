@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/cryptokoi"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type fakeTransferSource struct {
+	head   uint64
+	events []cryptokoi.CryptoKoiEvent
+	ranges [][2]uint64
+}
+
+func (s *fakeTransferSource) BlockNumber(ctx context.Context) (uint64, error) {
+	return s.head, nil
+}
+
+func (s *fakeTransferSource) FilterTransfers(ctx context.Context, from uint64, to uint64) ([]cryptokoi.CryptoKoiEvent, error) {
+	s.ranges = append(s.ranges, [2]uint64{from, to})
+	var res []cryptokoi.CryptoKoiEvent
+	for _, ev := range s.events {
+		if ev.BlockNumber >= from && ev.BlockNumber <= to {
+			res = append(res, ev)
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeTransferSource) Notify(cancelChan <-chan struct{}) <-chan cryptokoi.CryptoKoiEvent {
+	return make(chan cryptokoi.CryptoKoiEvent)
+}
+
+type memoryCheckpointRepository struct {
+	checkpoints map[string]models.BlockCheckpoint
+	processed   map[string]models.ProcessedLog
+}
+
+func newMemoryCheckpointRepository() *memoryCheckpointRepository {
+	return &memoryCheckpointRepository{
+		checkpoints: make(map[string]models.BlockCheckpoint),
+		processed:   make(map[string]models.ProcessedLog),
+	}
+}
+
+func (rep *memoryCheckpointRepository) GetCheckpoint(contract string) (models.BlockCheckpoint, error) {
+	checkpoint, ok := rep.checkpoints[contract]
+	if !ok {
+		return checkpoint, gorm.ErrRecordNotFound
+	}
+	return checkpoint, nil
+}
+
+func (rep *memoryCheckpointRepository) SaveCheckpoint(checkpoint *models.BlockCheckpoint) error {
+	rep.checkpoints[checkpoint.Contract] = *checkpoint
+	return nil
+}
+
+func (rep *memoryCheckpointRepository) IsProcessed(txHash string, logIndex uint) (bool, error) {
+	_, ok := rep.processed[fmt.Sprintf("%s-%d", txHash, logIndex)]
+	return ok, nil
+}
+
+func (rep *memoryCheckpointRepository) MarkProcessed(log *models.ProcessedLog) error {
+	rep.processed[fmt.Sprintf("%s-%d", log.TxHash, log.LogIndex)] = *log
+	return nil
+}
+
+// only implements the methods used by the transfer sync.
+type recordingCryptogotchiSvc struct {
+	CryptogotchiSvc
+	applied []string
+	// returned by the next call of ApplyTransfer.
+	errs []error
+}
+
+func (svc *recordingCryptogotchiSvc) ApplyTransfer(ev cryptokoi.CryptoKoiEvent) error {
+	if len(svc.errs) > 0 {
+		err := svc.errs[0]
+		svc.errs = svc.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	svc.applied = append(svc.applied, ev.TokenId)
+	return nil
+}
+
+func mint(tokenId string, blockNumber uint64, logIndex uint) cryptokoi.CryptoKoiEvent {
+	return cryptokoi.CryptoKoiEvent{
+		TokenId:     tokenId,
+		From:        mintAddress,
+		To:          "0xa111C225A0aFd5aD64221B1bc1D5d817e5D3Ca15",
+		TxHash:      fmt.Sprintf("0x%064d", blockNumber),
+		LogIndex:    logIndex,
+		BlockNumber: blockNumber,
+	}
+}
+
+func newTransferSyncService(source *fakeTransferSource) (*TransferSyncService, *memoryCheckpointRepository, *recordingCryptogotchiSvc) {
+	rep := newMemoryCheckpointRepository()
+	cryptogotchiSvc := &recordingCryptogotchiSvc{}
+	config := DefaultTransferSyncConfig("0x133c4b6c69322d09c5b266efa9559173b6c9f029")
+	config.Confirmations = 10
+	config.ChunkSize = 40
+	config.StartBlock = 5
+	return NewTransferSyncService(source, rep, cryptogotchiSvc, config).(*TransferSyncService), rep, cryptogotchiSvc
+}
+
+func TestSyncBackfillsConfirmedBlocksInChunks(t *testing.T) {
+	source := &fakeTransferSource{head: 100, events: []cryptokoi.CryptoKoiEvent{mint("1", 10, 0), mint("2", 90, 0), mint("3", 95, 0)}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Equal(t, [][2]uint64{{5, 44}, {45, 84}, {85, 90}}, source.ranges)
+	// the block 95 is not confirmed yet.
+	assert.Equal(t, []string{"1", "2"}, cryptogotchiSvc.applied)
+	assert.Equal(t, uint64(90), rep.checkpoints[svc.config.Contract].BlockNumber)
+
+	source.head = 105
+	source.ranges = nil
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Equal(t, [][2]uint64{{91, 95}}, source.ranges)
+	assert.Equal(t, []string{"1", "2", "3"}, cryptogotchiSvc.applied)
+}
+
+func TestSyncWaitsForConfirmations(t *testing.T) {
+	source := &fakeTransferSource{head: 8, events: []cryptokoi.CryptoKoiEvent{mint("1", 6, 0)}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Empty(t, source.ranges)
+	assert.Empty(t, cryptogotchiSvc.applied)
+	assert.Empty(t, rep.checkpoints)
+}
+
+func TestSyncProcessesLogsOnce(t *testing.T) {
+	source := &fakeTransferSource{head: 100, events: []cryptokoi.CryptoKoiEvent{mint("1", 10, 0), mint("2", 10, 1)}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	// the checkpoint got lost - like a crash before saving it.
+	delete(rep.checkpoints, svc.config.Contract)
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Equal(t, []string{"1", "2"}, cryptogotchiSvc.applied)
+}
+
+func TestSyncRetriesFailedLogs(t *testing.T) {
+	source := &fakeTransferSource{head: 100, events: []cryptokoi.CryptoKoiEvent{mint("1", 10, 0), mint("2", 20, 0), mint("3", 20, 1)}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+	cryptogotchiSvc.errs = []error{nil, nil, errors.New("database unavailable")}
+
+	assert.NotNil(t, svc.Sync(context.Background()))
+	assert.Equal(t, []string{"1", "2"}, cryptogotchiSvc.applied)
+	assert.Equal(t, uint64(19), rep.checkpoints[svc.config.Contract].BlockNumber)
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Equal(t, []string{"1", "2", "3"}, cryptogotchiSvc.applied)
+	assert.Equal(t, uint64(90), rep.checkpoints[svc.config.Contract].BlockNumber)
+}
+
+func TestSyncSkipsUnknownTokens(t *testing.T) {
+	source := &fakeTransferSource{head: 100, events: []cryptokoi.CryptoKoiEvent{mint("1", 10, 0), mint("2", 20, 0)}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+	cryptogotchiSvc.errs = []error{gorm.ErrRecordNotFound}
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Equal(t, []string{"2"}, cryptogotchiSvc.applied)
+	assert.Len(t, rep.processed, 2)
+}
+
+func TestSyncIgnoresRemovedLogs(t *testing.T) {
+	removed := mint("1", 10, 0)
+	removed.Removed = true
+	source := &fakeTransferSource{head: 100, events: []cryptokoi.CryptoKoiEvent{removed}}
+	svc, rep, cryptogotchiSvc := newTransferSyncService(source)
+
+	assert.Nil(t, svc.Sync(context.Background()))
+	assert.Empty(t, cryptogotchiSvc.applied)
+	assert.Empty(t, rep.processed)
+}
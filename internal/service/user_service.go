@@ -1,22 +1,41 @@
 package service
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
 	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/repositories"
 	"gitlab.com/l3montree/microservices/libs/orchardclient"
 )
 
 type UserSvc interface {
 	repositories.UserRepository
+	// applies the name policy - see NamePolicy.
+	Rename(user *models.User, name string) error
 }
 
 type UserService struct {
 	repositories.UserRepository
 	notificationSvc NotificationSvc
+	namePolicy      NamePolicy
 	logger          *logrus.Entry
 }
 
-func NewUserService(rep repositories.UserRepository) UserSvc {
+func NewUserService(rep repositories.UserRepository, namePolicy NamePolicy) UserSvc {
 	logger := orchardclient.Logger.WithField("component", "UserService")
-	return &UserService{UserRepository: rep, logger: logger}
+	return &UserService{UserRepository: rep, namePolicy: namePolicy, logger: logger}
+}
+
+func (svc *UserService) Rename(user *models.User, name string) error {
+	name, err := svc.namePolicy.checkRename(name, user.NameChangedAt, svc.namePolicy.UniqueUserNames, func(name string) (bool, error) {
+		return svc.IsNameTaken(name, user.Id)
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	user.Name = name
+	user.NameChangedAt = &now
+	return svc.Save(user)
 }
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/db"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+// the time a client has to sign the wallet proof.
+const WALLET_NONCE_TTL = 10 * time.Minute
+
+var ErrInvalidWalletProof = errors.New("invalid proof of wallet ownership")
+
+// the message is rebuilt from the stored nonce during the verification.
+// therefore it needs to be deterministic.
+func walletProofMessage(walletAddress string, userId string, nonce string, expiresAt time.Time) string {
+	return fmt.Sprintf(
+		"CryptoKoi wants you to prove the ownership of the wallet:\n%s\n\nAccount: %s\nNonce: %s\nExpiration Time: %s",
+		common.HexToAddress(walletAddress).Hex(),
+		userId,
+		nonce,
+		expiresAt.UTC().Format(time.RFC3339),
+	)
+}
+
+// extracts the nonce out of a wallet proof message.
+func walletProofNonce(message string) (string, bool) {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "Nonce: ") {
+			return strings.TrimPrefix(line, "Nonce: "), true
+		}
+	}
+	return "", false
+}
+
+func (svc *AuthService) CreateWalletChallenge(user *models.User, walletAddress string) (string, time.Time, error) {
+	if !common.IsHexAddress(walletAddress) {
+		return "", time.Time{}, fmt.Errorf("invalid wallet address: %s", walletAddress)
+	}
+	value, err := util.RandomNonce()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	lowerCasedWalletAddress := strings.ToLower(walletAddress)
+	nonce := models.Nonce{
+		Value:         value,
+		Purpose:       models.WalletNoncePurpose,
+		UserId:        &user.Id,
+		WalletAddress: &lowerCasedWalletAddress,
+		// the database does not store fractions of a second - the message needs to be rebuilt from the stored value.
+		ExpiresAt: time.Now().Add(WALLET_NONCE_TTL).Truncate(time.Second),
+	}
+	if err := svc.nonceRep.Save(&nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	return walletProofMessage(walletAddress, user.Id.String(), nonce.Value, nonce.ExpiresAt), nonce.ExpiresAt, nil
+}
+
+func (svc *AuthService) VerifyWalletOwnership(user *models.User, walletAddress, message, signature string) error {
+	if !common.IsHexAddress(walletAddress) {
+		return ErrInvalidWalletProof
+	}
+	signer, err := util.RecoverPersonalSignAddress([]byte(message), signature)
+	if err != nil || signer != common.HexToAddress(walletAddress) {
+		return ErrInvalidWalletProof
+	}
+
+	value, ok := walletProofNonce(message)
+	if !ok {
+		return ErrInvalidWalletProof
+	}
+	// the signature got checked already - a proof signed by another wallet does not consume the nonce.
+	// a validly signed proof consumes it, even if it does not match the stored challenge.
+	nonce, err := svc.nonceRep.Consume(value, models.WalletNoncePurpose, &user.Id)
+	if db.IsNotFound(err) {
+		return ErrInvalidWalletProof
+	}
+	if err != nil {
+		return err
+	}
+
+	// the message needs to match the one created for the user and the wallet.
+	if nonce.WalletAddress == nil || !strings.EqualFold(*nonce.WalletAddress, walletAddress) {
+		return ErrInvalidWalletProof
+	}
+	if message != walletProofMessage(walletAddress, user.Id.String(), nonce.Value, nonce.ExpiresAt) {
+		return ErrInvalidWalletProof
+	}
+	return nil
+}
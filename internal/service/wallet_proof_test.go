@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type memoryNonceRepository struct {
+	nonces map[string]models.Nonce
+}
+
+func (rep *memoryNonceRepository) Save(nonce *models.Nonce) error {
+	rep.nonces[nonce.Value] = *nonce
+	return nil
+}
+
+func (rep *memoryNonceRepository) Consume(value string, purpose models.NoncePurpose, userId *uuid.UUID) (models.Nonce, error) {
+	nonce, ok := rep.nonces[value]
+	if !ok || nonce.Purpose != purpose || nonce.ExpiresAt.Before(time.Now()) || (userId != nil && *nonce.UserId != *userId) {
+		return nonce, gorm.ErrRecordNotFound
+	}
+	delete(rep.nonces, value)
+	return nonce, nil
+}
+
+func (rep *memoryNonceRepository) ConsumeForAnyUser(value string, purpose models.NoncePurpose) (models.Nonce, error) {
+	nonce, ok := rep.nonces[value]
+	if !ok || nonce.Purpose != purpose || nonce.ExpiresAt.Before(time.Now()) {
+		return nonce, gorm.ErrRecordNotFound
+	}
+	delete(rep.nonces, value)
+	return nonce, nil
+}
+
+func (rep *memoryNonceRepository) DeleteExpired() error {
+	return nil
+}
+
+func personalSign(t *testing.T, message string) (string, string) {
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	assert.Nil(t, err)
+	sig[64] += 27
+	return crypto.PubkeyToAddress(key.PublicKey).Hex(), hexutil.Encode(sig)
+}
+
+// returns the service, the user, the wallet address, the message and its signature.
+func newWalletProofTest(t *testing.T) (*AuthService, *models.User, string, string, string) {
+	svc := &AuthService{nonceRep: &memoryNonceRepository{nonces: make(map[string]models.Nonce)}}
+	user := &models.User{Base: models.Base{Id: uuid.New()}}
+
+	// the address is needed to create the message - therefore the key is created upfront.
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message, _, err := svc.CreateWalletChallenge(user, address)
+	assert.Nil(t, err)
+	sig, _ := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	sig[64] += 27
+	return svc, user, address, message, hexutil.Encode(sig)
+}
+
+func TestVerifyWalletOwnership(t *testing.T) {
+	svc, user, address, message, signature := newWalletProofTest(t)
+
+	assert.Nil(t, svc.VerifyWalletOwnership(user, address, message, signature))
+	// replaying the proof fails.
+	assert.ErrorIs(t, svc.VerifyWalletOwnership(user, address, message, signature), ErrInvalidWalletProof)
+}
+
+func TestVerifyWalletOwnershipOtherUser(t *testing.T) {
+	svc, _, address, message, signature := newWalletProofTest(t)
+
+	other := &models.User{Base: models.Base{Id: uuid.New()}}
+	assert.ErrorIs(t, svc.VerifyWalletOwnership(other, address, message, signature), ErrInvalidWalletProof)
+}
+
+func TestVerifyWalletOwnershipOtherSigner(t *testing.T) {
+	svc, user, address, message, _ := newWalletProofTest(t)
+
+	_, signature := personalSign(t, message)
+	assert.ErrorIs(t, svc.VerifyWalletOwnership(user, address, message, signature), ErrInvalidWalletProof)
+}
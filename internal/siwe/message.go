@@ -0,0 +1,192 @@
+// Package siwe implements the parts of Sign-In with Ethereum (EIP-4361) the login needs.
+// https://eips.ethereum.org/EIPS/eip-4361
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/internal/util"
+)
+
+const headerSuffix = " wants you to sign in with your Ethereum account:"
+
+var (
+	ErrInvalidMessage   = errors.New("invalid sign-in message")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrDomainMismatch   = errors.New("the message was created for another domain")
+	ErrChainMismatch    = errors.New("the message was created for another chain")
+	ErrExpired          = errors.New("the message is expired or not yet valid")
+	ErrInvalidNonce     = errors.New("the nonce is unknown, expired or already used")
+)
+
+type Message struct {
+	Domain    string
+	Address   common.Address
+	Statement string
+	URI       string
+	Version   string
+	ChainId   int
+	Nonce     string
+	IssuedAt  time.Time
+	// optional
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestId      string
+	Resources      []string
+}
+
+// formats the message like wallets expect it.
+func (m Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + headerSuffix + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + strconv.Itoa(m.ChainId) + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.Format(time.RFC3339))
+	}
+	if m.RequestId != "" {
+		b.WriteString("\nRequest ID: " + m.RequestId)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("%w: invalid timestamp: %s", ErrInvalidMessage, value)
+	}
+	return t, nil
+}
+
+// parses a message in the EIP-4361 format.
+func ParseMessage(raw string) (Message, error) {
+	var m Message
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasSuffix(lines[0], headerSuffix) || lines[2] != "" {
+		return m, ErrInvalidMessage
+	}
+
+	m.Domain = strings.TrimSuffix(lines[0], headerSuffix)
+	if !common.IsHexAddress(lines[1]) {
+		return m, fmt.Errorf("%w: invalid address", ErrInvalidMessage)
+	}
+	m.Address = common.HexToAddress(lines[1])
+
+	rest := lines[3:]
+	if rest[0] != "" {
+		// the statement is optional - but it is always followed by an empty line.
+		if len(rest) < 2 || rest[1] != "" {
+			return m, ErrInvalidMessage
+		}
+		m.Statement = rest[0]
+		rest = rest[2:]
+	} else {
+		rest = rest[1:]
+	}
+
+	var err error
+	for i := 0; i < len(rest); i++ {
+		line := rest[i]
+		if line == "Resources:" {
+			for _, resource := range rest[i+1:] {
+				if !strings.HasPrefix(resource, "- ") {
+					return m, fmt.Errorf("%w: invalid resource", ErrInvalidMessage)
+				}
+				m.Resources = append(m.Resources, strings.TrimPrefix(resource, "- "))
+			}
+			break
+		}
+
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			return m, fmt.Errorf("%w: invalid line: %s", ErrInvalidMessage, line)
+		}
+		switch key {
+		case "URI":
+			m.URI = value
+		case "Version":
+			m.Version = value
+		case "Chain ID":
+			m.ChainId, err = strconv.Atoi(value)
+			if err != nil {
+				return m, fmt.Errorf("%w: invalid chain id", ErrInvalidMessage)
+			}
+		case "Nonce":
+			m.Nonce = value
+		case "Issued At":
+			m.IssuedAt, err = parseTime(value)
+		case "Expiration Time":
+			var t time.Time
+			t, err = parseTime(value)
+			m.ExpirationTime = &t
+		case "Not Before":
+			var t time.Time
+			t, err = parseTime(value)
+			m.NotBefore = &t
+		case "Request ID":
+			m.RequestId = value
+		default:
+			return m, fmt.Errorf("%w: unknown field: %s", ErrInvalidMessage, key)
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+
+	if m.URI == "" || m.Version != "1" || m.Nonce == "" || m.IssuedAt.IsZero() {
+		return m, fmt.Errorf("%w: missing required field", ErrInvalidMessage)
+	}
+	return m, nil
+}
+
+// checks everything except the nonce - the nonce is checked by the caller since it needs to be stored.
+func (m Message) Validate(domain string, chainId int, now time.Time) error {
+	if m.Domain != domain {
+		return ErrDomainMismatch
+	}
+	if m.ChainId != chainId {
+		return ErrChainMismatch
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return ErrExpired
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// parses the message and makes sure it was signed by the address it contains.
+func Verify(raw string, signature string) (Message, error) {
+	m, err := ParseMessage(raw)
+	if err != nil {
+		return m, err
+	}
+	address, err := util.RecoverPersonalSignAddress([]byte(raw), signature)
+	if err != nil || address != m.Address {
+		return m, ErrInvalidSignature
+	}
+	return m, nil
+}
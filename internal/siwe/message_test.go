@@ -0,0 +1,92 @@
+package siwe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMessage(t *testing.T) (Message, func(string) string) {
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	expiration := time.Date(2022, 4, 1, 12, 10, 0, 0, time.UTC)
+	m := Message{
+		Domain:         "api.crypto-koi.io",
+		Address:        crypto.PubkeyToAddress(key.PublicKey),
+		Statement:      "Sign in to CryptoKoi.",
+		URI:            "https://api.crypto-koi.io",
+		Version:        "1",
+		ChainId:        137,
+		Nonce:          "32891756",
+		IssuedAt:       time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC),
+		ExpirationTime: &expiration,
+	}
+	sign := func(raw string) string {
+		sig, err := crypto.Sign(accounts.TextHash([]byte(raw)), key)
+		assert.Nil(t, err)
+		sig[64] += 27
+		return hexutil.Encode(sig)
+	}
+	return m, sign
+}
+
+func TestParseMessage(t *testing.T) {
+	m, _ := newMessage(t)
+	m.Resources = []string{"ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq"}
+
+	parsed, err := ParseMessage(m.String())
+	assert.Nil(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestParseMessageWithoutStatement(t *testing.T) {
+	m, _ := newMessage(t)
+	m.Statement = ""
+	m.ExpirationTime = nil
+
+	parsed, err := ParseMessage(m.String())
+	assert.Nil(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestParseInvalidMessage(t *testing.T) {
+	_, err := ParseMessage("hello koi")
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	m, _ := newMessage(t)
+	m.Nonce = ""
+	_, err = ParseMessage(m.String())
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestVerify(t *testing.T) {
+	m, sign := newMessage(t)
+	raw := m.String()
+
+	verified, err := Verify(raw, sign(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, m.Address, verified.Address)
+}
+
+func TestVerifyRejectsOtherSigner(t *testing.T) {
+	m, _ := newMessage(t)
+	_, signWithOtherKey := newMessage(t)
+	raw := m.String()
+
+	_, err := Verify(raw, signWithOtherKey(raw))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestValidate(t *testing.T) {
+	m, _ := newMessage(t)
+	now := m.IssuedAt.Add(time.Minute)
+
+	assert.Nil(t, m.Validate("api.crypto-koi.io", 137, now))
+	assert.ErrorIs(t, m.Validate("evil.io", 137, now), ErrDomainMismatch)
+	assert.ErrorIs(t, m.Validate("api.crypto-koi.io", 1, now), ErrChainMismatch)
+	assert.ErrorIs(t, m.Validate("api.crypto-koi.io", 137, m.ExpirationTime.Add(time.Second)), ErrExpired)
+}
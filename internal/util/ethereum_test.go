@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPersonalSignAddress(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	message := []byte("hello koi")
+
+	sig, err := crypto.Sign(accounts.TextHash(message), key)
+	assert.Nil(t, err)
+	// like a wallet would return it.
+	sig[64] += 27
+
+	address, err := RecoverPersonalSignAddress(message, hexutil.Encode(sig))
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), address)
+
+	// another message results in another address.
+	address, err = RecoverPersonalSignAddress([]byte("hello dragon"), hexutil.Encode(sig))
+	assert.Nil(t, err)
+	assert.NotEqual(t, crypto.PubkeyToAddress(key.PublicKey), address)
+
+	_, err = RecoverPersonalSignAddress(message, "0x1234")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,116 @@
+// Package dataloader batches the loads of multiple resolvers into a single query.
+// A loader caches the values - create a new one for every request.
+package dataloader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// returns the values of the keys. Keys without a value are missing in the map.
+type FetchFunc[K comparable, V any] func(keys []K) (map[K]V, error)
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	results map[K]*result[V]
+	once    sync.Once
+}
+
+type Loader[K comparable, V any] struct {
+	fetch FetchFunc[K, V]
+	// how long to wait for more keys before fetching.
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending *batch[K, V]
+}
+
+func New[K comparable, V any](fetch FetchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    make(map[K]*result[V]),
+	}
+}
+
+// blocks until the batch containing the key is fetched.
+// found is false if the fetch function did not return a value for the key.
+func (l *Loader[K, V]) Load(key K) (value V, found bool, err error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		l.cache[key] = res
+		l.enqueue(key, res)
+	}
+	l.mu.Unlock()
+
+	<-res.done
+	return res.value, res.found, res.err
+}
+
+// has to be called while holding the lock.
+func (l *Loader[K, V]) enqueue(key K, res *result[V]) {
+	if l.pending == nil {
+		b := &batch[K, V]{results: make(map[K]*result[V])}
+		l.pending = b
+		time.AfterFunc(l.wait, func() { l.dispatch(b) })
+	}
+	b := l.pending
+	b.keys = append(b.keys, key)
+	b.results[key] = res
+	if len(b.keys) >= l.maxBatch {
+		go l.dispatch(b)
+	}
+}
+
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.pending == b {
+			l.pending = nil
+		}
+		l.mu.Unlock()
+
+		values, err := l.safeFetch(b.keys)
+		for key, res := range b.results {
+			res.value, res.found = values[key]
+			res.err = err
+			close(res.done)
+		}
+	})
+}
+
+// a panic would block every waiting resolver.
+func (l *Loader[K, V]) safeFetch(keys []K) (values map[K]V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while fetching: %v", r)
+		}
+	}()
+	return l.fetch(keys)
+}
+
+// forgets all cached values - pending loads are not affected.
+func (l *Loader[K, V]) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, res := range l.cache {
+		select {
+		case <-res.done:
+			delete(l.cache, key)
+		default:
+		}
+	}
+}
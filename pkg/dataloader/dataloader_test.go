@@ -0,0 +1,95 @@
+package dataloader
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (f *recordingFetch) fetch(keys []string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, keys)
+	values := make(map[string]string)
+	for _, key := range keys {
+		if key != "missing" {
+			values[key] = "value-" + key
+		}
+	}
+	return values, nil
+}
+
+func loadConcurrently(l *Loader[string, string], keys ...string) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(keys))
+	for _, key := range keys {
+		go func(k string) {
+			defer wg.Done()
+			l.Load(k)
+		}(key)
+	}
+	wg.Wait()
+}
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	f := &recordingFetch{}
+	l := New(f.fetch, 10*time.Millisecond, 100)
+
+	loadConcurrently(l, "a", "b", "c", "a")
+
+	assert.Len(t, f.batches, 1)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, f.batches[0])
+
+	value, found, err := l.Load("b")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value-b", value)
+	// cached.
+	assert.Len(t, f.batches, 1)
+}
+
+func TestLoaderRespectsMaxBatch(t *testing.T) {
+	f := &recordingFetch{}
+	l := New(f.fetch, time.Second, 2)
+
+	start := time.Now()
+	loadConcurrently(l, "a", "b")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, f.batches, 1)
+}
+
+func TestLoaderReportsMissingKeys(t *testing.T) {
+	f := &recordingFetch{}
+	l := New(f.fetch, time.Millisecond, 100)
+
+	_, found, err := l.Load("missing")
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestLoaderReturnsFetchErrors(t *testing.T) {
+	l := New(func(keys []string) (map[string]string, error) {
+		panic(errors.New("database gone"))
+	}, time.Millisecond, 100)
+
+	_, _, err := l.Load("a")
+	assert.NotNil(t, err)
+}
+
+func TestLoaderClear(t *testing.T) {
+	f := &recordingFetch{}
+	l := New(f.fetch, time.Millisecond, 100)
+
+	l.Load("a")
+	l.Clear()
+	l.Load("a")
+	assert.Len(t, f.batches, 2)
+}
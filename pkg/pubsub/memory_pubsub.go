@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// the messages are dropped for subscribers which do not keep up.
+const subscriberBufferSize = 16
+
+type subscriber struct {
+	ch chan []byte
+}
+
+// only delivers the messages inside the process - meant for a single replica.
+type MemoryPubSub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*subscriber]struct{}
+}
+
+func NewMemoryPubSub() *MemoryPubSub {
+	return &MemoryPubSub{
+		subscribers: make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+func (m *MemoryPubSub) Publish(topic string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sub := range m.subscribers[topic] {
+		select {
+		case sub.ch <- payload:
+		default:
+			// the subscriber is too slow - never block the publisher.
+		}
+	}
+	return nil
+}
+
+func (m *MemoryPubSub) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	sub := &subscriber{ch: make(chan []byte, subscriberBufferSize)}
+	m.mu.Lock()
+	if m.subscribers[topic] == nil {
+		m.subscribers[topic] = make(map[*subscriber]struct{})
+	}
+	m.subscribers[topic][sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		delete(m.subscribers[topic], sub)
+		if len(m.subscribers[topic]) == 0 {
+			delete(m.subscribers, topic)
+		}
+		// closed while holding the lock - publish never sends on a closed channel.
+		close(sub.ch)
+		m.mu.Unlock()
+	}()
+	return sub.ch
+}
+
+func (m *MemoryPubSub) Close() error {
+	return nil
+}
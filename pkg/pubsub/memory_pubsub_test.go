@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, ch <-chan []byte) []byte {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestMemoryPubSubDeliversToTopicSubscribers(t *testing.T) {
+	ps := NewMemoryPubSub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := ps.Subscribe(ctx, "a")
+	b := ps.Subscribe(ctx, "b")
+
+	assert.Nil(t, ps.Publish("a", []byte("hello")))
+	assert.Equal(t, []byte("hello"), receive(t, a))
+	assert.Len(t, b, 0)
+}
+
+func TestMemoryPubSubClosesOnCancel(t *testing.T) {
+	ps := NewMemoryPubSub()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ps.Subscribe(ctx, "a")
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+	// publishing after the subscriber is gone must not panic.
+	assert.Nil(t, ps.Publish("a", []byte("hello")))
+}
+
+func TestMemoryPubSubDoesNotBlockOnSlowSubscribers(t *testing.T) {
+	ps := NewMemoryPubSub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps.Subscribe(ctx, "a")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBufferSize*2; i++ {
+			ps.Publish("a", nil)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked")
+	}
+}
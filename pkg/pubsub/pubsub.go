@@ -0,0 +1,12 @@
+package pubsub
+
+import "context"
+
+// delivers messages to every subscriber of a topic - at most once.
+// messages are not persisted: subscribers only receive messages published after subscribing.
+type PubSub interface {
+	Publish(topic string, payload []byte) error
+	// the channel is closed as soon as the context is done.
+	Subscribe(ctx context.Context, topic string) <-chan []byte
+	Close() error
+}
@@ -0,0 +1,144 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/resp"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+const maxReconnectBackoff = 30 * time.Second
+
+// propagates the messages between replicas using redis - or any other server speaking its protocol.
+// a single connection subscribes to every topic with the prefix. The messages are distributed inside the process.
+type RedisPubSub struct {
+	addr     string
+	password string
+	// every topic is prefixed - allows to share the redis instance.
+	prefix string
+	local  *MemoryPubSub
+	logger *logrus.Entry
+
+	mu          sync.Mutex
+	publishConn *resp.Conn
+	receiveConn *resp.Conn
+	closed      bool
+}
+
+func NewRedisPubSub(addr, password, prefix string) *RedisPubSub {
+	ps := &RedisPubSub{
+		addr:     addr,
+		password: password,
+		prefix:   prefix,
+		local:    NewMemoryPubSub(),
+		logger:   orchardclient.Logger.WithField("component", "RedisPubSub"),
+	}
+	go ps.receive()
+	return ps
+}
+
+func (ps *RedisPubSub) Publish(topic string, payload []byte) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return fmt.Errorf("pubsub is closed")
+	}
+	var err error
+	// retry once - the connection might have been closed by the server.
+	for i := 0; i < 2; i++ {
+		if ps.publishConn == nil {
+			ps.publishConn, err = resp.Dial(ps.addr, ps.password)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = ps.publishConn.Do("PUBLISH", ps.prefix+topic, string(payload))
+		if _, ok := err.(resp.Error); err == nil || ok {
+			return err
+		}
+		ps.publishConn.Close()
+		ps.publishConn = nil
+	}
+	return err
+}
+
+// the published messages arrive through redis - even the ones of this replica.
+func (ps *RedisPubSub) Subscribe(ctx context.Context, topic string) <-chan []byte {
+	return ps.local.Subscribe(ctx, topic)
+}
+
+func (ps *RedisPubSub) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.closed = true
+	if ps.publishConn != nil {
+		ps.publishConn.Close()
+	}
+	if ps.receiveConn != nil {
+		// unblocks the receive loop.
+		ps.receiveConn.Close()
+	}
+	return nil
+}
+
+func (ps *RedisPubSub) isClosed() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.closed
+}
+
+// reconnects until the pubsub is closed. Messages published while disconnected are lost.
+func (ps *RedisPubSub) receive() {
+	backoff := time.Second
+	for !ps.isClosed() {
+		err := ps.receiveUntilError()
+		if ps.isClosed() {
+			return
+		}
+		ps.logger.Errorf("lost the redis subscription - reconnecting in %s: %s", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+func (ps *RedisPubSub) receiveUntilError() error {
+	conn, err := resp.Dial(ps.addr, ps.password)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Do("PSUBSCRIBE", ps.prefix+"*"); err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	if ps.closed {
+		ps.mu.Unlock()
+		return nil
+	}
+	ps.receiveConn = conn
+	ps.mu.Unlock()
+
+	for {
+		reply, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+		// ["pmessage", pattern, channel, payload]
+		message, ok := reply.([]interface{})
+		if !ok || len(message) != 4 || message[0] != "pmessage" {
+			continue
+		}
+		channel, _ := message[2].(string)
+		payload, _ := message[3].(string)
+		ps.local.Publish(strings.TrimPrefix(channel, ps.prefix), []byte(payload))
+	}
+}
@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/resp"
+)
+
+// speaks just enough of the redis protocol to test the pubsub.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu          sync.Mutex
+	subscribers map[*bufio.Writer]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeRedis{listener: listener, password: password, subscribers: make(map[*bufio.Writer]string)}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authenticated := f.password == ""
+	for {
+		reply, err := resp.ReadReply(r)
+		if err != nil {
+			return
+		}
+		args := reply.([]interface{})
+		f.mu.Lock()
+		switch strings.ToUpper(args[0].(string)) {
+		case "AUTH":
+			if args[1] == f.password {
+				authenticated = true
+				w.WriteString("+OK\r\n")
+			} else {
+				w.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case "PSUBSCRIBE":
+			if !authenticated {
+				w.WriteString("-NOAUTH Authentication required.\r\n")
+				break
+			}
+			f.subscribers[w] = strings.TrimSuffix(args[1].(string), "*")
+			resp.WriteCommand(w, "psubscribe", args[1].(string))
+		case "PUBLISH":
+			if !authenticated {
+				w.WriteString("-NOAUTH Authentication required.\r\n")
+				break
+			}
+			channel := args[1].(string)
+			for sub, prefix := range f.subscribers {
+				if strings.HasPrefix(channel, prefix) {
+					resp.WriteCommand(sub, "pmessage", prefix+"*", channel, args[2].(string))
+				}
+			}
+			w.WriteString(":1\r\n")
+		}
+		w.Flush()
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeRedis) subscriberCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers)
+}
+
+func TestRedisPubSubPropagatesBetweenInstances(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	a := NewRedisPubSub(server.listener.Addr().String(), "secret", "test:")
+	defer a.Close()
+	b := NewRedisPubSub(server.listener.Addr().String(), "secret", "test:")
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := b.Subscribe(ctx, "cryptogotchi:1")
+
+	// wait until both instances subscribed.
+	assert.Eventually(t, func() bool { return server.subscriberCount() == 2 }, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, a.Publish("cryptogotchi:1", []byte("fed")))
+	assert.Equal(t, []byte("fed"), receive(t, ch))
+}
+
+func TestRedisPubSubReturnsServerErrors(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	ps := NewRedisPubSub(server.listener.Addr().String(), "wrong", "test:")
+	defer ps.Close()
+
+	assert.NotNil(t, ps.Publish("leaderboard", nil))
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(now *time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestMemoryStoreAllowsBurst(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	policy := Policy{Limit: 3, Period: time.Minute}
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take("a", policy)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, _ := store.Take("a", policy)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 3, res.Limit)
+	// a token is refilled every 20 seconds.
+	assert.Equal(t, now.Add(20*time.Second), res.RetryAt)
+	assert.Equal(t, now.Add(time.Minute), res.ResetAt)
+
+	// other keys own their bucket.
+	res, _ = store.Take("b", policy)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	policy := Policy{Limit: 2, Period: time.Minute}
+
+	store.Take("a", policy)
+	store.Take("a", policy)
+	res, _ := store.Take("a", policy)
+	assert.False(t, res.Allowed)
+
+	now = now.Add(30 * time.Second)
+	res, _ = store.Take("a", policy)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// never more than the limit.
+	now = now.Add(time.Hour)
+	res, _ = store.Take("a", policy)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	policy := Policy{Limit: 2, Period: time.Minute}
+
+	store.Take("a", policy)
+	now = now.Add(30 * time.Second)
+	store.Take("b", policy)
+	store.Take("b", policy)
+	now = now.Add(45 * time.Second)
+	store.Take("c", policy)
+
+	// a is full again, b not yet.
+	assert.NotContains(t, store.buckets, "a")
+	assert.Contains(t, store.buckets, "b")
+	assert.Contains(t, store.buckets, "c")
+}
@@ -0,0 +1,132 @@
+// Package ratelimit implements token buckets. Every key owns a bucket holding up to Limit tokens.
+// A request takes a token - the bucket is refilled continuously with Limit tokens per Period.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.com/l3montree/microservices/libs/orchardclient"
+)
+
+type Policy struct {
+	// the size of the bucket - the amount of requests allowed in a burst.
+	Limit  int
+	Period time.Duration
+}
+
+// tokens per nanosecond.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / float64(p.Period)
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// parses "<limit>/<period>" - for example "10/1m".
+func ParsePolicy(s string) (Policy, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Policy{}, fmt.Errorf("invalid policy %q - expected <limit>/<period>", s)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit < 0 {
+		return Policy{}, fmt.Errorf("invalid limit in policy %q", s)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("invalid period in policy %q", s)
+	}
+	return Policy{Limit: limit, Period: period}, nil
+}
+
+// parses a comma separated list of named policies - for example "login=10/1m,register=5/1h".
+func ParsePolicies(s string) (map[string]Policy, error) {
+	policies := make(map[string]Policy)
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid policy %q - expected <name>=<limit>/<period>", entry)
+		}
+		policy, err := ParsePolicy(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		policies[strings.TrimSpace(parts[0])] = policy
+	}
+	return policies, nil
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// the time the next token is available - only set if the request is not allowed.
+	RetryAt time.Time
+	// the time the bucket is full again.
+	ResetAt time.Time
+}
+
+func newResult(policy Policy, tokens float64, allowed bool, now time.Time) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: int(math.Floor(tokens)),
+		ResetAt:   now.Add(time.Duration((float64(policy.Limit) - tokens) / policy.rate())),
+	}
+	if !allowed {
+		res.RetryAt = now.Add(time.Duration(math.Ceil((1 - tokens) / policy.rate())))
+	}
+	return res
+}
+
+// the tokens of the bucket after refilling it for the elapsed time.
+func refill(policy Policy, tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(policy.Limit), tokens+float64(elapsed)*policy.rate())
+}
+
+type Store interface {
+	// takes a token from the bucket of the key.
+	Take(key string, policy Policy) (Result, error)
+}
+
+// applies the named policies. Names without a policy are not limited.
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+	logger   *logrus.Entry
+}
+
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{
+		store:    store,
+		policies: policies,
+		logger:   orchardclient.Logger.WithField("component", "Limiter"),
+	}
+}
+
+// the bucket is identified by the name of the policy and the key - for example a user id or an ip address.
+// fails open - a broken store must not lock out every user.
+func (l *Limiter) Allow(name, key string) Result {
+	policy, ok := l.policies[name]
+	if !ok || policy.Limit <= 0 {
+		return Result{Allowed: true}
+	}
+	res, err := l.store.Take(name+":"+key, policy)
+	if err != nil {
+		l.logger.Errorf("could not apply the rate limit %s: %s", name, err)
+		return Result{Allowed: true}
+	}
+	return res
+}
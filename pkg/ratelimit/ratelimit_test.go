@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("login=10/1m, register=5/1h,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Policy{
+		"login":    {Limit: 10, Period: time.Minute},
+		"register": {Limit: 5, Period: time.Hour},
+	}, policies)
+
+	_, err = ParsePolicies("login=10")
+	assert.NotNil(t, err)
+	_, err = ParsePolicies("login=-1/1m")
+	assert.NotNil(t, err)
+	_, err = ParsePolicies("login=10/0s")
+	assert.NotNil(t, err)
+	_, err = ParsePolicies("10/1m")
+	assert.NotNil(t, err)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(key string, policy Policy) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestLimiterFailsOpen(t *testing.T) {
+	limiter := NewLimiter(failingStore{}, map[string]Policy{"login": {Limit: 1, Period: time.Minute}})
+	assert.True(t, limiter.Allow("login", "127.0.0.1").Allowed)
+}
+
+func TestLimiterIgnoresUnknownAndDisabledPolicies(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{"login": {Limit: 0, Period: time.Minute}})
+	for i := 0; i < 10; i++ {
+		assert.True(t, limiter.Allow("login", "127.0.0.1").Allowed)
+		assert.True(t, limiter.Allow("register", "127.0.0.1").Allowed)
+	}
+}
+
+func TestLimiterSeparatesPolicies(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Policy{
+		"login":    {Limit: 1, Period: time.Minute},
+		"register": {Limit: 1, Period: time.Minute},
+	})
+	assert.True(t, limiter.Allow("login", "127.0.0.1").Allowed)
+	assert.False(t, limiter.Allow("login", "127.0.0.1").Allowed)
+	assert.True(t, limiter.Allow("register", "127.0.0.1").Allowed)
+	assert.True(t, limiter.Allow("login", "127.0.0.2").Allowed)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/resp"
+)
+
+// refills and takes a token atomically. The bucket expires once it would be full again.
+// the time is passed by the caller - the clocks of the replicas are expected to be in sync.
+const takeScript = `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or limit
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - updated) * limit / period)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, tostring(tokens)}
+`
+
+// shares the buckets between the replicas using redis - or any other server speaking its protocol and supporting EVAL.
+type RedisStore struct {
+	addr     string
+	password string
+	// every key is prefixed - allows to share the redis instance.
+	prefix string
+
+	mu   sync.Mutex
+	conn *resp.Conn
+	now  func() time.Time
+}
+
+func NewRedisStore(addr, password, prefix string) *RedisStore {
+	return &RedisStore{addr: addr, password: password, prefix: prefix, now: time.Now}
+}
+
+func (s *RedisStore) Take(key string, policy Policy) (Result, error) {
+	now := s.now()
+	reply, err := s.do(
+		"EVAL", takeScript, "1", s.prefix+key,
+		strconv.Itoa(policy.Limit),
+		strconv.FormatInt(policy.Period.Milliseconds(), 10),
+		strconv.FormatInt(now.UnixMilli(), 10),
+	)
+	if err != nil {
+		return Result{}, err
+	}
+	// [allowed, tokens]
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	tokensStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected reply: %v", reply)
+	}
+	return newResult(policy, tokens, allowed == 1, now), nil
+}
+
+// retries once - the connection might have been closed by the server.
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			s.conn, err = resp.Dial(s.addr, s.password)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var reply interface{}
+		reply, err = s.conn.Do(args...)
+		if _, ok := err.(resp.Error); err == nil || ok {
+			return reply, err
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return nil, err
+}
+
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
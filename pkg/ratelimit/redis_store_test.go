@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/l3montree/crypto-koi/crypto-koi-api/pkg/resp"
+)
+
+// answers every EVAL with the next reply and records the arguments.
+type fakeRedis struct {
+	listener net.Listener
+	replies  []string
+
+	mu       sync.Mutex
+	commands [][]interface{}
+}
+
+func newFakeRedis(t *testing.T, replies ...string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeRedis{listener: listener, replies: replies}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		command, err := resp.ReadReply(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, command.([]interface{}))
+		reply := f.replies[0]
+		f.replies = f.replies[1:]
+		f.mu.Unlock()
+		if reply == "" {
+			// simulates a connection closed by the server.
+			return
+		}
+		w.WriteString(reply)
+		w.Flush()
+	}
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	server := newFakeRedis(t, "*2\r\n:1\r\n$3\r\n2.5\r\n", "*2\r\n:0\r\n$4\r\n0.25\r\n")
+	store := NewRedisStore(server.listener.Addr().String(), "", "test:")
+	defer store.Close()
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	policy := Policy{Limit: 4, Period: time.Minute}
+
+	res, err := store.Take("login:127.0.0.1", policy)
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+
+	res, err = store.Take("login:127.0.0.1", policy)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	// 0.75 tokens are missing - a token is refilled every 15 seconds.
+	assert.Equal(t, now.Add(11250*time.Millisecond), res.RetryAt)
+
+	command := server.commands[0]
+	assert.Equal(t, []interface{}{"EVAL", takeScript, "1", "test:login:127.0.0.1", "4", "60000", "1646136000000"}, command)
+}
+
+func TestRedisStoreReconnects(t *testing.T) {
+	server := newFakeRedis(t, "", "*2\r\n:1\r\n$1\r\n0\r\n")
+	store := NewRedisStore(server.listener.Addr().String(), "", "test:")
+	defer store.Close()
+
+	res, err := store.Take("a", Policy{Limit: 1, Period: time.Minute})
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestRedisStoreReturnsServerErrors(t *testing.T) {
+	server := newFakeRedis(t, "-ERR unknown command 'EVAL'\r\n")
+	store := NewRedisStore(server.listener.Addr().String(), "", "test:")
+	defer store.Close()
+
+	_, err := store.Take("a", Policy{Limit: 1, Period: time.Minute})
+	assert.Equal(t, resp.Error("ERR unknown command 'EVAL'"), err)
+}
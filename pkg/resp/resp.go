@@ -0,0 +1,134 @@
+// Package resp is a minimal client of the redis serialization protocol (RESP).
+// It covers everything needed by the pubsub and the rate limiter - any server speaking the protocol works.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const Timeout = 5 * time.Second
+
+// an error reply of the server. The connection can still be used.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+func WriteCommand(w *bufio.Writer, args ...string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return w.Flush()
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("invalid RESP line: %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// returns a string, an int64, a []interface{}, nil or an Error.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		elements := make([]interface{}, length)
+		for i := range elements {
+			if elements[i], err = ReadReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return elements, nil
+	}
+	return nil, errors.New("unknown RESP type: " + line[:1])
+}
+
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// authenticates if the password is not empty.
+func Dial(addr, password string) (*Conn, error) {
+	conn, err := net.DialTimeout("tcp", addr, Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if password != "" {
+		if _, err := c.Do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// sends the command and waits for the reply. Error replies are returned as Error.
+func (c *Conn) Do(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(Timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if err := WriteCommand(c.w, args...); err != nil {
+		return nil, err
+	}
+	reply, err := ReadReply(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if respErr, ok := reply.(Error); ok {
+		return nil, respErr
+	}
+	return reply, nil
+}
+
+// blocks until the server pushes the next reply - used by subscriptions.
+func (c *Conn) Receive() (interface{}, error) {
+	return ReadReply(c.r)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
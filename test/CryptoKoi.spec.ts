@@ -14,6 +14,26 @@ const expectedHexHash =
 const expectedSignature =
   '0x0577530589f065fdb25b8f29132865782ab2a4ea75a294ba56deecddeeefb77b18755f1811bb76dfadf417ff58f6bd2b593ddb4c80b1eaa85752e0df5a5b44f41b';
 
+// the fixed values are provided by the golang tests. The values can be found: voucher_test.go
+const voucherDomain = {
+  name: 'CryptoKoi',
+  version: '1',
+  chainId: 137,
+  verifyingContract: '0x133c4b6c69322D09C5B266EFa9559173B6c9F029',
+};
+const voucherTypes = {
+  Voucher: [
+    { name: 'account', type: 'address' },
+    { name: 'tokenId', type: 'uint256' },
+    { name: 'price', type: 'uint256' },
+    { name: 'expiry', type: 'uint256' },
+  ],
+};
+const expectedVoucherHash =
+  '0xaf4659e6a9b84ba77d4aa28c59e67d904321189ef119097e15074d877a91a2ec';
+const expectedVoucherSignature =
+  '0xc3dc3426cb48e36d0a98273d451c1fbaa7a10d29172c8b52f2dee82fc9a6a83b6a4f73e213e99f4019a9720680d6f486690418af0d0471dbc5a03af5641cce2f1c';
+
 type TokenType = {
   tokenId?: string;
   account?: string;
@@ -38,6 +58,30 @@ function hashToken(tokenId: string, account: string) {
   );
 }
 
+type VoucherType = {
+  account: string;
+  tokenId: string;
+  price: number;
+  expiry: number;
+};
+
+async function signVoucher(
+  signer: SignerWithAddress | Wallet,
+  contract: Contract,
+  voucher: VoucherType,
+) {
+  const { chainId } = await ethers.provider.getNetwork();
+  return await signer._signTypedData(
+    { ...voucherDomain, chainId, verifyingContract: contract.address },
+    voucherTypes,
+    voucher,
+  );
+}
+
+async function latestTimestamp() {
+  return (await ethers.provider.getBlock('latest')).timestamp;
+}
+
 describe('CryptoKoi', function () {
   let accounts: SignerWithAddress[];
   let signer: SignerWithAddress;
@@ -62,6 +106,26 @@ describe('CryptoKoi', function () {
       admin = new ethers.Wallet(privateKey);
     });
 
+    it('should create the same voucher as golang', async () => {
+      const voucher = {
+        account: otherUserAddress,
+        tokenId,
+        price: 1000 * 1000 * 1000,
+        expiry: 1700000000,
+      };
+      expect(
+        ethers.utils._TypedDataEncoder.hash(
+          voucherDomain,
+          voucherTypes,
+          voucher,
+        ),
+      ).to.eq(expectedVoucherHash);
+
+      expect(
+        await admin._signTypedData(voucherDomain, voucherTypes, voucher),
+      ).to.eq(expectedVoucherSignature);
+    });
+
     it('should work with fixed values', async () => {
       // the fixed values are provided by the golang executable. The values can be found: web3_test.go
       const hash = hashToken(tokenId, otherUserAddress);
@@ -223,4 +287,111 @@ describe('CryptoKoi', function () {
       ).to.be.revertedWith('Invalid signature');
     });
   });
+
+  describe('Vouchers', function () {
+    let contract: Contract;
+    let voucher: VoucherType;
+    beforeEach(async function () {
+      contract = await deploy(
+        'CryptoKoi',
+        signer,
+        'Name',
+        'Symbol',
+        'http://localhost:8080/tokens/',
+        1,
+      );
+
+      const [t, account] = Object.entries(tokens)[0];
+      voucher = {
+        account,
+        tokenId: t,
+        price: 1000 * 1000 * 1000,
+        expiry: (await latestTimestamp()) + 3600,
+      };
+    });
+
+    function redeemVoucher(
+      v: VoucherType,
+      signature: string,
+      value = v.price,
+    ) {
+      return contract
+        .connect(accounts[2])
+        .redeemVoucher(
+          v.account,
+          v.tokenId,
+          v.price,
+          v.expiry,
+          signature,
+          { value },
+        );
+    }
+
+    it('redeem voucher - success', async function () {
+      const signature = await signVoucher(signer, contract, voucher);
+      await expect(redeemVoucher(voucher, signature))
+        .to.emit(contract, 'Transfer')
+        .withArgs(
+          ethers.constants.AddressZero,
+          voucher.account,
+          voucher.tokenId,
+        );
+    });
+
+    it('expired voucher - failure', async function () {
+      voucher.expiry = (await latestTimestamp()) - 1;
+      const signature = await signVoucher(signer, contract, voucher);
+      await expect(redeemVoucher(voucher, signature)).to.be.revertedWith(
+        'Voucher expired',
+      );
+    });
+
+    it('insufficient funds - failure', async function () {
+      const signature = await signVoucher(signer, contract, voucher);
+      await expect(
+        redeemVoucher(voucher, signature, voucher.price - 1),
+      ).to.be.revertedWith('Insufficient funds');
+    });
+
+    it('changed price - failure', async function () {
+      const signature = await signVoucher(signer, contract, voucher);
+      await expect(
+        redeemVoucher({ ...voucher, price: 1 }, signature),
+      ).to.be.revertedWith('Invalid signature');
+    });
+
+    it('another deployment - failure', async function () {
+      const other = await deploy(
+        'CryptoKoi',
+        signer,
+        'Name',
+        'Symbol',
+        'http://localhost:8080/tokens/',
+        1,
+      );
+      const signature = await signVoucher(signer, other, voucher);
+      await expect(redeemVoucher(voucher, signature)).to.be.revertedWith(
+        'Invalid signature',
+      );
+    });
+
+    it('legacy voucher after migration - failure', async function () {
+      const signature = await signer.signMessage(
+        hashToken(voucher.tokenId, voucher.account),
+      );
+      await contract.disableLegacyVouchers();
+      expect(await contract.legacyVouchersAccepted()).to.eq(false);
+      await expect(
+        contract.redeem(voucher.account, voucher.tokenId, signature, {
+          value: 1 * 1000 * 1000 * 1000,
+        }),
+      ).to.be.revertedWith('Legacy vouchers disabled');
+    });
+
+    it('disable legacy vouchers - owner only', async function () {
+      await expect(
+        contract.connect(accounts[2]).disableLegacyVouchers(),
+      ).to.be.revertedWith('Owner privilege only');
+    });
+  });
 });